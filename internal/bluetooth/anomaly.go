@@ -0,0 +1,201 @@
+package bluetooth
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// AnomalyKind names a pattern of unstable connections.
+type AnomalyKind string
+
+// Kinds of anomaly DetectAnomalies reports.
+const (
+	AnomalyFlapping      AnomalyKind = "flapping"        // repeated disconnect/reconnect cycles
+	AnomalyShortSessions AnomalyKind = "short-sessions"  // sessions that ended almost at once
+	AnomalyLinkLossBurst AnomalyKind = "link-loss-burst" // several link losses close together
+)
+
+// AnomalyThresholds configures DetectAnomalies.
+type AnomalyThresholds struct {
+	MaxCycles      int           // more disconnect/reconnect cycles than this...
+	CycleWindow    time.Duration // ...within this window is flapping
+	MinSession     time.Duration // an ended session shorter than this is short
+	MaxLinkLosses  int           // more link losses than this...
+	LinkLossWindow time.Duration // ...within this window is a burst
+}
+
+// DefaultAnomalyThresholds flags a device that reconnects more than three
+// times in half an hour, drops sessions within two minutes, or loses the
+// link more than twice in ten minutes.
+var DefaultAnomalyThresholds = AnomalyThresholds{
+	MaxCycles:      3,
+	CycleWindow:    30 * time.Minute,
+	MinSession:     2 * time.Minute,
+	MaxLinkLosses:  2,
+	LinkLossWindow: 10 * time.Minute,
+}
+
+// Validate checks that the thresholds can be applied.
+func (t AnomalyThresholds) Validate() error {
+	switch {
+	case t.MaxCycles < 0 || t.MaxLinkLosses < 0:
+		return fmt.Errorf("invalid anomaly thresholds: counts must not be negative")
+	case t.CycleWindow <= 0 || t.LinkLossWindow <= 0:
+		return fmt.Errorf("invalid anomaly thresholds: windows must be positive")
+	case t.MinSession < 0:
+		return fmt.Errorf("invalid anomaly thresholds: minimum session must not be negative")
+	}
+	return nil
+}
+
+// Anomaly is one offending time window of a device.
+type Anomaly struct {
+	Kind  AnomalyKind `json:"kind"`
+	Start time.Time   `json:"start"`
+	End   time.Time   `json:"end"`
+	Count int         `json:"count"` // cycles, short sessions, or link losses in the window
+}
+
+// DeviceStability is the stability analysis of one device.
+type DeviceStability struct {
+	Device    string    `json:"device"`
+	Address   string    `json:"address,omitempty"`
+	Score     int       `json:"score"` // percentage of sessions that were stable
+	Sessions  int       `json:"sessions"`
+	Anomalies []Anomaly `json:"anomalies"`
+}
+
+// DetectAnomalies analyses each device's sessions for flapping, short
+// sessions, and link-loss bursts, ordered from least to most stable.
+// Devices are told apart by address, as in SummarizeSessions.
+//
+// A session is unstable if it was short, ended by link loss, or started in
+// a flapping window; the score is the percentage of stable sessions, so a
+// device with no anomalies scores 100.
+func DetectAnomalies(sessions []Session, t AnomalyThresholds) []DeviceStability {
+	var devices []string
+	byDevice := make(map[string][]Session)
+	keys := sessionDeviceKeys(sessions)
+	for i, s := range sessions {
+		key := keys[i]
+		if _, ok := byDevice[key]; !ok {
+			devices = append(devices, key)
+		}
+		byDevice[key] = append(byDevice[key], s)
+	}
+
+	var out []DeviceStability
+	for _, device := range devices {
+		out = append(out, deviceStability(byDevice[device], t))
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score < out[j].Score
+		}
+		return out[i].Device < out[j].Device
+	})
+	return out
+}
+
+// deviceStability analyses the sessions of one device, in start order.
+func deviceStability(sessions []Session, t AnomalyThresholds) DeviceStability {
+	ds := DeviceStability{Device: sessions[0].Device, Sessions: len(sessions), Anomalies: []Anomaly{}}
+
+	var reconnects, linkLosses []time.Time
+	short := make([]bool, len(sessions))
+	for i, s := range sessions {
+		if ds.Address == "" {
+			ds.Address = s.Address
+		}
+		if i > 0 && !sessions[i-1].End.IsZero() && !s.Start.IsZero() {
+			reconnects = append(reconnects, s.Start)
+		}
+		if s.EndedBy == SessionLinkLoss {
+			linkLosses = append(linkLosses, s.End)
+		}
+		short[i] = s.EndedBy != SessionConnected && !s.Start.IsZero() && s.Duration < t.MinSession
+	}
+
+	flapping := burstWindows(reconnects, t.CycleWindow, t.MaxCycles, AnomalyFlapping)
+	ds.Anomalies = append(ds.Anomalies, flapping...)
+	ds.Anomalies = append(ds.Anomalies, shortWindows(sessions, short)...)
+	ds.Anomalies = append(ds.Anomalies, burstWindows(linkLosses, t.LinkLossWindow, t.MaxLinkLosses, AnomalyLinkLossBurst)...)
+	sort.SliceStable(ds.Anomalies, func(i, j int) bool {
+		return ds.Anomalies[i].Start.Before(ds.Anomalies[j].Start)
+	})
+
+	unstable := 0
+	for i, s := range sessions {
+		if short[i] || s.EndedBy == SessionLinkLoss || inWindows(s.Start, flapping) {
+			unstable++
+		}
+	}
+	ds.Score = int(math.Round(100 * float64(len(sessions)-unstable) / float64(len(sessions))))
+	return ds
+}
+
+// burstWindows returns the windows in which more than limit of the sorted
+// times fall within window of each other. Overlapping windows are merged.
+func burstWindows(times []time.Time, window time.Duration, limit int, kind AnomalyKind) []Anomaly {
+	var out []Anomaly
+	j := 0
+	for i := range times {
+		if j < i {
+			j = i
+		}
+		for j+1 < len(times) && times[j+1].Sub(times[i]) <= window {
+			j++
+		}
+		if j-i+1 <= limit {
+			continue
+		}
+		if n := len(out); n > 0 && !times[i].After(out[n-1].End) {
+			last := &out[n-1]
+			last.Count += countBetween(times, last.End, times[j])
+			last.End = times[j]
+			continue
+		}
+		out = append(out, Anomaly{Kind: kind, Start: times[i], End: times[j], Count: j - i + 1})
+	}
+	return out
+}
+
+// countBetween counts the sorted times in (after, until].
+func countBetween(times []time.Time, after, until time.Time) int {
+	n := 0
+	for _, t := range times {
+		if t.After(after) && !t.After(until) {
+			n++
+		}
+	}
+	return n
+}
+
+// shortWindows returns one window per run of consecutive short sessions.
+func shortWindows(sessions []Session, short []bool) []Anomaly {
+	var out []Anomaly
+	for i, s := range sessions {
+		if !short[i] {
+			continue
+		}
+		if n := len(out); n > 0 && i > 0 && short[i-1] {
+			out[n-1].End = s.End
+			out[n-1].Count++
+			continue
+		}
+		out = append(out, Anomaly{Kind: AnomalyShortSessions, Start: s.Start, End: s.End, Count: 1})
+	}
+	return out
+}
+
+// inWindows reports whether t falls within any of the windows.
+func inWindows(t time.Time, windows []Anomaly) bool {
+	for _, w := range windows {
+		if !t.Before(w.Start) && !t.After(w.End) {
+			return true
+		}
+	}
+	return false
+}
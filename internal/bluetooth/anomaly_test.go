@@ -0,0 +1,147 @@
+package bluetooth
+
+import (
+	"testing"
+	"time"
+)
+
+// anomalySession is a session from minute start to minute end, or still
+// connected if end is negative.
+func anomalySession(device string, start, end int, endedBy SessionEnd) Session {
+	s := Session{Device: device, Start: sessionBase.Add(time.Duration(start) * time.Minute), EndedBy: endedBy}
+	if end >= 0 {
+		s.End = sessionBase.Add(time.Duration(end) * time.Minute)
+		s.Duration = s.End.Sub(s.Start)
+	}
+	return s
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	sessions := []Session{
+		anomalySession("Magic Keyboard", 0, 60, SessionUserDisconnect),
+		// Buds drop the link a minute after every reconnect.
+		anomalySession("Buds", 0, 1, SessionLinkLoss),
+		anomalySession("Buds", 5, 6, SessionLinkLoss),
+		anomalySession("Buds", 10, 11, SessionLinkLoss),
+		anomalySession("Buds", 15, 16, SessionLinkLoss),
+		anomalySession("Buds", 20, 21, SessionLinkLoss),
+		anomalySession("Buds", 120, -1, SessionConnected),
+	}
+
+	got := DetectAnomalies(sessions, DefaultAnomalyThresholds)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 devices, got %+v", got)
+	}
+
+	buds := got[0]
+	if buds.Device != "Buds" || buds.Sessions != 6 || buds.Score != 17 {
+		t.Errorf("expected Buds first with score 17 over 6 sessions, got %+v", buds)
+	}
+	want := []struct {
+		kind       AnomalyKind
+		start, end int
+		count      int
+	}{
+		{AnomalyShortSessions, 0, 21, 5},
+		{AnomalyLinkLossBurst, 1, 21, 5},
+		{AnomalyFlapping, 5, 20, 4},
+	}
+	if len(buds.Anomalies) != len(want) {
+		t.Fatalf("expected %d anomalies, got %+v", len(want), buds.Anomalies)
+	}
+	for i, w := range want {
+		a := buds.Anomalies[i]
+		start := sessionBase.Add(time.Duration(w.start) * time.Minute)
+		end := sessionBase.Add(time.Duration(w.end) * time.Minute)
+		if a.Kind != w.kind || !a.Start.Equal(start) || !a.End.Equal(end) || a.Count != w.count {
+			t.Errorf("anomaly %d: expected %s %d-%d x%d, got %+v", i, w.kind, w.start, w.end, w.count, a)
+		}
+	}
+
+	if kb := got[1]; kb.Device != "Magic Keyboard" || kb.Score != 100 || len(kb.Anomalies) != 0 {
+		t.Errorf("expected a stable keyboard, got %+v", kb)
+	}
+}
+
+func TestDetectAnomalies_SameName(t *testing.T) {
+	// Two pairs of AirPods reconnect in turn; neither flaps on its own.
+	var sessions []Session
+	for i, address := range []string{"74:15:F5:4E:D0:50", "F4:34:F0:96:DD:A0", "74:15:F5:4E:D0:50", "F4:34:F0:96:DD:A0"} {
+		s := anomalySession("AirPods", i*5, i*5+4, SessionUserDisconnect)
+		s.Address = address
+		sessions = append(sessions, s)
+	}
+	sessions[3].Address = "f4:34:f0:96:dd:a0"
+
+	got := DetectAnomalies(sessions, AnomalyThresholds{
+		MaxCycles: 1, CycleWindow: time.Hour, LinkLossWindow: time.Hour,
+	})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 devices, got %+v", got)
+	}
+	for _, ds := range got {
+		if ds.Sessions != 2 || ds.Score != 100 || len(ds.Anomalies) != 0 {
+			t.Errorf("expected 2 stable sessions of %s, got %+v", ds.Address, ds)
+		}
+	}
+	if got[0].Address == got[1].Address {
+		t.Errorf("expected each device's own address, got %s twice", got[0].Address)
+	}
+}
+
+func TestDetectAnomalies_Thresholds(t *testing.T) {
+	sessions := []Session{
+		anomalySession("Buds", 0, 10, SessionUserDisconnect),
+		anomalySession("Buds", 12, 22, SessionUserDisconnect),
+		anomalySession("Buds", 24, -1, SessionConnected),
+	}
+
+	if got := DetectAnomalies(sessions, DefaultAnomalyThresholds); got[0].Score != 100 || len(got[0].Anomalies) != 0 {
+		t.Errorf("expected two reconnects to be within the defaults, got %+v", got[0])
+	}
+
+	strict := DefaultAnomalyThresholds
+	strict.MaxCycles = 1
+	strict.MinSession = 15 * time.Minute
+	got := DetectAnomalies(sessions, strict)
+	if len(got[0].Anomalies) != 2 || got[0].Anomalies[0].Kind != AnomalyShortSessions || got[0].Anomalies[1].Kind != AnomalyFlapping {
+		t.Errorf("expected short sessions and flapping, got %+v", got[0].Anomalies)
+	}
+	if got[0].Score != 0 {
+		t.Errorf("expected every session unstable, got score %d", got[0].Score)
+	}
+}
+
+func TestBurstWindows(t *testing.T) {
+	var times []time.Time
+	for _, m := range []int{0, 1, 2, 3, 30, 100, 101, 102, 103} {
+		times = append(times, sessionBase.Add(time.Duration(m)*time.Minute))
+	}
+	got := burstWindows(times, 10*time.Minute, 3, AnomalyLinkLossBurst)
+	if len(got) != 2 || got[0].Count != 4 || got[1].Count != 4 || !got[1].Start.Equal(times[5]) {
+		t.Errorf("expected two separate bursts of 4, got %+v", got)
+	}
+}
+
+func TestAnomalyThresholds_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*AnomalyThresholds)
+		wantErr bool
+	}{
+		{"defaults", func(*AnomalyThresholds) {}, false},
+		{"zero cycles", func(a *AnomalyThresholds) { a.MaxCycles = 0 }, false},
+		{"negative cycles", func(a *AnomalyThresholds) { a.MaxCycles = -1 }, true},
+		{"zero window", func(a *AnomalyThresholds) { a.LinkLossWindow = 0 }, true},
+		{"negative session", func(a *AnomalyThresholds) { a.MinSession = -time.Second }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := DefaultAnomalyThresholds
+			tt.modify(&th)
+			if err := th.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
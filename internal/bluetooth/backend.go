@@ -0,0 +1,259 @@
+package bluetooth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned when the selected backend cannot perform an operation.
+var ErrUnsupported = errors.New("not supported by this backend")
+
+// Backend is the platform layer bltctl drives. It covers device enumeration,
+// connection control, power, reset, and log retrieval. Every call honours the
+// context's deadline and cancellation.
+type Backend interface {
+	// Name returns the identifier used to select the backend.
+	Name() string
+	// Snapshot returns the controller state and all paired devices.
+	Snapshot(ctx context.Context) (*Snapshot, error)
+	// CanControl returns nil if Connect, Disconnect, and Remove are available.
+	CanControl(ctx context.Context) error
+	// Tools reports, by name, whether each external tool the backend
+	// controls devices with is installed, e.g. {"blueutil": false}.
+	// Backends that need none return nil.
+	Tools(ctx context.Context) map[string]bool
+	// Connect connects to a device by address.
+	Connect(ctx context.Context, address string) error
+	// Disconnect disconnects a device by address.
+	Disconnect(ctx context.Context, address string) error
+	// Remove unpairs a device by address.
+	Remove(ctx context.Context, address string) error
+	// SetPower turns the controller on or off.
+	SetPower(ctx context.Context, on bool) error
+	// Reset restarts the Bluetooth stack.
+	Reset(ctx context.Context) error
+	// ReadLog returns raw Bluetooth log output for the query.
+	ReadLog(ctx context.Context, q LogQuery) ([]byte, error)
+	// StreamLog calls fn with each Bluetooth log line written from now on,
+	// until ctx is done or the log ends.
+	StreamLog(ctx context.Context, fn func(line string)) error
+}
+
+// Snapshot is a point-in-time view of the controller and its paired devices.
+type Snapshot struct {
+	PowerState     string            `json:"power_state"`
+	ControllerInfo map[string]string `json:"controller_info"` // default controller
+	Controllers    []Controller      `json:"controllers,omitempty"`
+	Devices        []Device          `json:"devices"` // across all controllers
+}
+
+// LogQuery selects the log entries returned by Backend.ReadLog: either the
+// last Last of the log, or the absolute range from Since to Until.
+type LogQuery struct {
+	Last  string    // relative window, e.g. "24h" or "5m"; ignored when Since is set
+	Since time.Time // start of an absolute range
+	Until time.Time // end of an absolute range; zero for now
+
+	Archive string // a .logarchive to read instead of the live log
+}
+
+// CommandRunner executes an external command and returns its stdout.
+// The command is killed when ctx is done.
+type CommandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// ExecRunner runs commands on the local system. A command killed by an
+// expired deadline returns a *TimeoutError naming it.
+func ExecRunner(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	// Don't wait on output pipes held open by orphaned grandchildren
+	// (e.g. a shell wrapper whose child outlived the kill).
+	cmd.WaitDelay = 500 * time.Millisecond
+	start := time.Now()
+	out, err := cmd.Output()
+	if err != nil && ctx.Err() != nil {
+		return out, contextError(ctx, name, start)
+	}
+	return out, err
+}
+
+// LineStreamer runs a long-lived external command and calls fn with each
+// line of its stdout. It returns ctx.Err() once ctx is done.
+type LineStreamer func(ctx context.Context, fn func(line string), name string, args ...string) error
+
+// ExecStreamer streams commands on the local system.
+func ExecStreamer(ctx context.Context, fn func(line string), name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = 500 * time.Millisecond
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		fn(sc.Text())
+	}
+	if sc.Err() != nil {
+		// Stop a command that would otherwise block writing to a pipe
+		// nobody reads.
+		cmd.Process.Kill()
+	}
+	err = cmd.Wait()
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case sc.Err() != nil:
+		return sc.Err()
+	case err != nil && stderr.Len() > 0:
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// runnerStreamer streams through a CommandRunner, which only returns output
+// once the command ends. It serves replays, which deliver a recorded stream
+// all at once; live commands, recorded or not, need a LineStreamer.
+func runnerStreamer(run CommandRunner) LineStreamer {
+	return func(ctx context.Context, fn func(line string), name string, args ...string) error {
+		out, err := run(ctx, name, args...)
+		for _, line := range strings.Split(string(out), "\n") {
+			if line != "" {
+				fn(line)
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+}
+
+// BackendOptions configures a backend created by NewBackend.
+type BackendOptions struct {
+	// Runner executes external commands. Nil uses ExecRunner.
+	// Backends that do not shell out reject a non-nil Runner.
+	Runner CommandRunner
+	// Streamer runs long-lived commands such as log stream. Nil uses
+	// ExecStreamer, or streams through Runner when only Runner is set.
+	Streamer LineStreamer
+	// LookPath reports whether a tool is installed, as exec.LookPath
+	// does. Nil uses exec.LookPath.
+	LookPath func(file string) (string, error)
+	// Scenario is the scenario file for the sim backend.
+	Scenario string
+}
+
+// BackendFactory creates a backend instance.
+type BackendFactory func(opts BackendOptions) (Backend, error)
+
+// DefaultBackend is the backend used when none is selected.
+var DefaultBackend = defaultBackendFor(runtime.GOOS)
+
+// defaultBackendFor returns the default backend name for an operating system.
+func defaultBackendFor(goos string) string {
+	if goos == "linux" {
+		return "bluetoothctl"
+	}
+	return "macos"
+}
+
+var backendFactories = map[string]BackendFactory{
+	"macos": func(opts BackendOptions) (Backend, error) {
+		b := newMacOSBackend(opts.Runner, opts.LookPath)
+		if opts.Streamer != nil {
+			b.stream = opts.Streamer
+		}
+		return b, nil
+	},
+}
+
+// RegisterBackend makes a backend selectable by name.
+// It panics if a backend with the same name is already registered.
+func RegisterBackend(name string, factory BackendFactory) {
+	if _, ok := backendFactories[name]; ok {
+		panic("bluetooth: backend already registered: " + name)
+	}
+	backendFactories[name] = factory
+}
+
+// Backends returns the names of all registered backends, sorted.
+func Backends() []string {
+	names := make([]string, 0, len(backendFactories))
+	for name := range backendFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBackend creates the backend registered under name.
+func NewBackend(name string, opts BackendOptions) (Backend, error) {
+	factory, ok := backendFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend: %s (available: %s)", name, strings.Join(Backends(), ", "))
+	}
+	return factory(opts)
+}
+
+// Client runs Bluetooth operations against a Backend.
+type Client struct {
+	backend Backend
+	timeout time.Duration // overrides the per-operation defaults when non-zero
+	cache   *snapshotCache
+	config  *Config // aliases and groups; nil if none
+	store   *Store  // records fresh snapshots; nil if none
+
+	logSource LogSource // the live system log unless set
+}
+
+// NewClient returns a client that uses the given backend. Snapshots are
+// reused for DefaultSnapshotTTL.
+func NewClient(b Backend) *Client {
+	return &Client{backend: b, cache: newSnapshotCache(DefaultSnapshotTTL, nil)}
+}
+
+// Backend returns the backend the client uses.
+func (c *Client) Backend() Backend {
+	return c.backend
+}
+
+// defaultClient serves the package-level functions.
+var defaultClient = NewClient(NewMacOSBackend(nil))
+
+// SetBackend replaces the backend used by the package-level functions.
+// It is meant to be called once at startup, before any operation runs.
+func SetBackend(b Backend) {
+	defaultClient.backend = b
+	defaultClient.cache.invalidate()
+}
+
+// CurrentBackend returns the backend used by the package-level functions.
+func CurrentBackend() Backend {
+	return defaultClient.backend
+}
+
+// CanControl returns nil if the backend can connect, disconnect, and remove devices.
+func (c *Client) CanControl(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx, DefaultSnapshotTimeout)
+	defer cancel()
+	return c.backend.CanControl(ctx)
+}
+
+// CanControl returns nil if the current backend can connect, disconnect, and remove devices.
+func CanControl(ctx context.Context) error {
+	return defaultClient.CanControl(ctx)
+}
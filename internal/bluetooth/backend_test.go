@@ -0,0 +1,213 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeBackend is an in-memory Backend for exercising Client logic.
+type fakeBackend struct {
+	snap       *Snapshot
+	snapErr    error
+	controlErr error
+	log        string
+	logErr     error
+	tools      map[string]bool
+	calls      []string
+}
+
+func (f *fakeBackend) Name() string { return "fake" }
+
+func (f *fakeBackend) Snapshot(ctx context.Context) (*Snapshot, error) {
+	if f.snapErr != nil {
+		return nil, f.snapErr
+	}
+	return f.snap, nil
+}
+
+func (f *fakeBackend) CanControl(ctx context.Context) error { return f.controlErr }
+
+func (f *fakeBackend) Tools(ctx context.Context) map[string]bool { return f.tools }
+
+func (f *fakeBackend) Connect(ctx context.Context, address string) error {
+	f.calls = append(f.calls, "connect "+address)
+	return f.controlErr
+}
+
+func (f *fakeBackend) Disconnect(ctx context.Context, address string) error {
+	f.calls = append(f.calls, "disconnect "+address)
+	return f.controlErr
+}
+
+func (f *fakeBackend) Remove(ctx context.Context, address string) error {
+	f.calls = append(f.calls, "remove "+address)
+	return f.controlErr
+}
+
+func (f *fakeBackend) SetPower(ctx context.Context, on bool) error {
+	if on {
+		f.calls = append(f.calls, "power on")
+	} else {
+		f.calls = append(f.calls, "power off")
+	}
+	return nil
+}
+
+func (f *fakeBackend) Reset(ctx context.Context) error {
+	f.calls = append(f.calls, "reset")
+	return nil
+}
+
+func (f *fakeBackend) ReadLog(ctx context.Context, q LogQuery) ([]byte, error) {
+	f.calls = append(f.calls, "log "+q.Last)
+	if f.logErr != nil {
+		return nil, f.logErr
+	}
+	return []byte(f.log), nil
+}
+
+func (f *fakeBackend) StreamLog(ctx context.Context, fn func(line string)) error {
+	f.calls = append(f.calls, "stream")
+	for _, line := range strings.Split(f.log, "\n") {
+		if line != "" {
+			fn(line)
+		}
+	}
+	return f.logErr
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		snap: &Snapshot{
+			PowerState:     "on",
+			ControllerInfo: map[string]string{"controller_chipset": "BCM_4387"},
+			Devices: []Device{
+				{Name: "AirPods Max", Address: "70:F9:4A:7A:8B:CA", Connected: true, BatteryLevel: 85},
+				{Name: "Magic Keyboard", Address: "AA:BB:CC:DD:EE:01", BatteryLevel: -1},
+			},
+		},
+	}
+}
+
+func TestClient_ListConnected(t *testing.T) {
+	t.Parallel()
+	c := NewClient(newFakeBackend())
+
+	devices, err := c.ListConnected(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Name != "AirPods Max" {
+		t.Errorf("expected only AirPods Max, got %+v", devices)
+	}
+}
+
+func TestClient_GetDevice(t *testing.T) {
+	t.Parallel()
+	c := NewClient(newFakeBackend())
+
+	d, err := c.GetDevice(t.Context(), "magic keyboard")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Address != "AA:BB:CC:DD:EE:01" {
+		t.Errorf("expected AA:BB:CC:DD:EE:01, got %s", d.Address)
+	}
+}
+
+func TestClient_SnapshotError(t *testing.T) {
+	t.Parallel()
+	fb := newFakeBackend()
+	fb.snapErr = errors.New("adapter gone")
+	c := NewClient(fb)
+
+	if _, err := c.ListDevices(t.Context()); err == nil {
+		t.Error("expected ListDevices error")
+	}
+	if _, err := c.Diagnose(t.Context()); err == nil {
+		t.Error("expected Diagnose error")
+	}
+}
+
+func TestClient_Diagnose(t *testing.T) {
+	t.Parallel()
+	fb := newFakeBackend()
+	fb.log = "error: page timeout\nnormal line\n"
+	c := NewClient(fb)
+
+	report, err := c.Diagnose(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.PowerState != "on" {
+		t.Errorf("expected power state on, got %s", report.PowerState)
+	}
+	if len(report.ConnectedDevices) != 1 {
+		t.Errorf("expected 1 connected device, got %d", len(report.ConnectedDevices))
+	}
+	if len(report.RecentErrors) != 1 {
+		t.Errorf("expected 1 recent error, got %v", report.RecentErrors)
+	}
+	if fb.calls[0] != "log 5m" {
+		t.Errorf("expected 5m log window, got %v", fb.calls)
+	}
+}
+
+func TestClient_ControlDelegates(t *testing.T) {
+	t.Parallel()
+	fb := newFakeBackend()
+	c := NewClient(fb)
+
+	_ = c.Connect(t.Context(), "A")
+	_ = c.Disconnect(t.Context(), "B")
+	_ = c.Remove(t.Context(), "C")
+	_ = c.PowerOn(t.Context())
+	_ = c.PowerOff(t.Context())
+	_ = c.Reset(t.Context())
+
+	want := []string{"connect A", "disconnect B", "remove C", "power on", "power off", "reset"}
+	if len(fb.calls) != len(want) {
+		t.Fatalf("expected %v, got %v", want, fb.calls)
+	}
+	for i := range want {
+		if fb.calls[i] != want[i] {
+			t.Errorf("call[%d]: expected %q, got %q", i, want[i], fb.calls[i])
+		}
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	t.Parallel()
+
+	b, err := NewBackend("macos", BackendOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Name() != "macos" {
+		t.Errorf("expected macos, got %s", b.Name())
+	}
+
+	if _, err := NewBackend("nope", BackendOptions{}); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func TestParseSnapshot(t *testing.T) {
+	t.Parallel()
+
+	snap, err := ParseSnapshot([]byte(sampleJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snap.PowerState != "on" {
+		t.Errorf("expected power state on, got %s", snap.PowerState)
+	}
+	if snap.ControllerInfo["controller_chipset"] != "BCM_4387" {
+		t.Errorf("expected chipset BCM_4387, got %s", snap.ControllerInfo["controller_chipset"])
+	}
+	if len(snap.Devices) != 5 {
+		t.Errorf("expected 5 devices, got %d", len(snap.Devices))
+	}
+}
@@ -0,0 +1,84 @@
+package bluetooth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BatteryComponent identifies a separately powered part of a device.
+type BatteryComponent string
+
+// Battery components reported by system_profiler.
+const (
+	BatteryMain  BatteryComponent = "main"
+	BatteryLeft  BatteryComponent = "left"
+	BatteryRight BatteryComponent = "right"
+	BatteryCase  BatteryComponent = "case"
+)
+
+// Label returns the one-letter label used in "L 100% R 40% C 77%".
+func (c BatteryComponent) Label() string {
+	switch c {
+	case BatteryLeft:
+		return "L"
+	case BatteryRight:
+		return "R"
+	case BatteryCase:
+		return "C"
+	default:
+		return "M"
+	}
+}
+
+// BatteryReading is the charge of one battery component.
+type BatteryReading struct {
+	Component BatteryComponent `json:"component"`
+	Level     int              `json:"level"` // 0-100
+}
+
+// batteryComponentKeys maps system_profiler fields to components, in display order.
+var batteryComponentKeys = []struct {
+	key       string
+	component BatteryComponent
+}{
+	{"device_batteryLevelMain", BatteryMain},
+	{"device_batteryLevelLeft", BatteryLeft},
+	{"device_batteryLevelRight", BatteryRight},
+	{"device_batteryLevelCase", BatteryCase},
+}
+
+// parseBatteryComponents extracts the per-component battery levels.
+// Unparseable values are skipped.
+func parseBatteryComponents(props map[string]interface{}) []BatteryReading {
+	var readings []BatteryReading
+	for _, bk := range batteryComponentKeys {
+		val, ok := props[bk.key]
+		if !ok {
+			continue
+		}
+		if level := parseBatteryString(val); level >= 0 {
+			readings = append(readings, BatteryReading{Component: bk.component, Level: level})
+		}
+	}
+	return readings
+}
+
+// HasComponentBatteries reports whether the device reports more than a
+// single main battery, e.g. AirPods buds and case.
+func (d Device) HasComponentBatteries() bool {
+	for _, r := range d.Batteries {
+		if r.Component != BatteryMain {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatBatteries renders readings as "L 100% R 40% C 77%".
+func FormatBatteries(readings []BatteryReading) string {
+	parts := make([]string, 0, len(readings))
+	for _, r := range readings {
+		parts = append(parts, fmt.Sprintf("%s %d%%", r.Component.Label(), r.Level))
+	}
+	return strings.Join(parts, " ")
+}
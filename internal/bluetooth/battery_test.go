@@ -0,0 +1,68 @@
+package bluetooth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBatteryComponents(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]interface{}
+		want  []BatteryReading
+	}{
+		{
+			name: "buds and case in display order",
+			props: map[string]interface{}{
+				"device_batteryLevelCase":  "77%",
+				"device_batteryLevelRight": "40%",
+				"device_batteryLevelLeft":  "100%",
+			},
+			want: []BatteryReading{{BatteryLeft, 100}, {BatteryRight, 40}, {BatteryCase, 77}},
+		},
+		{
+			name:  "main only",
+			props: map[string]interface{}{"device_batteryLevelMain": "64%"},
+			want:  []BatteryReading{{BatteryMain, 64}},
+		},
+		{
+			name:  "generic level is not a component",
+			props: map[string]interface{}{"device_batteryLevel": "85%"},
+			want:  nil,
+		},
+		{
+			name:  "invalid values skipped",
+			props: map[string]interface{}{"device_batteryLevelLeft": "n/a", "device_batteryLevelRight": "150%"},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseBatteryComponents(tt.props); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDevice_HasComponentBatteries(t *testing.T) {
+	if (Device{Batteries: []BatteryReading{{BatteryMain, 64}}}).HasComponentBatteries() {
+		t.Error("expected a main-only device to have no components")
+	}
+	if !(Device{Batteries: []BatteryReading{{BatteryCase, 10}}}).HasComponentBatteries() {
+		t.Error("expected a case reading to count as a component")
+	}
+	if (Device{}).HasComponentBatteries() {
+		t.Error("expected no components without readings")
+	}
+}
+
+func TestFormatBatteries(t *testing.T) {
+	got := FormatBatteries([]BatteryReading{{BatteryLeft, 100}, {BatteryRight, 40}, {BatteryCase, 77}})
+	if got != "L 100% R 40% C 77%" {
+		t.Errorf("unexpected format: %q", got)
+	}
+	if got := FormatBatteries(nil); got != "" {
+		t.Errorf("expected empty string, got %q", got)
+	}
+}
@@ -0,0 +1,369 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrBluetoothctlNotInstalled is returned when bluetoothctl is required but not available.
+var ErrBluetoothctlNotInstalled = errors.New("bluetoothctl required — install bluez")
+
+func init() {
+	RegisterBackend("bluetoothctl", func(opts BackendOptions) (Backend, error) {
+		return newBluetoothctlBackend(opts.Runner, opts.LookPath), nil
+	})
+}
+
+// bluetoothctlBackend drives BlueZ on Linux through the bluetoothctl CLI.
+type bluetoothctlBackend struct {
+	run      CommandRunner
+	lookPath func(file string) (string, error)
+}
+
+// NewBluetoothctlBackend returns the Linux BlueZ backend. A nil runner uses ExecRunner.
+func NewBluetoothctlBackend(run CommandRunner) Backend {
+	return newBluetoothctlBackend(run, nil)
+}
+
+// newBluetoothctlBackend returns the bluetoothctl backend with an injectable lookPath.
+func newBluetoothctlBackend(run CommandRunner, lookPath func(string) (string, error)) *bluetoothctlBackend {
+	if run == nil {
+		run = ExecRunner
+	}
+	if lookPath == nil {
+		lookPath = exec.LookPath
+	}
+	return &bluetoothctlBackend{run: run, lookPath: lookPath}
+}
+
+// Name returns "bluetoothctl".
+func (b *bluetoothctlBackend) Name() string {
+	return "bluetoothctl"
+}
+
+// Snapshot combines bluetoothctl show, devices, and info for each device.
+// Only devices info reports as paired are kept. A device whose info cannot
+// be read, e.g. one being removed, keeps only the name and address from
+// devices if bluetoothctl could list paired devices alone, and is dropped
+// otherwise.
+func (b *bluetoothctlBackend) Snapshot(ctx context.Context) (*Snapshot, error) {
+	showOut, err := b.run(ctx, "bluetoothctl", "show")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run bluetoothctl show: %w", err)
+	}
+	powerState, info := parseBluetoothctlShow(string(showOut))
+
+	listedPaired, pairedOnly, err := b.listDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	for _, listed := range listedPaired {
+		infoOut, err := b.run(ctx, "bluetoothctl", "info", listed.Address)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to run bluetoothctl info %s: %w", listed.Address, err)
+		}
+		if err == nil {
+			// bluetoothctl exits 0 for "Device ... not available".
+			err = bluetoothctlFailure(string(infoOut))
+		}
+		if err != nil {
+			if pairedOnly {
+				devices = append(devices, listed)
+			}
+			continue
+		}
+		d, paired := parseBluetoothctlInfo(string(infoOut))
+		if !paired {
+			continue
+		}
+		if d.Address == "" {
+			d.Address = listed.Address
+		}
+		if d.Name == "" {
+			d.Name = listed.Name
+		}
+		devices = append(devices, d)
+	}
+
+	return &Snapshot{
+		PowerState:     powerState,
+		ControllerInfo: info,
+		Devices:        devices,
+	}, nil
+}
+
+// listDevices lists the known devices, paired ones only if bluetoothctl
+// supports "devices Paired" (BlueZ 5.65 and later); pairedOnly reports
+// whether it did. Older releases reject the argument, so every known
+// device is listed and the caller checks each one's info.
+func (b *bluetoothctlBackend) listDevices(ctx context.Context) (devices []Device, pairedOnly bool, err error) {
+	out, err := b.run(ctx, "bluetoothctl", "devices", "Paired")
+	if err == nil && !strings.Contains(string(out), "Too many arguments") {
+		return parseBluetoothctlDevices(string(out)), true, nil
+	}
+	if ctx.Err() != nil {
+		return nil, false, fmt.Errorf("failed to run bluetoothctl devices: %w", err)
+	}
+	out, err = b.run(ctx, "bluetoothctl", "devices")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to run bluetoothctl devices: %w", err)
+	}
+	return parseBluetoothctlDevices(string(out)), false, nil
+}
+
+// CanControl returns ErrBluetoothctlNotInstalled if bluetoothctl is missing.
+func (b *bluetoothctlBackend) CanControl(ctx context.Context) error {
+	if _, err := b.lookPath("bluetoothctl"); err != nil {
+		return ErrBluetoothctlNotInstalled
+	}
+	return nil
+}
+
+// Tools reports whether bluetoothctl is installed.
+func (b *bluetoothctlBackend) Tools(ctx context.Context) map[string]bool {
+	return map[string]bool{"bluetoothctl": b.CanControl(ctx) == nil}
+}
+
+// Connect connects to a device by address.
+func (b *bluetoothctlBackend) Connect(ctx context.Context, address string) error {
+	if err := b.control(ctx, "connect", address); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	return nil
+}
+
+// Disconnect disconnects a device by address.
+func (b *bluetoothctlBackend) Disconnect(ctx context.Context, address string) error {
+	if err := b.control(ctx, "disconnect", address); err != nil {
+		return fmt.Errorf("failed to disconnect %s: %w", address, err)
+	}
+	return nil
+}
+
+// Remove unpairs a device by address.
+func (b *bluetoothctlBackend) Remove(ctx context.Context, address string) error {
+	if err := b.control(ctx, "remove", address); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", address, err)
+	}
+	return nil
+}
+
+// SetPower turns the default adapter on or off.
+func (b *bluetoothctlBackend) SetPower(ctx context.Context, on bool) error {
+	state := "off"
+	if on {
+		state = "on"
+	}
+	if err := b.control(ctx, "power", state); err != nil {
+		return fmt.Errorf("failed to set power state: %w", err)
+	}
+	return nil
+}
+
+// Reset restarts the bluetooth systemd service.
+func (b *bluetoothctlBackend) Reset(ctx context.Context) error {
+	_, err := b.run(ctx, "systemctl", "restart", "bluetooth")
+	if err != nil {
+		return fmt.Errorf("failed to reset bluetooth (sudo required): %w", err)
+	}
+	return nil
+}
+
+// ReadLog is not supported; BlueZ does not log in the macOS unified log format.
+func (b *bluetoothctlBackend) ReadLog(ctx context.Context, q LogQuery) ([]byte, error) {
+	return nil, fmt.Errorf("%w: log retrieval (bluetoothctl)", ErrUnsupported)
+}
+
+// StreamLog is not supported, for the same reason as ReadLog.
+func (b *bluetoothctlBackend) StreamLog(ctx context.Context, fn func(line string)) error {
+	return fmt.Errorf("%w: log streaming (bluetoothctl)", ErrUnsupported)
+}
+
+// control runs a bluetoothctl subcommand and checks its output for failures.
+// bluetoothctl often exits 0 even when the operation failed.
+func (b *bluetoothctlBackend) control(ctx context.Context, args ...string) error {
+	if err := b.CanControl(ctx); err != nil {
+		return err
+	}
+	out, err := b.run(ctx, "bluetoothctl", args...)
+	if err != nil {
+		return err
+	}
+	return bluetoothctlFailure(string(out))
+}
+
+// bluetoothctlFailure returns an error for the first failure line in output, or nil.
+func bluetoothctlFailure(output string) error {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Failed to") ||
+			strings.HasPrefix(line, "Device ") && strings.HasSuffix(line, "not available") ||
+			strings.HasPrefix(line, "No default controller available") {
+			return errors.New(line)
+		}
+	}
+	return nil
+}
+
+// parseBluetoothctlDevices parses `bluetoothctl devices` output.
+// Each line has the form "Device <address> <name>". Only Name and Address are set.
+func parseBluetoothctlDevices(output string) []Device {
+	var devices []Device
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+		if len(fields) < 2 || fields[0] != "Device" {
+			continue
+		}
+		d := Device{Address: fields[1], BatteryLevel: -1}
+		if len(fields) == 3 {
+			d.Name = fields[2]
+		}
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+// parseBluetoothctlInfo parses `bluetoothctl info <addr>` output into a device.
+// The second result is true only when the output says "Paired: yes".
+func parseBluetoothctlInfo(output string) (Device, bool) {
+	d := Device{BatteryLevel: -1}
+	var details DeviceDetails
+	paired := false
+	var alias string
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Device ") {
+			if fields := strings.Fields(trimmed); len(fields) >= 2 {
+				d.Address = fields[1]
+			}
+			continue
+		}
+		key, value, ok := splitBluetoothctlField(trimmed)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Name":
+			d.Name = value
+		case "Alias":
+			alias = value
+		case "Icon":
+			d.MinorType = minorTypeFromIcon(value)
+		case "Paired":
+			paired = value == "yes"
+		case "Connected":
+			d.Connected = value == "yes"
+		case "Battery Percentage":
+			if level, err := strconv.Atoi(bluetoothctlNumber(value)); err == nil && level >= 0 && level <= 100 {
+				d.BatteryLevel = level
+			}
+		case "RSSI":
+			if rssi, err := strconv.Atoi(bluetoothctlNumber(value)); err == nil {
+				d.RSSI = rssi
+			}
+		case "Modalias":
+			details.VendorSource, details.VendorID, details.ProductID = parseModalias(value)
+		case "UUID":
+			// "Audio Sink                (0000110b-...)" -> "Audio Sink"
+			if name, _, _ := strings.Cut(value, "("); strings.TrimSpace(name) != "" {
+				details.Services = append(details.Services, strings.TrimSpace(name))
+			}
+		}
+	}
+
+	if d.Name == "" {
+		d.Name = alias
+	}
+	if details.VendorID != "" || len(details.Services) > 0 {
+		d.Details = &details
+	}
+	return d, paired
+}
+
+// parseBluetoothctlShow parses `bluetoothctl show` output into a power state
+// and controller properties keyed like system_profiler's controller_* fields.
+func parseBluetoothctlShow(output string) (string, map[string]string) {
+	info := make(map[string]string)
+
+	inController := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		// Unindented lines start a section; only the Controller section is kept.
+		if trimmed == line {
+			inController = strings.HasPrefix(trimmed, "Controller ")
+			if fields := strings.Fields(trimmed); inController && len(fields) >= 2 {
+				info["controller_address"] = fields[1]
+			}
+			continue
+		}
+		if !inController {
+			continue
+		}
+		key, value, ok := splitBluetoothctlField(trimmed)
+		// UUID and Roles repeat, so they don't fit a flat map.
+		if !ok || key == "UUID" || key == "Roles" {
+			continue
+		}
+		info["controller_"+strings.ToLower(strings.ReplaceAll(key, " ", "_"))] = value
+	}
+
+	if len(info) == 0 {
+		return "unknown", nil
+	}
+	return powerStateFromInfo(info), info
+}
+
+// splitBluetoothctlField splits an indented "Key: value" line.
+func splitBluetoothctlField(line string) (string, string, bool) {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok || key == "" {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(value), true
+}
+
+// bluetoothctlNumber extracts the decimal value from fields such as
+// "0x55 (85)" or "-56". The parenthesised form is preferred.
+func bluetoothctlNumber(value string) string {
+	if open := strings.LastIndex(value, "("); open >= 0 {
+		if end := strings.Index(value[open:], ")"); end > 0 {
+			return strings.TrimSpace(value[open+1 : open+end])
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+// minorTypeFromIcon maps a BlueZ icon name to a system_profiler-style minor type.
+func minorTypeFromIcon(icon string) string {
+	switch icon {
+	case "audio-headphones":
+		return "Headphones"
+	case "audio-headset":
+		return "Headset"
+	case "audio-card":
+		return "Speaker"
+	case "input-keyboard":
+		return "Keyboard"
+	case "input-mouse":
+		return "Mouse"
+	case "input-tablet":
+		return "Tablet"
+	case "input-gaming":
+		return "Gamepad"
+	case "phone":
+		return "Phone"
+	case "computer":
+		return "Computer"
+	}
+	return ""
+}
@@ -0,0 +1,361 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const bluetoothctlShowOutput = `Controller BC:D0:74:22:43:D6 (public)
+	Name: thinkpad
+	Alias: thinkpad
+	Class: 0x006c010c
+	Powered: yes
+	Discoverable: no
+	DiscoverableTimeout: 0x000000b4 (180)
+	Pairable: yes
+	UUID: A/V Remote Control        (0000110e-0000-1000-8000-00805f9b34fb)
+	UUID: Audio Sink                (0000110b-0000-1000-8000-00805f9b34fb)
+	Modalias: usb:v1D6Bp0246d0548
+	Discovering: no
+	Roles: central
+	Roles: peripheral
+Advertising Features:
+	ActiveInstances: 0x00 (0)
+	SupportedInstances: 0x05 (5)
+`
+
+const bluetoothctlDevicesOutput = `Device 70:F9:4A:7A:8B:CA AirPods Max
+Device 74:15:F5:4E:D0:50 AirPods Pro
+Device A8:91:3D:DE:91:C6 Beats Flex
+Device 11:22:33:44:55:66 Nearby Phone
+`
+
+const bluetoothctlPairedDevicesOutput = `Device 70:F9:4A:7A:8B:CA AirPods Max
+Device 74:15:F5:4E:D0:50 AirPods Pro
+Device A8:91:3D:DE:91:C6 Beats Flex
+`
+
+const bluetoothctlInfoAirPodsMax = `Device 70:F9:4A:7A:8B:CA (public)
+	Name: AirPods Max
+	Alias: AirPods Max
+	Class: 0x00240418
+	Icon: audio-headphones
+	Paired: yes
+	Bonded: yes
+	Trusted: yes
+	Blocked: no
+	Connected: yes
+	LegacyPairing: no
+	UUID: Audio Sink                (0000110b-0000-1000-8000-00805f9b34fb)
+	Modalias: bluetooth:v004Cp201Fd0000
+	RSSI: 0xffffffc8 (-56)
+	Battery Percentage: 0x55 (85)
+`
+
+const bluetoothctlInfoAirPodsPro = `Device 74:15:F5:4E:D0:50 (public)
+	Name: AirPods Pro
+	Alias: AirPods Pro
+	Icon: audio-headset
+	Paired: yes
+	Connected: no
+	RSSI: -72
+`
+
+const bluetoothctlInfoBeatsFlex = `Device A8:91:3D:DE:91:C6 (public)
+	Alias: Beats Flex
+	Paired: yes
+	Connected: no
+`
+
+const bluetoothctlInfoUnpaired = `Device 11:22:33:44:55:66 (random)
+	Name: Nearby Phone
+	Alias: Nearby Phone
+	Paired: no
+	Connected: no
+`
+
+// fakeBluetoothctl serves canned bluetoothctl output keyed by arguments.
+func fakeBluetoothctl(t *testing.T) CommandRunner {
+	t.Helper()
+	outputs := map[string]string{
+		"show":                   bluetoothctlShowOutput,
+		"devices":                bluetoothctlDevicesOutput,
+		"devices Paired":         bluetoothctlPairedDevicesOutput,
+		"info 70:F9:4A:7A:8B:CA": bluetoothctlInfoAirPodsMax,
+		"info 74:15:F5:4E:D0:50": bluetoothctlInfoAirPodsPro,
+		"info A8:91:3D:DE:91:C6": bluetoothctlInfoBeatsFlex,
+		"info 11:22:33:44:55:66": bluetoothctlInfoUnpaired,
+	}
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name != "bluetoothctl" {
+			t.Errorf("unexpected command %s", name)
+		}
+		out, ok := outputs[strings.Join(args, " ")]
+		if !ok {
+			return nil, errors.New("unexpected args: " + strings.Join(args, " "))
+		}
+		return []byte(out), nil
+	}
+}
+
+func TestParseBluetoothctlDevices(t *testing.T) {
+	devices := parseBluetoothctlDevices(bluetoothctlDevicesOutput + "garbage line\n")
+	if len(devices) != 4 {
+		t.Fatalf("expected 4 devices, got %d", len(devices))
+	}
+	if devices[0].Address != "70:F9:4A:7A:8B:CA" || devices[0].Name != "AirPods Max" {
+		t.Errorf("unexpected first device: %+v", devices[0])
+	}
+}
+
+func TestParseBluetoothctlInfo(t *testing.T) {
+	d, paired := parseBluetoothctlInfo(bluetoothctlInfoAirPodsMax)
+	if !paired {
+		t.Error("expected paired")
+	}
+	if d.Name != "AirPods Max" {
+		t.Errorf("expected name AirPods Max, got %s", d.Name)
+	}
+	if d.Address != "70:F9:4A:7A:8B:CA" {
+		t.Errorf("expected address 70:F9:4A:7A:8B:CA, got %s", d.Address)
+	}
+	if !d.Connected {
+		t.Error("expected connected")
+	}
+	if d.BatteryLevel != 85 {
+		t.Errorf("expected BatteryLevel 85, got %d", d.BatteryLevel)
+	}
+	if d.RSSI != -56 {
+		t.Errorf("expected RSSI -56, got %d", d.RSSI)
+	}
+	if d.MinorType != "Headphones" {
+		t.Errorf("expected MinorType Headphones, got %s", d.MinorType)
+	}
+	if d.Details == nil || d.Details.VendorID != "0x004C" || d.Details.ProductID != "0x201F" {
+		t.Errorf("expected vendor and product from Modalias, got %+v", d.Details)
+	} else if len(d.Details.Services) != 1 || d.Details.Services[0] != "Audio Sink" {
+		t.Errorf("expected Audio Sink service, got %v", d.Details.Services)
+	}
+}
+
+func TestParseBluetoothctlInfo_Fallbacks(t *testing.T) {
+	d, _ := parseBluetoothctlInfo(bluetoothctlInfoBeatsFlex)
+	if d.Name != "Beats Flex" {
+		t.Errorf("expected alias as name, got %s", d.Name)
+	}
+	if d.BatteryLevel != -1 {
+		t.Errorf("expected BatteryLevel -1, got %d", d.BatteryLevel)
+	}
+	if d.RSSI != 0 {
+		t.Errorf("expected RSSI 0, got %d", d.RSSI)
+	}
+
+	d, _ = parseBluetoothctlInfo(bluetoothctlInfoAirPodsPro)
+	if d.RSSI != -72 {
+		t.Errorf("expected plain RSSI -72, got %d", d.RSSI)
+	}
+
+	_, paired := parseBluetoothctlInfo(bluetoothctlInfoUnpaired)
+	if paired {
+		t.Error("expected unpaired")
+	}
+}
+
+func TestParseBluetoothctlShow(t *testing.T) {
+	state, info := parseBluetoothctlShow(bluetoothctlShowOutput)
+	if state != "on" {
+		t.Errorf("expected power state on, got %s", state)
+	}
+	if info["controller_address"] != "BC:D0:74:22:43:D6" {
+		t.Errorf("expected controller address, got %s", info["controller_address"])
+	}
+	if info["controller_name"] != "thinkpad" {
+		t.Errorf("expected controller name thinkpad, got %s", info["controller_name"])
+	}
+	for _, k := range []string{"controller_uuid", "controller_roles", "controller_activeinstances"} {
+		if _, ok := info[k]; ok {
+			t.Errorf("unexpected key %s", k)
+		}
+	}
+
+	state, _ = parseBluetoothctlShow("Controller AA:BB:CC:DD:EE:FF (public)\n\tPowered: no\n")
+	if state != "off" {
+		t.Errorf("expected power state off, got %s", state)
+	}
+
+	state, info = parseBluetoothctlShow("No default controller available\n")
+	if state != "unknown" || info != nil {
+		t.Errorf("expected unknown with no info, got %s %v", state, info)
+	}
+}
+
+func TestBluetoothctlBackend_Snapshot(t *testing.T) {
+	t.Parallel()
+	b := newBluetoothctlBackend(fakeBluetoothctl(t), nil)
+
+	snap, err := b.Snapshot(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snap.PowerState != "on" {
+		t.Errorf("expected power state on, got %s", snap.PowerState)
+	}
+	if len(snap.Devices) != 3 {
+		t.Fatalf("expected 3 paired devices, got %d", len(snap.Devices))
+	}
+	connected := 0
+	for _, d := range snap.Devices {
+		if d.Connected {
+			connected++
+		}
+	}
+	if connected != 1 {
+		t.Errorf("expected 1 connected device, got %d", connected)
+	}
+}
+
+func TestBluetoothctlBackend_SnapshotInfoFails(t *testing.T) {
+	t.Parallel()
+	fake := fakeBluetoothctl(t)
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if strings.Join(args, " ") == "info 74:15:F5:4E:D0:50" {
+			return nil, errors.New("Device 74:15:F5:4E:D0:50 not available")
+		}
+		return fake(ctx, name, args...)
+	}
+
+	snap, err := newBluetoothctlBackend(run, nil).Snapshot(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snap.Devices) != 3 {
+		t.Fatalf("expected 3 paired devices, got %d", len(snap.Devices))
+	}
+	pro := snap.Devices[1]
+	if pro.Name != "AirPods Pro" || pro.Address != "74:15:F5:4E:D0:50" || pro.Connected || pro.BatteryLevel != -1 {
+		t.Errorf("expected AirPods Pro from devices only, got %+v", pro)
+	}
+	if snap.Devices[0].Name != "AirPods Max" || snap.Devices[0].BatteryLevel != 85 {
+		t.Errorf("expected the other devices in full, got %+v", snap.Devices[0])
+	}
+}
+
+func TestBluetoothctlBackend_SnapshotNotAvailable(t *testing.T) {
+	t.Parallel()
+	fake := fakeBluetoothctl(t)
+	for _, tt := range []struct {
+		name       string
+		pairedOnly bool
+		want       int
+	}{
+		// The device was listed as paired, so it is kept from the listing.
+		{"devices Paired", true, 3},
+		// Older bluetoothctl lists every known device; one whose info
+		// cannot be read is not known to be paired.
+		{"devices", false, 2},
+	} {
+		run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			switch strings.Join(args, " ") {
+			case "info 74:15:F5:4E:D0:50":
+				return []byte("Device 74:15:F5:4E:D0:50 not available\n"), nil
+			case "devices Paired":
+				if !tt.pairedOnly {
+					return []byte("Too many arguments: 1 > 0\n"), nil
+				}
+			}
+			return fake(ctx, name, args...)
+		}
+
+		snap, err := newBluetoothctlBackend(run, nil).Snapshot(t.Context())
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if len(snap.Devices) != tt.want {
+			t.Fatalf("%s: expected %d devices, got %+v", tt.name, tt.want, snap.Devices)
+		}
+		for _, d := range snap.Devices {
+			if d.Name == "Nearby Phone" {
+				t.Errorf("%s: expected the unpaired device dropped", tt.name)
+			}
+			if d.Address == "74:15:F5:4E:D0:50" && (d.Connected || d.BatteryLevel != -1) {
+				t.Errorf("%s: expected AirPods Pro from the listing only, got %+v", tt.name, d)
+			}
+		}
+	}
+
+	if _, paired := parseBluetoothctlInfo("Device 74:15:F5:4E:D0:50 not available\n"); paired {
+		t.Error("expected info without a Paired line not to count as paired")
+	}
+}
+
+func TestBluetoothctlBackend_Control(t *testing.T) {
+	t.Parallel()
+	lookPath := func(file string) (string, error) { return "/usr/bin/bluetoothctl", nil }
+
+	var calls []string
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		return []byte("Attempting to connect to AA:BB:CC:DD:EE:FF\n"), nil
+	}
+	b := newBluetoothctlBackend(run, lookPath)
+
+	if err := b.Connect(t.Context(), "AA:BB:CC:DD:EE:FF"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Disconnect(t.Context(), "AA:BB:CC:DD:EE:FF"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Remove(t.Context(), "AA:BB:CC:DD:EE:FF"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.SetPower(t.Context(), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"bluetoothctl connect AA:BB:CC:DD:EE:FF",
+		"bluetoothctl disconnect AA:BB:CC:DD:EE:FF",
+		"bluetoothctl remove AA:BB:CC:DD:EE:FF",
+		"bluetoothctl power off",
+	}
+	for i := range want {
+		if i >= len(calls) || calls[i] != want[i] {
+			t.Errorf("call[%d]: expected %q, got %v", i, want[i], calls)
+		}
+	}
+}
+
+func TestBluetoothctlBackend_ControlFailureOutput(t *testing.T) {
+	t.Parallel()
+	lookPath := func(file string) (string, error) { return "/usr/bin/bluetoothctl", nil }
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("Attempting to connect to AA:BB:CC:DD:EE:FF\nFailed to connect: org.bluez.Error.Failed\n"), nil
+	}
+	b := newBluetoothctlBackend(run, lookPath)
+
+	err := b.Connect(t.Context(), "AA:BB:CC:DD:EE:FF")
+	if err == nil || !strings.Contains(err.Error(), "org.bluez.Error.Failed") {
+		t.Errorf("expected bluez failure, got %v", err)
+	}
+}
+
+func TestBluetoothctlBackend_NotInstalled(t *testing.T) {
+	t.Parallel()
+	lookPath := func(file string) (string, error) { return "", errors.New("not found") }
+	b := newBluetoothctlBackend(nil, lookPath)
+
+	if err := b.Connect(t.Context(), "AA:BB:CC:DD:EE:FF"); !errors.Is(err, ErrBluetoothctlNotInstalled) {
+		t.Errorf("expected ErrBluetoothctlNotInstalled, got %v", err)
+	}
+}
+
+func TestBluetoothctlBackend_ReadLogUnsupported(t *testing.T) {
+	t.Parallel()
+	b := newBluetoothctlBackend(nil, nil)
+
+	if _, err := b.ReadLog(t.Context(), LogQuery{Last: "5m"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,391 @@
+package bluetooth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// BlueZ D-Bus names.
+const (
+	bluezService       = "org.bluez"
+	bluezAdapterIface  = "org.bluez.Adapter1"
+	bluezDeviceIface   = "org.bluez.Device1"
+	bluezBatteryIface  = "org.bluez.Battery1"
+	objectManagerIface = "org.freedesktop.DBus.ObjectManager"
+)
+
+func init() {
+	RegisterBackend("bluez", func(opts BackendOptions) (Backend, error) {
+		if opts.Runner != nil {
+			return nil, fmt.Errorf("%w: command recording (bluez)", ErrUnsupported)
+		}
+		return NewBluezBackend(nil), nil
+	})
+}
+
+// bluezObjects is the reply of ObjectManager.GetManagedObjects:
+// object path -> interface -> property -> value.
+type bluezObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+// bluezBackend talks to org.bluez over D-Bus.
+type bluezBackend struct {
+	dial func(ctx context.Context) (*dbus.Conn, error)
+}
+
+// NewBluezBackend returns the BlueZ D-Bus backend. A nil dial connects to the system bus.
+func NewBluezBackend(dial func(ctx context.Context) (*dbus.Conn, error)) Backend {
+	if dial == nil {
+		dial = func(ctx context.Context) (*dbus.Conn, error) {
+			return dbus.ConnectSystemBus(dbus.WithContext(ctx))
+		}
+	}
+	return &bluezBackend{dial: dial}
+}
+
+// Name returns "bluez".
+func (b *bluezBackend) Name() string {
+	return "bluez"
+}
+
+// connect opens a bus connection and fetches the BlueZ object tree.
+func (b *bluezBackend) connect(ctx context.Context) (*dbus.Conn, bluezObjects, error) {
+	start := time.Now()
+	conn, err := b.dial(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, contextError(ctx, "dbus", start)
+		}
+		return nil, nil, fmt.Errorf("failed to connect to D-Bus: %w", err)
+	}
+	var objects bluezObjects
+	err = b.call(ctx, conn, "/", objectManagerIface+".GetManagedObjects").Store(&objects)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to query %s: %w", bluezService, err)
+	}
+	return conn, objects, nil
+}
+
+// call invokes a BlueZ method, reporting an expired deadline as a
+// TimeoutError for bluetoothd.
+func (b *bluezBackend) call(ctx context.Context, conn *dbus.Conn, path dbus.ObjectPath, method string, args ...any) *dbus.Call {
+	start := time.Now()
+	call := conn.Object(bluezService, path).CallWithContext(ctx, method, 0, args...)
+	if call.Err != nil && ctx.Err() != nil {
+		call.Err = contextError(ctx, "bluetoothd", start)
+	}
+	return call
+}
+
+// Snapshot reads adapters, devices, and batteries from the object manager.
+func (b *bluezBackend) Snapshot(ctx context.Context) (*Snapshot, error) {
+	conn, objects, err := b.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return parseBluezObjects(objects), nil
+}
+
+// CanControl returns an error if the BlueZ service is unreachable.
+func (b *bluezBackend) CanControl(ctx context.Context) error {
+	conn, _, err := b.connect(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Tools returns nil; the backend talks to bluetoothd over D-Bus directly.
+func (b *bluezBackend) Tools(ctx context.Context) map[string]bool {
+	return nil
+}
+
+// Connect calls Device1.Connect on the device.
+func (b *bluezBackend) Connect(ctx context.Context, address string) error {
+	if err := b.callDevice(ctx, address, "Connect"); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	return nil
+}
+
+// Disconnect calls Device1.Disconnect on the device.
+func (b *bluezBackend) Disconnect(ctx context.Context, address string) error {
+	if err := b.callDevice(ctx, address, "Disconnect"); err != nil {
+		return fmt.Errorf("failed to disconnect %s: %w", address, err)
+	}
+	return nil
+}
+
+// Remove calls Adapter1.RemoveDevice on the device's adapter.
+func (b *bluezBackend) Remove(ctx context.Context, address string) error {
+	conn, objects, err := b.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	path, adapter, ok := findBluezDevice(objects, address)
+	if !ok {
+		return fmt.Errorf("device not found: %s", address)
+	}
+	err = b.call(ctx, conn, adapter, bluezAdapterIface+".RemoveDevice", path).Err
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %w", address, err)
+	}
+	return nil
+}
+
+// SetPower sets the Powered property of the default adapter.
+func (b *bluezBackend) SetPower(ctx context.Context, on bool) error {
+	conn, objects, err := b.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	adapter, ok := defaultBluezAdapter(objects)
+	if !ok {
+		return fmt.Errorf("failed to set power state: no adapter found")
+	}
+	err = b.call(ctx, conn, adapter, "org.freedesktop.DBus.Properties.Set",
+		bluezAdapterIface, "Powered", dbus.MakeVariant(on)).Err
+	if err != nil {
+		return fmt.Errorf("failed to set power state: %w", err)
+	}
+	return nil
+}
+
+// Reset power-cycles the default adapter.
+func (b *bluezBackend) Reset(ctx context.Context) error {
+	if err := b.SetPower(ctx, false); err != nil {
+		return fmt.Errorf("failed to reset bluetooth: %w", err)
+	}
+	if err := b.SetPower(ctx, true); err != nil {
+		return fmt.Errorf("failed to reset bluetooth: %w", err)
+	}
+	return nil
+}
+
+// ReadLog is not supported; BlueZ exposes no log over D-Bus.
+func (b *bluezBackend) ReadLog(ctx context.Context, q LogQuery) ([]byte, error) {
+	return nil, fmt.Errorf("%w: log retrieval (bluez)", ErrUnsupported)
+}
+
+// StreamLog is not supported, for the same reason as ReadLog.
+func (b *bluezBackend) StreamLog(ctx context.Context, fn func(line string)) error {
+	return fmt.Errorf("%w: log streaming (bluez)", ErrUnsupported)
+}
+
+// callDevice invokes a no-argument Device1 method on the device with address.
+func (b *bluezBackend) callDevice(ctx context.Context, address, method string) error {
+	conn, objects, err := b.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	path, _, ok := findBluezDevice(objects, address)
+	if !ok {
+		return fmt.Errorf("device not found: %s", address)
+	}
+	return b.call(ctx, conn, path, bluezDeviceIface+"."+method).Err
+}
+
+// parseBluezObjects converts a GetManagedObjects reply into a snapshot.
+// Every adapter becomes a controller; PowerState and ControllerInfo
+// describe the default adapter.
+func parseBluezObjects(objects bluezObjects) *Snapshot {
+	snap := &Snapshot{PowerState: "unknown"}
+	adapterAddrs := make(map[dbus.ObjectPath]string)
+
+	defaultAdapter, _ := defaultBluezAdapter(objects)
+	for _, path := range sortedBluezPaths(objects) {
+		props, ok := objects[path][bluezAdapterIface]
+		if !ok {
+			continue
+		}
+		info := map[string]string{"controller_path": string(path)}
+		for _, key := range []string{"Address", "Name", "Alias", "Class", "Modalias", "Powered", "Discoverable", "Pairable"} {
+			if v, ok := props[key]; ok {
+				info["controller_"+strings.ToLower(key)] = variantString(v)
+			}
+		}
+		c := controllerFromInfo(info)
+		uuids, _ := props["UUIDs"].Value().([]string)
+		c.Services = bluezServiceNames(uuids)
+		adapterAddrs[path] = c.Address
+		snap.Controllers = append(snap.Controllers, c)
+		if path == defaultAdapter {
+			snap.ControllerInfo = info
+			snap.PowerState = c.State
+		}
+	}
+
+	for _, path := range sortedBluezPaths(objects) {
+		props, ok := objects[path][bluezDeviceIface]
+		if !ok {
+			continue
+		}
+		if paired, _ := props["Paired"].Value().(bool); !paired {
+			continue
+		}
+		d := Device{
+			Name:         variantString(props["Name"]),
+			Address:      variantString(props["Address"]),
+			BatteryLevel: -1,
+		}
+		if d.Name == "" {
+			d.Name = variantString(props["Alias"])
+		}
+		d.Controller = adapterAddrs[bluezDeviceAdapter(path, props)]
+		if icon, ok := props["Icon"].Value().(string); ok {
+			d.MinorType = minorTypeFromIcon(icon)
+		}
+		d.Connected, _ = props["Connected"].Value().(bool)
+		if rssi, ok := props["RSSI"].Value().(int16); ok {
+			d.RSSI = int(rssi)
+		}
+		var details DeviceDetails
+		if modalias, ok := props["Modalias"].Value().(string); ok {
+			details.VendorSource, details.VendorID, details.ProductID = parseModalias(modalias)
+		}
+		uuids, _ := props["UUIDs"].Value().([]string)
+		details.Services = bluezServiceNames(uuids)
+		if details.VendorID != "" || len(details.Services) > 0 {
+			d.Details = &details
+		}
+		if battery, ok := objects[path][bluezBatteryIface]; ok {
+			if pct, ok := battery["Percentage"].Value().(byte); ok && pct <= 100 {
+				d.BatteryLevel = int(pct)
+			}
+		}
+		snap.Devices = append(snap.Devices, d)
+	}
+
+	return snap
+}
+
+// bluetoothServiceNames are the names bluetoothctl prints for well-known
+// 16-bit service UUIDs, from BlueZ's own table.
+var bluetoothServiceNames = map[uint16]string{
+	0x1101: "Serial Port",
+	0x1103: "Dialup Networking",
+	0x1105: "OBEX Object Push",
+	0x1106: "OBEX File Transfer",
+	0x1108: "Headset",
+	0x110a: "Audio Source",
+	0x110b: "Audio Sink",
+	0x110c: "A/V Remote Control Target",
+	0x110d: "Advanced Audio Distribution",
+	0x110e: "A/V Remote Control",
+	0x110f: "A/V Remote Control Controller",
+	0x1112: "Headset AG",
+	0x1115: "PANU",
+	0x1116: "NAP",
+	0x111e: "Handsfree",
+	0x111f: "Handsfree Audio Gateway",
+	0x1124: "Human Interface Device",
+	0x112d: "SIM Access",
+	0x112f: "Phonebook Access Server",
+	0x1131: "Headset HS",
+	0x1132: "Message Access Server",
+	0x1133: "Message Notification Server",
+	0x1200: "PnP Information",
+	0x1203: "Generic Audio",
+	0x1800: "Generic Access Profile",
+	0x1801: "Generic Attribute Profile",
+	0x180a: "Device Information",
+	0x180f: "Battery Service",
+	0x1812: "Human Interface Device",
+}
+
+// bluetoothBaseUUID is the suffix of a 16-bit UUID in 128-bit form.
+const bluetoothBaseUUID = "-0000-1000-8000-00805f9b34fb"
+
+// bluezServiceNames converts service UUIDs to the names bluetoothctl
+// prints for them, so both Linux backends report the same services.
+// UUIDs without a well-known name, such as vendor services, are kept as is.
+func bluezServiceNames(uuids []string) []string {
+	if len(uuids) == 0 {
+		return nil
+	}
+	out := make([]string, len(uuids))
+	for i, uuid := range uuids {
+		out[i] = uuid
+		u := strings.ToLower(uuid)
+		if len(u) != 36 || !strings.HasPrefix(u, "0000") || !strings.HasSuffix(u, bluetoothBaseUUID) {
+			continue
+		}
+		if n, err := strconv.ParseUint(u[4:8], 16, 16); err == nil && bluetoothServiceNames[uint16(n)] != "" {
+			out[i] = bluetoothServiceNames[uint16(n)]
+		}
+	}
+	return out
+}
+
+// defaultBluezAdapter returns the adapter with the lowest object path (usually hci0).
+func defaultBluezAdapter(objects bluezObjects) (dbus.ObjectPath, bool) {
+	for _, path := range sortedBluezPaths(objects) {
+		if _, ok := objects[path][bluezAdapterIface]; ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// findBluezDevice returns the object path and adapter path of the device with address.
+func findBluezDevice(objects bluezObjects, address string) (dbus.ObjectPath, dbus.ObjectPath, bool) {
+	for _, path := range sortedBluezPaths(objects) {
+		props, ok := objects[path][bluezDeviceIface]
+		if !ok || !strings.EqualFold(variantString(props["Address"]), address) {
+			continue
+		}
+		return path, bluezDeviceAdapter(path, props), true
+	}
+	return "", "", false
+}
+
+// bluezDeviceAdapter returns the adapter a device object belongs to, from its
+// Adapter property or, failing that, its parent path.
+func bluezDeviceAdapter(path dbus.ObjectPath, props map[string]dbus.Variant) dbus.ObjectPath {
+	if adapter, ok := props["Adapter"].Value().(dbus.ObjectPath); ok {
+		return adapter
+	}
+	return path[:strings.LastIndex(string(path), "/")]
+}
+
+// sortedBluezPaths returns the object paths in lexical order for stable output.
+func sortedBluezPaths(objects bluezObjects) []dbus.ObjectPath {
+	paths := make([]dbus.ObjectPath, 0, len(objects))
+	for path := range objects {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+	return paths
+}
+
+// variantString formats a D-Bus property value the way bluetoothctl prints it.
+func variantString(v dbus.Variant) string {
+	switch val := v.Value().(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		if val {
+			return "yes"
+		}
+		return "no"
+	case uint32:
+		return fmt.Sprintf("0x%08x", val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,345 @@
+package bluetooth
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	fakeAdapterPath = dbus.ObjectPath("/org/bluez/hci0")
+	fakeMaxPath     = dbus.ObjectPath("/org/bluez/hci0/dev_70_F9_4A_7A_8B_CA")
+	fakeFlexPath    = dbus.ObjectPath("/org/bluez/hci0/dev_A8_91_3D_DE_91_C6")
+	fakePhonePath   = dbus.ObjectPath("/org/bluez/hci0/dev_11_22_33_44_55_66")
+)
+
+// fakeBluezObjects returns a BlueZ tree with one adapter and three devices.
+func fakeBluezObjects() bluezObjects {
+	return bluezObjects{
+		"/org/bluez": {
+			"org.bluez.AgentManager1": {},
+		},
+		fakeAdapterPath: {
+			bluezAdapterIface: {
+				"Address":      dbus.MakeVariant("BC:D0:74:22:43:D6"),
+				"Name":         dbus.MakeVariant("thinkpad"),
+				"Alias":        dbus.MakeVariant("thinkpad"),
+				"Class":        dbus.MakeVariant(uint32(0x6c010c)),
+				"Powered":      dbus.MakeVariant(true),
+				"Discoverable": dbus.MakeVariant(false),
+				"Pairable":     dbus.MakeVariant(true),
+			},
+		},
+		fakeMaxPath: {
+			bluezDeviceIface: {
+				"Address":   dbus.MakeVariant("70:F9:4A:7A:8B:CA"),
+				"Name":      dbus.MakeVariant("AirPods Max"),
+				"Alias":     dbus.MakeVariant("AirPods Max"),
+				"Icon":      dbus.MakeVariant("audio-headphones"),
+				"Paired":    dbus.MakeVariant(true),
+				"Connected": dbus.MakeVariant(true),
+				"RSSI":      dbus.MakeVariant(int16(-56)),
+				"Adapter":   dbus.MakeVariant(fakeAdapterPath),
+				"Modalias":  dbus.MakeVariant("bluetooth:v004Cp201Fd0000"),
+				"UUIDs":     dbus.MakeVariant([]string{"0000110b-0000-1000-8000-00805f9b34fb"}),
+			},
+			bluezBatteryIface: {
+				"Percentage": dbus.MakeVariant(byte(85)),
+			},
+		},
+		fakeFlexPath: {
+			bluezDeviceIface: {
+				"Address":   dbus.MakeVariant("A8:91:3D:DE:91:C6"),
+				"Alias":     dbus.MakeVariant("Beats Flex"),
+				"Paired":    dbus.MakeVariant(true),
+				"Connected": dbus.MakeVariant(false),
+				"Adapter":   dbus.MakeVariant(fakeAdapterPath),
+			},
+		},
+		fakePhonePath: {
+			bluezDeviceIface: {
+				"Address": dbus.MakeVariant("11:22:33:44:55:66"),
+				"Name":    dbus.MakeVariant("Nearby Phone"),
+				"Paired":  dbus.MakeVariant(false),
+			},
+		},
+	}
+}
+
+// fakeBluez exports a fake org.bluez tree and records method calls.
+type fakeBluez struct {
+	mu      sync.Mutex
+	objects bluezObjects
+	calls   []string
+}
+
+func (f *fakeBluez) record(call string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+func (f *fakeBluez) recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+type fakeObjectManager struct{ f *fakeBluez }
+
+func (m fakeObjectManager) GetManagedObjects() (bluezObjects, *dbus.Error) {
+	return m.f.objects, nil
+}
+
+type fakeDevice struct {
+	f    *fakeBluez
+	path dbus.ObjectPath
+}
+
+func (d fakeDevice) Connect() *dbus.Error {
+	d.f.record("Connect " + string(d.path))
+	return nil
+}
+
+func (d fakeDevice) Disconnect() *dbus.Error {
+	d.f.record("Disconnect " + string(d.path))
+	return nil
+}
+
+type fakeAdapter struct{ f *fakeBluez }
+
+func (a fakeAdapter) RemoveDevice(path dbus.ObjectPath) *dbus.Error {
+	a.f.record("RemoveDevice " + string(path))
+	return nil
+}
+
+type fakeProperties struct{ f *fakeBluez }
+
+func (p fakeProperties) Set(iface, name string, v dbus.Variant) *dbus.Error {
+	p.f.record("Set " + iface + "." + name + "=" + v.String())
+	return nil
+}
+
+// startPrivateBus launches a private dbus-daemon and returns its address.
+// The test is skipped if dbus-daemon is not installed.
+func startPrivateBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	cmd := exec.Command(daemon, "--session", "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed to pipe dbus-daemon: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("failed to start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	addr, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read bus address: %v", err)
+	}
+	return strings.TrimSpace(addr)
+}
+
+// dialBus returns a dial function for the private bus at addr.
+func dialBus(addr string) func(ctx context.Context) (*dbus.Conn, error) {
+	return func(ctx context.Context) (*dbus.Conn, error) {
+		conn, err := dbus.Dial(addr, dbus.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.Auth(nil); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if err := conn.Hello(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// exportFakeBluez serves a fake org.bluez on the private bus at addr.
+func exportFakeBluez(t *testing.T, addr string) *fakeBluez {
+	t.Helper()
+	conn, err := dialBus(addr)(t.Context())
+	if err != nil {
+		t.Fatalf("failed to connect to private bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	f := &fakeBluez{objects: fakeBluezObjects()}
+	exports := []struct {
+		v     any
+		path  dbus.ObjectPath
+		iface string
+	}{
+		{fakeObjectManager{f}, "/", objectManagerIface},
+		{fakeAdapter{f}, fakeAdapterPath, bluezAdapterIface},
+		{fakeProperties{f}, fakeAdapterPath, "org.freedesktop.DBus.Properties"},
+		{fakeDevice{f, fakeMaxPath}, fakeMaxPath, bluezDeviceIface},
+		{fakeDevice{f, fakeFlexPath}, fakeFlexPath, bluezDeviceIface},
+	}
+	for _, e := range exports {
+		if err := conn.Export(e.v, e.path, e.iface); err != nil {
+			t.Fatalf("failed to export %s: %v", e.path, err)
+		}
+	}
+
+	reply, err := conn.RequestName(bluezService, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to own %s: %v", bluezService, err)
+	}
+	return f
+}
+
+func TestParseBluezObjects(t *testing.T) {
+	snap := parseBluezObjects(fakeBluezObjects())
+
+	if snap.PowerState != "on" {
+		t.Errorf("expected power state on, got %s", snap.PowerState)
+	}
+	if snap.ControllerInfo["controller_address"] != "BC:D0:74:22:43:D6" {
+		t.Errorf("expected controller address, got %s", snap.ControllerInfo["controller_address"])
+	}
+	if snap.ControllerInfo["controller_pairable"] != "yes" {
+		t.Errorf("expected pairable yes, got %s", snap.ControllerInfo["controller_pairable"])
+	}
+	if snap.ControllerInfo["controller_class"] != "0x006c010c" {
+		t.Errorf("expected class 0x006c010c, got %s", snap.ControllerInfo["controller_class"])
+	}
+
+	if len(snap.Devices) != 2 {
+		t.Fatalf("expected 2 paired devices, got %d", len(snap.Devices))
+	}
+	airpodsMax := snap.Devices[0]
+	if airpodsMax.Name != "AirPods Max" || !airpodsMax.Connected || airpodsMax.BatteryLevel != 85 || airpodsMax.RSSI != -56 {
+		t.Errorf("unexpected AirPods Max: %+v", airpodsMax)
+	}
+	if airpodsMax.MinorType != "Headphones" {
+		t.Errorf("expected MinorType Headphones, got %s", airpodsMax.MinorType)
+	}
+	if d := airpodsMax.Details; d == nil || d.VendorID != "0x004C" || d.ProductID != "0x201F" || len(d.Services) != 1 || d.Services[0] != "Audio Sink" {
+		t.Errorf("unexpected AirPods Max details: %+v", d)
+	}
+	flex := snap.Devices[1]
+	if flex.Name != "Beats Flex" || flex.Connected || flex.BatteryLevel != -1 {
+		t.Errorf("unexpected Beats Flex: %+v", flex)
+	}
+	if flex.Details != nil {
+		t.Errorf("expected no details for Beats Flex, got %+v", flex.Details)
+	}
+}
+
+func TestParseBluezObjects_NoAdapter(t *testing.T) {
+	snap := parseBluezObjects(bluezObjects{})
+	if snap.PowerState != "unknown" {
+		t.Errorf("expected power state unknown, got %s", snap.PowerState)
+	}
+	if len(snap.Devices) != 0 {
+		t.Errorf("expected no devices, got %d", len(snap.Devices))
+	}
+}
+
+func TestBluezServiceNames(t *testing.T) {
+	got := bluezServiceNames([]string{
+		"0000110b-0000-1000-8000-00805f9b34fb",
+		"0000110E-0000-1000-8000-00805F9B34FB",
+		"0000180f-0000-1000-8000-00805f9b34fb",
+		"0000fd6f-0000-1000-8000-00805f9b34fb",
+		"74ec2172-0bad-4d01-8f77-997b2be0722a",
+	})
+	want := []string{
+		"Audio Sink",
+		"A/V Remote Control",
+		"Battery Service",
+		"0000fd6f-0000-1000-8000-00805f9b34fb",
+		"74ec2172-0bad-4d01-8f77-997b2be0722a",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := bluezServiceNames(nil); got != nil {
+		t.Errorf("expected no services, got %v", got)
+	}
+}
+
+func TestFindBluezDevice(t *testing.T) {
+	path, adapter, ok := findBluezDevice(fakeBluezObjects(), "70:f9:4a:7a:8b:ca")
+	if !ok || path != fakeMaxPath || adapter != fakeAdapterPath {
+		t.Errorf("unexpected lookup result: %s %s %v", path, adapter, ok)
+	}
+
+	// Adapter falls back to the parent path when the property is missing.
+	_, adapter, ok = findBluezDevice(fakeBluezObjects(), "11:22:33:44:55:66")
+	if !ok || adapter != fakeAdapterPath {
+		t.Errorf("expected parent adapter, got %s %v", adapter, ok)
+	}
+
+	if _, _, ok := findBluezDevice(fakeBluezObjects(), "00:00:00:00:00:00"); ok {
+		t.Error("expected unknown address to be missing")
+	}
+}
+
+func TestBluezBackend_PrivateBus(t *testing.T) {
+	addr := startPrivateBus(t)
+	fake := exportFakeBluez(t, addr)
+	b := NewBluezBackend(dialBus(addr))
+
+	snap, err := b.Snapshot(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snap.PowerState != "on" || len(snap.Devices) != 2 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+	if err := b.CanControl(t.Context()); err != nil {
+		t.Fatalf("unexpected CanControl error: %v", err)
+	}
+
+	if err := b.Connect(t.Context(), "A8:91:3D:DE:91:C6"); err != nil {
+		t.Fatalf("unexpected connect error: %v", err)
+	}
+	if err := b.Disconnect(t.Context(), "70:F9:4A:7A:8B:CA"); err != nil {
+		t.Fatalf("unexpected disconnect error: %v", err)
+	}
+	if err := b.Remove(t.Context(), "70:F9:4A:7A:8B:CA"); err != nil {
+		t.Fatalf("unexpected remove error: %v", err)
+	}
+	if err := b.SetPower(t.Context(), false); err != nil {
+		t.Fatalf("unexpected power error: %v", err)
+	}
+	if err := b.Connect(t.Context(), "00:00:00:00:00:00"); err == nil {
+		t.Error("expected error for unknown device")
+	}
+
+	want := []string{
+		"Connect " + string(fakeFlexPath),
+		"Disconnect " + string(fakeMaxPath),
+		"RemoveDevice " + string(fakeMaxPath),
+		"Set org.bluez.Adapter1.Powered=false",
+	}
+	got := fake.recorded()
+	if len(got) != len(want) {
+		t.Fatalf("expected calls %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("call[%d]: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
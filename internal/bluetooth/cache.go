@@ -0,0 +1,171 @@
+package bluetooth
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultSnapshotTTL is how long a snapshot is reused before the backend is
+// queried again. It is shorter than the TUI's refresh interval, so each
+// refresh sees fresh data while the lookups within one command share a read.
+const DefaultSnapshotTTL = 2 * time.Second
+
+// snapshotCache holds the most recent snapshot and de-duplicates concurrent
+// fetches, so callers racing on an expired entry share one backend query.
+type snapshotCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	now      func() time.Time
+	snap     *Snapshot
+	fetched  time.Time
+	gen      uint64 // bumped by invalidate; fetches from older generations are not stored
+	inflight *snapshotCall
+}
+
+// snapshotCall is a fetch in progress that other callers can wait on.
+type snapshotCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc // stops the fetch once nobody waits on it
+	waiters int                // guarded by snapshotCache.mu
+	snap    *Snapshot
+	err     error
+}
+
+// newSnapshotCache returns an empty cache. A nil now uses time.Now.
+func newSnapshotCache(ttl time.Duration, now func() time.Time) *snapshotCache {
+	if now == nil {
+		now = time.Now
+	}
+	return &snapshotCache{ttl: ttl, now: now}
+}
+
+// get returns the cached snapshot if it is younger than the TTL. Otherwise
+// it joins the fetch in progress or starts one with fetch. The fetch is
+// shared, so it runs under its own context rather than the first caller's
+// and must bound itself, as Client.snapshot does with the snapshot timeout.
+// A caller whose ctx ends stops waiting with the error contextError reports
+// for tool; the fetch is cancelled once no caller is left waiting on it.
+// Each caller gets its own copy.
+func (sc *snapshotCache) get(ctx context.Context, tool string, fetch func(context.Context) (*Snapshot, error)) (*Snapshot, error) {
+	start := time.Now()
+	sc.mu.Lock()
+	if sc.snap != nil && sc.ttl > 0 && sc.now().Sub(sc.fetched) < sc.ttl {
+		snap := sc.snap
+		sc.mu.Unlock()
+		return snap.clone(), nil
+	}
+
+	call := sc.inflight
+	if call == nil {
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &snapshotCall{done: make(chan struct{}), cancel: cancel}
+		sc.inflight = call
+		gen := sc.gen
+
+		go func() {
+			defer cancel()
+			call.snap, call.err = fetch(fetchCtx)
+
+			sc.mu.Lock()
+			if sc.inflight == call {
+				sc.inflight = nil
+			}
+			if call.err == nil && sc.gen == gen {
+				sc.snap = call.snap
+				sc.fetched = sc.now()
+			}
+			sc.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	sc.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		sc.leave(call)
+		return nil, contextError(ctx, tool, start)
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	return call.snap.clone(), nil
+}
+
+// leave drops a caller that stopped waiting on call. The last one to leave
+// cancels the fetch and detaches it, so later callers start a new one.
+func (sc *snapshotCache) leave(call *snapshotCall) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	call.cancel()
+	if sc.inflight == call {
+		sc.inflight = nil
+	}
+}
+
+// invalidate drops the cached snapshot. A fetch already in progress is
+// detached, so later callers start a new one that sees the change.
+func (sc *snapshotCache) invalidate() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.gen++
+	sc.snap = nil
+	sc.inflight = nil
+}
+
+// setTTL changes the TTL. Zero disables reuse but keeps de-duplication.
+func (sc *snapshotCache) setTTL(ttl time.Duration) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.ttl = ttl
+}
+
+// clone returns a copy that callers can modify without touching the cache.
+func (s *Snapshot) clone() *Snapshot {
+	c := *s
+	c.ControllerInfo = maps.Clone(s.ControllerInfo)
+	c.Controllers = slices.Clone(s.Controllers)
+	for i := range c.Controllers {
+		c.Controllers[i].Info = maps.Clone(c.Controllers[i].Info)
+		c.Controllers[i].Services = slices.Clone(c.Controllers[i].Services)
+	}
+	c.Devices = slices.Clone(s.Devices)
+	for i, d := range c.Devices {
+		c.Devices[i].Batteries = slices.Clone(d.Batteries)
+		if d.Product != nil {
+			product := *d.Product
+			c.Devices[i].Product = &product
+		}
+		if d.Details != nil {
+			details := *d.Details
+			details.Services = slices.Clone(details.Services)
+			details.Extra = maps.Clone(details.Extra)
+			c.Devices[i].Details = &details
+		}
+	}
+	return &c
+}
+
+// SetSnapshotTTL sets how long the client reuses a snapshot.
+// Zero queries the backend on every call.
+func (c *Client) SetSnapshotTTL(ttl time.Duration) {
+	c.cache.setTTL(ttl)
+}
+
+// InvalidateSnapshot forces the next call to query the backend.
+func (c *Client) InvalidateSnapshot() {
+	c.cache.invalidate()
+}
+
+// SetSnapshotTTL sets how long the package-level functions reuse a snapshot.
+func SetSnapshotTTL(ttl time.Duration) {
+	defaultClient.SetSnapshotTTL(ttl)
+}
@@ -0,0 +1,270 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingBackend is a fakeBackend that counts Snapshot calls.
+type countingBackend struct {
+	*fakeBackend
+	snapshots atomic.Int32
+}
+
+func (c *countingBackend) Snapshot(ctx context.Context) (*Snapshot, error) {
+	c.snapshots.Add(1)
+	return c.fakeBackend.Snapshot(ctx)
+}
+
+func TestSnapshotCache_TTL(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	sc := newSnapshotCache(2*time.Second, clock.now)
+	fetches := 0
+	fetch := func(context.Context) (*Snapshot, error) {
+		fetches++
+		return &Snapshot{PowerState: "on"}, nil
+	}
+
+	for range 3 {
+		if _, err := sc.get(t.Context(), "system_profiler", fetch); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("expected 1 fetch within TTL, got %d", fetches)
+	}
+
+	clock.add(2 * time.Second)
+	_, _ = sc.get(t.Context(), "system_profiler", fetch)
+	if fetches != 2 {
+		t.Errorf("expected refetch after TTL, got %d fetches", fetches)
+	}
+
+	sc.setTTL(0)
+	_, _ = sc.get(t.Context(), "system_profiler", fetch)
+	_, _ = sc.get(t.Context(), "system_profiler", fetch)
+	if fetches != 4 {
+		t.Errorf("expected every call to fetch with TTL 0, got %d fetches", fetches)
+	}
+}
+
+func TestSnapshotCache_Singleflight(t *testing.T) {
+	t.Parallel()
+	sc := newSnapshotCache(time.Minute, nil)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	fetch := func(context.Context) (*Snapshot, error) {
+		fetches.Add(1)
+		<-release
+		return &Snapshot{PowerState: "on"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if snap, err := sc.get(t.Context(), "system_profiler", fetch); err != nil || snap.PowerState != "on" {
+				t.Errorf("unexpected result: %+v, %v", snap, err)
+			}
+		}()
+	}
+	for {
+		sc.mu.Lock()
+		started := sc.inflight != nil
+		sc.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected concurrent callers to share 1 fetch, got %d", n)
+	}
+}
+
+func TestSnapshotCache_FirstCallerCancelled(t *testing.T) {
+	t.Parallel()
+	sc := newSnapshotCache(time.Minute, nil)
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (*Snapshot, error) {
+		select {
+		case <-release:
+			return &Snapshot{PowerState: "on"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	first, cancel := context.WithCancel(t.Context())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := sc.get(first, "system_profiler", fetch)
+		firstErr <- err
+	}()
+	for {
+		sc.mu.Lock()
+		started := sc.inflight != nil
+		sc.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	second := make(chan error, 1)
+	go func() {
+		snap, err := sc.get(t.Context(), "system_profiler", fetch)
+		if err == nil && snap.PowerState != "on" {
+			err = fmt.Errorf("unexpected snapshot %+v", snap)
+		}
+		second <- err
+	}()
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the first caller to stop with its own cancellation, got %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("expected the other caller to get the shared snapshot, got %v", err)
+	}
+}
+
+func TestSnapshotCache_CallerDeadline(t *testing.T) {
+	t.Parallel()
+	sc := newSnapshotCache(time.Minute, nil)
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (*Snapshot, error) {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("expected the shared fetch not to inherit a caller's deadline")
+		}
+		select {
+		case <-release:
+			return &Snapshot{PowerState: "on"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	second := make(chan error, 1)
+	go func() {
+		snap, err := sc.get(t.Context(), "system_profiler", fetch)
+		if err == nil && snap.PowerState != "on" {
+			err = fmt.Errorf("unexpected snapshot %+v", snap)
+		}
+		second <- err
+	}()
+	for {
+		sc.mu.Lock()
+		started := sc.inflight != nil
+		sc.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	short, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	_, err := sc.get(short, "system_profiler", fetch)
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Tool != "system_profiler" {
+		t.Errorf("expected a system_profiler TimeoutError, got %v", err)
+	}
+
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("expected the other caller to get the shared snapshot, got %v", err)
+	}
+}
+
+func TestSnapshotCache_LastWaiterCancelsFetch(t *testing.T) {
+	t.Parallel()
+	sc := newSnapshotCache(time.Minute, nil)
+	stopped := make(chan struct{})
+	fetch := func(ctx context.Context) (*Snapshot, error) {
+		<-ctx.Done()
+		close(stopped)
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := sc.get(ctx, "system_profiler", fetch); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("expected the fetch to stop once no caller waits on it")
+	}
+}
+
+func TestSnapshotCache_InvalidateDuringFetch(t *testing.T) {
+	t.Parallel()
+	sc := newSnapshotCache(time.Minute, nil)
+	fetches := 0
+	fetch := func(context.Context) (*Snapshot, error) {
+		fetches++
+		if fetches == 1 {
+			sc.invalidate() // a mutation lands while the first read is running
+		}
+		return &Snapshot{PowerState: "on"}, nil
+	}
+
+	_, _ = sc.get(t.Context(), "system_profiler", fetch)
+	_, _ = sc.get(t.Context(), "system_profiler", fetch)
+	if fetches != 2 {
+		t.Errorf("expected stale fetch not to be cached, got %d fetches", fetches)
+	}
+}
+
+func TestClient_SharesSnapshot(t *testing.T) {
+	t.Parallel()
+	cb := &countingBackend{fakeBackend: newFakeBackend()}
+	c := NewClient(cb)
+
+	d, err := c.GetDevice(t.Context(), "AirPods Max")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Diagnose(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := cb.snapshots.Load(); n != 1 {
+		t.Errorf("expected lookups to share 1 snapshot, got %d", n)
+	}
+
+	if err := c.Disconnect(t.Context(), d.Address); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.ListDevices(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := cb.snapshots.Load(); n != 2 {
+		t.Errorf("expected refetch after disconnect, got %d snapshots", n)
+	}
+}
+
+func TestClient_SnapshotCopies(t *testing.T) {
+	t.Parallel()
+	c := NewClient(newFakeBackend())
+
+	devices, _ := c.ListDevices(t.Context())
+	devices[0].Name = "changed"
+	again, _ := c.ListDevices(t.Context())
+	if again[0].Name != "AirPods Max" {
+		t.Errorf("expected cached snapshot to be unaffected, got %q", again[0].Name)
+	}
+}
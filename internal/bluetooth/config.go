@@ -0,0 +1,181 @@
+package bluetooth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds user-defined device aliases and groups, and optionally the
+// backend to use, as read from config.yaml:
+//
+//	backend: bluez
+//	aliases:
+//	  buds: F4:34:F0:96:DD:A0
+//	groups:
+//	  desk: [Magic Keyboard, Magic Trackpad, buds]
+//
+// Alias targets are anything FindDevice accepts. Group members act on
+// several devices at once, so they must be a full address, a whole device
+// name, or an alias whose target is one. Names are matched
+// case-insensitively.
+type Config struct {
+	Backend string              `yaml:"backend" json:"backend,omitempty"` // see BackendName
+	Aliases map[string]string   `yaml:"aliases" json:"aliases"`
+	Groups  map[string][]string `yaml:"groups" json:"groups"`
+}
+
+// ParseConfig parses a config.yaml file.
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if _, ok := backendFactories[cfg.Backend]; cfg.Backend != "" && !ok {
+		return nil, fmt.Errorf("failed to parse config: unknown backend: %s (available: %s)",
+			cfg.Backend, strings.Join(Backends(), ", "))
+	}
+	// Names are looked up case-insensitively, so two that differ only in
+	// case would resolve to either at random.
+	aliases := make(map[string]string, len(cfg.Aliases))
+	for name, target := range cfg.Aliases {
+		if strings.TrimSpace(name) == "" || strings.TrimSpace(target) == "" {
+			return nil, fmt.Errorf("failed to parse config: alias %q needs a name and a device", name)
+		}
+		key := strings.ToLower(strings.TrimSpace(name))
+		if other, ok := aliases[key]; ok {
+			return nil, fmt.Errorf("failed to parse config: aliases %q and %q differ only in case", other, name)
+		}
+		aliases[key] = name
+	}
+	groups := make(map[string]string, len(cfg.Groups))
+	for name, members := range cfg.Groups {
+		if strings.TrimSpace(name) == "" || len(members) == 0 {
+			return nil, fmt.Errorf("failed to parse config: group %q needs a name and at least one device", name)
+		}
+		key := strings.ToLower(strings.TrimSpace(name))
+		if other, ok := groups[key]; ok {
+			return nil, fmt.Errorf("failed to parse config: groups %q and %q differ only in case", other, name)
+		}
+		groups[key] = name
+		if _, ok := aliases[key]; ok {
+			return nil, fmt.Errorf("failed to parse config: %q is both an alias and a group", name)
+		}
+	}
+	return &cfg, nil
+}
+
+// LoadConfig reads a config.yaml file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return ParseConfig(data)
+}
+
+// Alias returns the device an alias stands for.
+func (cfg *Config) Alias(name string) (string, bool) {
+	if cfg == nil {
+		return "", false
+	}
+	for alias, target := range cfg.Aliases {
+		if strings.EqualFold(alias, strings.TrimSpace(name)) {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// Group returns the members of a group.
+func (cfg *Config) Group(name string) ([]string, bool) {
+	if cfg == nil {
+		return nil, false
+	}
+	for group, members := range cfg.Groups {
+		if strings.EqualFold(group, strings.TrimSpace(name)) {
+			return members, true
+		}
+	}
+	return nil, false
+}
+
+// Expand returns the device an alias stands for, or query unchanged if it
+// is not an alias.
+func (cfg *Config) Expand(query string) string {
+	if target, ok := cfg.Alias(query); ok {
+		return target
+	}
+	return query
+}
+
+// BackendName returns the backend to use: the one named by flag, else by
+// env, else by cfg, else DefaultBackend.
+func BackendName(flag, env string, cfg *Config) string {
+	switch {
+	case flag != "":
+		return flag
+	case env != "":
+		return env
+	case cfg != nil && cfg.Backend != "":
+		return cfg.Backend
+	}
+	return DefaultBackend
+}
+
+// SetConfig sets the aliases and groups the client resolves. nil clears them.
+func (c *Client) SetConfig(cfg *Config) {
+	c.config = cfg
+}
+
+// Config returns the client's aliases and groups, or nil if none are set.
+func (c *Client) Config() *Config {
+	return c.config
+}
+
+// GetDevices resolves a group to its member devices, or a single name,
+// address, or alias to a one-element list. Members are matched exactly,
+// so a typo fails rather than picking a similar device.
+func (c *Client) GetDevices(ctx context.Context, query string) ([]Device, error) {
+	members, ok := c.config.Group(query)
+	if !ok {
+		d, err := c.GetDevice(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return []Device{*d}, nil
+	}
+
+	devices, err := c.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Device, 0, len(members))
+	for _, m := range members {
+		d, err := findExact(devices, c.config.Expand(m))
+		if err != nil {
+			return nil, fmt.Errorf("group %s: member %s: %w", query, m, err)
+		}
+		out = append(out, *d)
+	}
+	return out, nil
+}
+
+// SetConfig sets the aliases and groups used by the package-level functions.
+func SetConfig(cfg *Config) {
+	defaultClient.SetConfig(cfg)
+}
+
+// CurrentConfig returns the aliases and groups used by the package-level
+// functions, or nil if none are set.
+func CurrentConfig() *Config {
+	return defaultClient.Config()
+}
+
+// GetDevices resolves a group, name, address, or alias using the current backend.
+func GetDevices(ctx context.Context, query string) ([]Device, error) {
+	return defaultClient.GetDevices(ctx, query)
+}
@@ -0,0 +1,144 @@
+package bluetooth
+
+import (
+	"errors"
+	"testing"
+)
+
+const configYAML = `
+backend: sim
+aliases:
+  max: 70:F9:4A:7A:8B:CA
+  kb: Magic Keyboard
+groups:
+  Desk: [kb, AirPods Max]
+  broken: [kb, Speaker]
+`
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(configYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target, ok := cfg.Alias("MAX"); !ok || target != "70:F9:4A:7A:8B:CA" {
+		t.Errorf("expected alias max, got %q %v", target, ok)
+	}
+	if members, ok := cfg.Group("desk"); !ok || len(members) != 2 {
+		t.Errorf("expected group desk with 2 members, got %v %v", members, ok)
+	}
+	if cfg.Backend != "sim" {
+		t.Errorf("expected backend sim, got %q", cfg.Backend)
+	}
+	if got := cfg.Expand("Beats Flex"); got != "Beats Flex" {
+		t.Errorf("expected non-alias unchanged, got %q", got)
+	}
+
+	var empty *Config
+	if _, ok := empty.Alias("max"); ok {
+		t.Error("expected nil config to have no aliases")
+	}
+	if got := empty.Expand("max"); got != "max" {
+		t.Errorf("expected nil config to leave queries unchanged, got %q", got)
+	}
+}
+
+func TestParseConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{"invalid yaml", "aliases: [\n"},
+		{"empty alias target", "aliases:\n  buds: \"\"\n"},
+		{"empty group", "groups:\n  desk: []\n"},
+		{"alias and group", "aliases:\n  desk: AirPods\ngroups:\n  Desk: [AirPods]\n"},
+		{"aliases differing in case", "aliases:\n  buds: AirPods\n  Buds: Beats Flex\n"},
+		{"groups differing in case", "groups:\n  desk: [AirPods]\n  DESK: [Beats Flex]\n"},
+		{"unknown backend", "backend: bluetoothd\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseConfig([]byte(tt.yaml)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestBackendName(t *testing.T) {
+	cfg := &Config{Backend: "sim"}
+	tests := []struct {
+		name string
+		flag string
+		env  string
+		cfg  *Config
+		want string
+	}{
+		{"flag", "bluez", "macos", cfg, "bluez"},
+		{"env", "", "macos", cfg, "macos"},
+		{"config", "", "", cfg, "sim"},
+		{"config without backend", "", "", &Config{}, DefaultBackend},
+		{"no config", "", "", nil, DefaultBackend},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BackendName(tt.flag, tt.env, tt.cfg); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClient_GetDeviceAlias(t *testing.T) {
+	c := NewClient(newFakeBackend())
+	cfg, _ := ParseConfig([]byte(configYAML))
+	c.SetConfig(cfg)
+
+	d, err := c.GetDevice(t.Context(), "max")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Name != "AirPods Max" {
+		t.Errorf("expected AirPods Max, got %s", d.Name)
+	}
+}
+
+func TestClient_GetDevices(t *testing.T) {
+	c := NewClient(newFakeBackend())
+	cfg, _ := ParseConfig([]byte(configYAML))
+	c.SetConfig(cfg)
+
+	devices, err := c.GetDevices(t.Context(), "desk")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != 2 || devices[0].Name != "Magic Keyboard" || devices[1].Name != "AirPods Max" {
+		t.Errorf("unexpected group members: %+v", devices)
+	}
+
+	devices, err = c.GetDevices(t.Context(), "kb")
+	if err != nil || len(devices) != 1 || devices[0].Name != "Magic Keyboard" {
+		t.Errorf("expected alias to resolve to one device, got %+v %v", devices, err)
+	}
+
+	if _, err := c.GetDevices(t.Context(), "broken"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound for missing member, got %v", err)
+	}
+}
+
+func TestClient_GetDevicesNearMiss(t *testing.T) {
+	c := NewClient(newFakeBackend())
+	cfg, err := ParseConfig([]byte("groups:\n  desk: [Magic Keybord, AirPods Max]\n  audio: [AirPods]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetConfig(cfg)
+
+	// FindDevice would accept both the typo and the substring.
+	_, err = c.GetDevices(t.Context(), "desk")
+	if !errors.Is(err, ErrDeviceNotFound) || err.Error() != "group desk: member Magic Keybord: device not found: Magic Keybord" {
+		t.Errorf("expected the typo'd member to be reported, got %v", err)
+	}
+	if _, err := c.GetDevices(t.Context(), "audio"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected a substring member not to resolve, got %v", err)
+	}
+}
@@ -1,9 +1,8 @@
 package bluetooth
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"os/exec"
 )
 
 // ErrBlueUtilNotInstalled is returned when blueutil is required but not available.
@@ -12,85 +11,69 @@ var ErrBlueUtilNotInstalled = errors.New("blueutil required — brew install blu
 // ErrSudoRequired is returned when a command requires root privileges.
 var ErrSudoRequired = errors.New("this operation requires sudo")
 
-// IsBlueUtilInstalled checks if blueutil is available on the system.
-func IsBlueUtilInstalled() bool {
-	_, err := lookPath("blueutil")
-	return err == nil
-}
-
-// lookPath abstracts exec.LookPath for testing.
-var lookPath = exec.LookPath
-
 // PowerOn enables the Bluetooth controller.
 // Requires sudo; returns a clear error if not root.
-func PowerOn() error {
-	return setPowerState(1)
+func (c *Client) PowerOn(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx, DefaultPowerTimeout)
+	defer cancel()
+	defer c.cache.invalidate()
+	return c.backend.SetPower(ctx, true)
 }
 
 // PowerOff disables the Bluetooth controller.
 // Requires sudo; returns a clear error if not root.
-func PowerOff() error {
-	return setPowerState(0)
+func (c *Client) PowerOff(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx, DefaultPowerTimeout)
+	defer cancel()
+	defer c.cache.invalidate()
+	return c.backend.SetPower(ctx, false)
 }
 
-// setPowerState sets the Bluetooth controller power state.
-func setPowerState(state int) error {
-	stateStr := fmt.Sprintf("%d", state)
-	_, err := commandRunner("defaults", "write",
-		"/Library/Preferences/com.apple.Bluetooth",
-		"ControllerPowerState", "-int", stateStr)
-	if err != nil {
-		return fmt.Errorf("failed to set power state (sudo required): %w", err)
-	}
+// Connect connects to a device by address.
+func (c *Client) Connect(ctx context.Context, address string) error {
+	ctx, cancel := c.withTimeout(ctx, DefaultControlTimeout)
+	defer cancel()
+	defer c.cache.invalidate()
+	return c.backend.Connect(ctx, address)
+}
 
-	_, err = commandRunner("killall", "-HUP", "bluetoothd")
-	if err != nil {
-		return fmt.Errorf("failed to restart bluetoothd: %w", err)
-	}
+// Disconnect disconnects a device by address.
+func (c *Client) Disconnect(ctx context.Context, address string) error {
+	ctx, cancel := c.withTimeout(ctx, DefaultControlTimeout)
+	defer cancel()
+	defer c.cache.invalidate()
+	return c.backend.Disconnect(ctx, address)
+}
+
+// Remove unpairs a device by address.
+func (c *Client) Remove(ctx context.Context, address string) error {
+	ctx, cancel := c.withTimeout(ctx, DefaultControlTimeout)
+	defer cancel()
+	defer c.cache.invalidate()
+	return c.backend.Remove(ctx, address)
+}
 
-	return nil
+// PowerOn enables the Bluetooth controller using the current backend.
+func PowerOn(ctx context.Context) error {
+	return defaultClient.PowerOn(ctx)
 }
 
-// requireBlueUtil returns an error if blueutil is not installed.
-func requireBlueUtil() error {
-	if !IsBlueUtilInstalled() {
-		return ErrBlueUtilNotInstalled
-	}
-	return nil
+// PowerOff disables the Bluetooth controller using the current backend.
+func PowerOff(ctx context.Context) error {
+	return defaultClient.PowerOff(ctx)
 }
 
-// Connect connects to a device by address using blueutil.
-func Connect(address string) error {
-	if err := requireBlueUtil(); err != nil {
-		return err
-	}
-	_, err := commandRunner("blueutil", "--connect", address)
-	if err != nil {
-		return fmt.Errorf("failed to connect to %s: %w", address, err)
-	}
-	return nil
+// Connect connects to a device by address using the current backend.
+func Connect(ctx context.Context, address string) error {
+	return defaultClient.Connect(ctx, address)
 }
 
-// Disconnect disconnects a device by address using blueutil.
-func Disconnect(address string) error {
-	if err := requireBlueUtil(); err != nil {
-		return err
-	}
-	_, err := commandRunner("blueutil", "--disconnect", address)
-	if err != nil {
-		return fmt.Errorf("failed to disconnect %s: %w", address, err)
-	}
-	return nil
+// Disconnect disconnects a device by address using the current backend.
+func Disconnect(ctx context.Context, address string) error {
+	return defaultClient.Disconnect(ctx, address)
 }
 
-// Remove unpairs a device by address using blueutil.
-func Remove(address string) error {
-	if err := requireBlueUtil(); err != nil {
-		return err
-	}
-	_, err := commandRunner("blueutil", "--unpair", address)
-	if err != nil {
-		return fmt.Errorf("failed to remove %s: %w", address, err)
-	}
-	return nil
+// Remove unpairs a device by address using the current backend.
+func Remove(ctx context.Context, address string) error {
+	return defaultClient.Remove(ctx, address)
 }
@@ -1,72 +1,57 @@
 package bluetooth
 
 import (
+	"context"
 	"errors"
 	"testing"
 )
 
 func TestIsBlueUtilInstalled_Found(t *testing.T) {
-	orig := lookPath
-	defer func() { lookPath = orig }()
-
-	lookPath = func(file string) (string, error) {
+	lookPath := func(file string) (string, error) {
 		if file == "blueutil" {
 			return "/opt/homebrew/bin/blueutil", nil
 		}
 		return "", errors.New("not found")
 	}
 
-	if !IsBlueUtilInstalled() {
+	if !newMacOSBackend(nil, lookPath).blueUtilInstalled() {
 		t.Error("expected blueutil to be detected as installed")
 	}
 }
 
 func TestIsBlueUtilInstalled_NotFound(t *testing.T) {
-	orig := lookPath
-	defer func() { lookPath = orig }()
-
-	lookPath = func(file string) (string, error) {
+	lookPath := func(file string) (string, error) {
 		return "", errors.New("not found")
 	}
 
-	if IsBlueUtilInstalled() {
+	if newMacOSBackend(nil, lookPath).blueUtilInstalled() {
 		t.Error("expected blueutil to be detected as not installed")
 	}
 }
 
 func TestConnect_BlueUtilNotInstalled(t *testing.T) {
-	origLook := lookPath
-	defer func() { lookPath = origLook }()
-
-	lookPath = func(file string) (string, error) {
+	lookPath := func(file string) (string, error) {
 		return "", errors.New("not found")
 	}
 
-	err := Connect("AA:BB:CC:DD:EE:FF")
+	err := NewClient(newMacOSBackend(nil, lookPath)).Connect(t.Context(), "AA:BB:CC:DD:EE:FF")
 	if !errors.Is(err, ErrBlueUtilNotInstalled) {
 		t.Errorf("expected ErrBlueUtilNotInstalled, got %v", err)
 	}
 }
 
 func TestConnect_Success(t *testing.T) {
-	origLook := lookPath
-	origCmd := commandRunner
-	defer func() {
-		lookPath = origLook
-		commandRunner = origCmd
-	}()
-
-	lookPath = func(file string) (string, error) {
+	lookPath := func(file string) (string, error) {
 		return "/opt/homebrew/bin/blueutil", nil
 	}
 
 	var gotArgs []string
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		gotArgs = append([]string{name}, args...)
 		return nil, nil
 	}
 
-	err := Connect("AA:BB:CC:DD:EE:FF")
+	err := NewClient(newMacOSBackend(run, lookPath)).Connect(t.Context(), "AA:BB:CC:DD:EE:FF")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -77,22 +62,15 @@ func TestConnect_Success(t *testing.T) {
 }
 
 func TestConnect_Failure(t *testing.T) {
-	origLook := lookPath
-	origCmd := commandRunner
-	defer func() {
-		lookPath = origLook
-		commandRunner = origCmd
-	}()
-
-	lookPath = func(file string) (string, error) {
+	lookPath := func(file string) (string, error) {
 		return "/opt/homebrew/bin/blueutil", nil
 	}
 
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return nil, errors.New("connection failed")
 	}
 
-	err := Connect("AA:BB:CC:DD:EE:FF")
+	err := NewClient(newMacOSBackend(run, lookPath)).Connect(t.Context(), "AA:BB:CC:DD:EE:FF")
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -102,38 +80,28 @@ func TestConnect_Failure(t *testing.T) {
 }
 
 func TestDisconnect_BlueUtilNotInstalled(t *testing.T) {
-	origLook := lookPath
-	defer func() { lookPath = origLook }()
-
-	lookPath = func(file string) (string, error) {
+	lookPath := func(file string) (string, error) {
 		return "", errors.New("not found")
 	}
 
-	err := Disconnect("AA:BB:CC:DD:EE:FF")
+	err := NewClient(newMacOSBackend(nil, lookPath)).Disconnect(t.Context(), "AA:BB:CC:DD:EE:FF")
 	if !errors.Is(err, ErrBlueUtilNotInstalled) {
 		t.Errorf("expected ErrBlueUtilNotInstalled, got %v", err)
 	}
 }
 
 func TestDisconnect_Success(t *testing.T) {
-	origLook := lookPath
-	origCmd := commandRunner
-	defer func() {
-		lookPath = origLook
-		commandRunner = origCmd
-	}()
-
-	lookPath = func(file string) (string, error) {
+	lookPath := func(file string) (string, error) {
 		return "/opt/homebrew/bin/blueutil", nil
 	}
 
 	var gotArgs []string
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		gotArgs = append([]string{name}, args...)
 		return nil, nil
 	}
 
-	err := Disconnect("AA:BB:CC:DD:EE:FF")
+	err := NewClient(newMacOSBackend(run, lookPath)).Disconnect(t.Context(), "AA:BB:CC:DD:EE:FF")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -144,38 +112,28 @@ func TestDisconnect_Success(t *testing.T) {
 }
 
 func TestRemove_BlueUtilNotInstalled(t *testing.T) {
-	origLook := lookPath
-	defer func() { lookPath = origLook }()
-
-	lookPath = func(file string) (string, error) {
+	lookPath := func(file string) (string, error) {
 		return "", errors.New("not found")
 	}
 
-	err := Remove("AA:BB:CC:DD:EE:FF")
+	err := NewClient(newMacOSBackend(nil, lookPath)).Remove(t.Context(), "AA:BB:CC:DD:EE:FF")
 	if !errors.Is(err, ErrBlueUtilNotInstalled) {
 		t.Errorf("expected ErrBlueUtilNotInstalled, got %v", err)
 	}
 }
 
 func TestRemove_Success(t *testing.T) {
-	origLook := lookPath
-	origCmd := commandRunner
-	defer func() {
-		lookPath = origLook
-		commandRunner = origCmd
-	}()
-
-	lookPath = func(file string) (string, error) {
+	lookPath := func(file string) (string, error) {
 		return "/opt/homebrew/bin/blueutil", nil
 	}
 
 	var gotArgs []string
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		gotArgs = append([]string{name}, args...)
 		return nil, nil
 	}
 
-	err := Remove("AA:BB:CC:DD:EE:FF")
+	err := NewClient(newMacOSBackend(run, lookPath)).Remove(t.Context(), "AA:BB:CC:DD:EE:FF")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -186,16 +144,13 @@ func TestRemove_Success(t *testing.T) {
 }
 
 func TestPowerOn_Success(t *testing.T) {
-	origCmd := commandRunner
-	defer func() { commandRunner = origCmd }()
-
 	var calls [][]string
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		calls = append(calls, append([]string{name}, args...))
 		return nil, nil
 	}
 
-	err := PowerOn()
+	err := NewClient(newMacOSBackend(run, nil)).PowerOn(t.Context())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -216,16 +171,13 @@ func TestPowerOn_Success(t *testing.T) {
 }
 
 func TestPowerOff_Success(t *testing.T) {
-	origCmd := commandRunner
-	defer func() { commandRunner = origCmd }()
-
 	var calls [][]string
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		calls = append(calls, append([]string{name}, args...))
 		return nil, nil
 	}
 
-	err := PowerOff()
+	err := NewClient(newMacOSBackend(run, nil)).PowerOff(t.Context())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -241,34 +193,28 @@ func TestPowerOff_Success(t *testing.T) {
 }
 
 func TestPowerOn_DefaultsWriteError(t *testing.T) {
-	origCmd := commandRunner
-	defer func() { commandRunner = origCmd }()
-
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		if name == "defaults" {
 			return nil, errors.New("permission denied")
 		}
 		return nil, nil
 	}
 
-	err := PowerOn()
+	err := NewClient(newMacOSBackend(run, nil)).PowerOn(t.Context())
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
 
 func TestPowerOn_KillallError(t *testing.T) {
-	origCmd := commandRunner
-	defer func() { commandRunner = origCmd }()
-
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		if name == "killall" {
 			return nil, errors.New("process not found")
 		}
 		return nil, nil
 	}
 
-	err := PowerOn()
+	err := NewClient(newMacOSBackend(run, nil)).PowerOn(t.Context())
 	if err == nil {
 		t.Fatal("expected error when killall fails")
 	}
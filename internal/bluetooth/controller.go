@@ -0,0 +1,172 @@
+package bluetooth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Controller is one Bluetooth radio, e.g. the built-in chip or a USB dongle.
+// Devices paired through it carry its address in Device.Controller.
+type Controller struct {
+	Address      string            `json:"address"`
+	Chipset      string            `json:"chipset,omitempty"`
+	Firmware     string            `json:"firmware,omitempty"`
+	VendorID     string            `json:"vendor_id,omitempty"`     // e.g. "0x004C"
+	VendorSource string            `json:"vendor_source,omitempty"` // VendorSourceBluetooth or VendorSourceUSB, if known
+	Vendor       string            `json:"vendor,omitempty"`
+	ProductID    string            `json:"product_id,omitempty"`
+	Transport    string            `json:"transport,omitempty"` // e.g. "PCIe", "UART", "USB"
+	Discoverable bool              `json:"discoverable"`
+	Services     []string          `json:"services,omitempty"`
+	State        string            `json:"state"`          // "on", "off", or "unknown"
+	Info         map[string]string `json:"info,omitempty"` // raw controller_* properties
+}
+
+// controllerFromInfo builds a controller from controller_* properties, as
+// reported by system_profiler or mapped from BlueZ adapter properties.
+func controllerFromInfo(info map[string]string) Controller {
+	c := Controller{
+		Address:   info["controller_address"],
+		Chipset:   info["controller_chipset"],
+		Firmware:  info["controller_firmwareVersion"],
+		ProductID: info["controller_productID"],
+		Transport: info["controller_transport"],
+		State:     powerStateFromInfo(info),
+		Info:      info,
+	}
+	c.VendorID, c.Vendor = splitVendor(info["controller_vendorID"])
+	if c.VendorID != "" {
+		// system_profiler gives a Bluetooth SIG company ID, except for a
+		// USB-attached controller, which reports its USB vendor ID
+		// (0x05AC for Apple).
+		c.VendorSource = VendorSourceBluetooth
+		if strings.EqualFold(c.Transport, "USB") {
+			c.VendorSource = VendorSourceUSB
+		}
+	}
+	if c.VendorID == "" {
+		c.VendorSource, c.VendorID, c.ProductID = parseModalias(info["controller_modalias"])
+	}
+	if c.Vendor == "" && c.VendorID != "" {
+		product, _ := Products.Lookup(c.VendorSource, c.VendorID, "")
+		c.Vendor = product.Vendor
+	}
+	c.Discoverable, _ = parseAttribBool(info["controller_discoverable"])
+	if services := info["controller_supportedServices"]; services != "" {
+		c.Services = parseServices(services)
+	}
+	return c
+}
+
+// splitVendor splits a vendor ID such as "0x004C (Apple)" into its ID and name.
+func splitVendor(s string) (id, name string) {
+	id, rest, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return id, ""
+	}
+	return id, strings.Trim(strings.TrimSpace(rest), "()")
+}
+
+// attribValue normalizes a system_profiler attribute value such as
+// "attrib_on" or "attrib_Yes" to lowercase without the prefix ("on", "yes").
+func attribValue(s string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "attrib_"))
+}
+
+// parseAttribBool interprets an on/off attribute in any of the encodings
+// seen in practice: "attrib_on", "attrib_Yes", "yes", "true", "1".
+// The second result is false if the value is not recognised.
+func parseAttribBool(s string) (bool, bool) {
+	switch attribValue(s) {
+	case "on", "yes", "true", "1":
+		return true, true
+	case "off", "no", "false", "0":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// powerStateFromInfo derives "on" or "off" from controller_state, falling
+// back to BlueZ's controller_powered. Unrecognised values are passed through
+// without the attrib_ prefix.
+func powerStateFromInfo(info map[string]string) string {
+	state, ok := info["controller_state"]
+	if !ok {
+		if state, ok = info["controller_powered"]; !ok {
+			return "unknown"
+		}
+	}
+	on, ok := parseAttribBool(state)
+	switch {
+	case !ok:
+		return attribValue(state)
+	case on:
+		return "on"
+	default:
+		return "off"
+	}
+}
+
+// normalizeControllers fills Controllers for backends that report a single
+// controller through PowerState and ControllerInfo, and assigns untagged
+// devices to the first controller.
+func (s *Snapshot) normalizeControllers() {
+	if len(s.Controllers) == 0 && len(s.ControllerInfo) > 0 {
+		c := controllerFromInfo(s.ControllerInfo)
+		c.State = s.PowerState
+		s.Controllers = []Controller{c}
+	}
+	if len(s.Controllers) == 0 {
+		return
+	}
+	for i := range s.Devices {
+		if s.Devices[i].Controller == "" {
+			s.Devices[i].Controller = s.Controllers[0].Address
+		}
+	}
+}
+
+// DevicesOn returns the devices paired through the controller with address.
+func DevicesOn(devices []Device, address string) []Device {
+	var out []Device
+	for _, d := range devices {
+		if strings.EqualFold(d.Controller, address) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// SelectController returns the controller matching sel, which is an index
+// into controllers ("0", "1"), an address, or a chipset (case-insensitive).
+func SelectController(controllers []Controller, sel string) (*Controller, error) {
+	if i, err := strconv.Atoi(sel); err == nil {
+		if i < 0 || i >= len(controllers) {
+			return nil, fmt.Errorf("controller index out of range: %d (have %d)", i, len(controllers))
+		}
+		return &controllers[i], nil
+	}
+	for i, c := range controllers {
+		if strings.EqualFold(c.Address, sel) || strings.EqualFold(c.Chipset, sel) {
+			return &controllers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("controller not found: %s", sel)
+}
+
+// Controllers returns the Bluetooth controllers, the default one first.
+func (c *Client) Controllers(ctx context.Context) ([]Controller, error) {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Controllers, nil
+}
+
+// Controllers returns the controllers using the current backend.
+func Controllers(ctx context.Context) ([]Controller, error) {
+	return defaultClient.Controllers(ctx)
+}
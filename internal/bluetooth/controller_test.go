@@ -0,0 +1,325 @@
+package bluetooth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const multiControllerJSON = `{
+  "SPBluetoothDataType" : [
+    {
+      "controller_properties" : {
+        "controller_address" : "BC:D0:74:22:43:D6",
+        "controller_chipset" : "BCM_4387",
+        "controller_firmwareVersion" : "23.1.623.4111",
+        "controller_state" : "attrib_on"
+      },
+      "device_connected" : [
+        {
+          "AirPods Max" : {
+            "device_address" : "70:F9:4A:7A:8B:CA",
+            "device_batteryLevel" : "85%"
+          }
+        }
+      ]
+    },
+    {
+      "controller_properties" : {
+        "controller_address" : "00:1A:7D:DA:71:13",
+        "controller_chipset" : "CSR8510",
+        "controller_state" : "attrib_off"
+      },
+      "device_not_connected" : [
+        {
+          "Magic Keyboard" : {
+            "device_address" : "AA:BB:CC:DD:EE:01"
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParseSnapshot_MultipleControllers(t *testing.T) {
+	snap, err := ParseSnapshot([]byte(multiControllerJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(snap.Controllers) != 2 {
+		t.Fatalf("expected 2 controllers, got %d", len(snap.Controllers))
+	}
+	builtin, dongle := snap.Controllers[0], snap.Controllers[1]
+	if builtin.Address != "BC:D0:74:22:43:D6" || builtin.Chipset != "BCM_4387" || builtin.Firmware != "23.1.623.4111" || builtin.State != "on" {
+		t.Errorf("unexpected built-in controller: %+v", builtin)
+	}
+	if dongle.Address != "00:1A:7D:DA:71:13" || dongle.Chipset != "CSR8510" || dongle.State != "off" {
+		t.Errorf("unexpected dongle controller: %+v", dongle)
+	}
+
+	// The first controller remains the default.
+	if snap.PowerState != "on" || snap.ControllerInfo["controller_address"] != "BC:D0:74:22:43:D6" {
+		t.Errorf("expected default controller to be the built-in one, got %s %v", snap.PowerState, snap.ControllerInfo)
+	}
+
+	if len(snap.Devices) != 2 {
+		t.Fatalf("expected 2 devices, got %d", len(snap.Devices))
+	}
+	if snap.Devices[0].Controller != builtin.Address {
+		t.Errorf("expected AirPods Max on %s, got %s", builtin.Address, snap.Devices[0].Controller)
+	}
+	if snap.Devices[1].Controller != dongle.Address {
+		t.Errorf("expected Magic Keyboard on %s, got %s", dongle.Address, snap.Devices[1].Controller)
+	}
+}
+
+func TestSnapshot_NormalizeControllers(t *testing.T) {
+	snap := &Snapshot{
+		PowerState:     "on",
+		ControllerInfo: map[string]string{"controller_address": "BC:D0:74:22:43:D6", "controller_chipset": "BCM_4387"},
+		Devices:        []Device{{Name: "AirPods Max"}, {Name: "Dongle Mouse", Controller: "00:1A:7D:DA:71:13"}},
+	}
+	snap.normalizeControllers()
+
+	if len(snap.Controllers) != 1 {
+		t.Fatalf("expected 1 controller, got %d", len(snap.Controllers))
+	}
+	if c := snap.Controllers[0]; c.Address != "BC:D0:74:22:43:D6" || c.Chipset != "BCM_4387" || c.State != "on" {
+		t.Errorf("unexpected controller: %+v", c)
+	}
+	if snap.Devices[0].Controller != "BC:D0:74:22:43:D6" {
+		t.Errorf("expected untagged device on default controller, got %q", snap.Devices[0].Controller)
+	}
+	if snap.Devices[1].Controller != "00:1A:7D:DA:71:13" {
+		t.Errorf("expected tagged device unchanged, got %q", snap.Devices[1].Controller)
+	}
+}
+
+func TestSelectController(t *testing.T) {
+	controllers := []Controller{
+		{Address: "BC:D0:74:22:43:D6", Chipset: "BCM_4387"},
+		{Address: "00:1A:7D:DA:71:13", Chipset: "CSR8510"},
+	}
+
+	tests := []struct {
+		sel     string
+		want    string
+		wantErr bool
+	}{
+		{"0", "BC:D0:74:22:43:D6", false},
+		{"1", "00:1A:7D:DA:71:13", false},
+		{"00:1a:7d:da:71:13", "00:1A:7D:DA:71:13", false},
+		{"bcm_4387", "BC:D0:74:22:43:D6", false},
+		{"2", "", true},
+		{"-1", "", true},
+		{"nope", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sel, func(t *testing.T) {
+			c, err := SelectController(controllers, tt.sel)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", c)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Address != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, c.Address)
+			}
+		})
+	}
+}
+
+func TestDiagReport_ForController(t *testing.T) {
+	snap, err := ParseSnapshot([]byte(multiControllerJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report := &DiagReport{
+		PowerState:       snap.PowerState,
+		ControllerInfo:   snap.ControllerInfo,
+		Controllers:      snap.Controllers,
+		ConnectedDevices: snap.Devices,
+		RecentDisconnects: []HistoryEvent{
+			{Device: "AirPods Max", EventType: "disconnected"},
+			{Device: "AA:BB:CC:DD:EE:01", Address: "AA:BB:CC:DD:EE:01", EventType: "disconnected"},
+		},
+		devices: snap.Devices,
+	}
+
+	dongle, err := report.ForController("CSR8510")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dongle.PowerState != "off" || dongle.ControllerInfo["controller_chipset"] != "CSR8510" {
+		t.Errorf("unexpected dongle report: %s %v", dongle.PowerState, dongle.ControllerInfo)
+	}
+	if len(dongle.ConnectedDevices) != 1 || dongle.ConnectedDevices[0].Name != "Magic Keyboard" {
+		t.Errorf("expected only the Magic Keyboard, got %+v", dongle.ConnectedDevices)
+	}
+	if len(dongle.RecentDisconnects) != 1 || dongle.RecentDisconnects[0].Address != "AA:BB:CC:DD:EE:01" {
+		t.Errorf("expected only the Magic Keyboard's disconnect, got %+v", dongle.RecentDisconnects)
+	}
+	if len(report.ConnectedDevices) != 2 || len(report.RecentDisconnects) != 2 {
+		t.Errorf("expected original report unchanged, got %d devices", len(report.ConnectedDevices))
+	}
+
+	if _, err := report.ForController("5"); err == nil {
+		t.Error("expected error for unknown controller")
+	}
+}
+
+func TestParseBluezObjects_MultipleAdapters(t *testing.T) {
+	objects := fakeBluezObjects()
+	const dongle = dbus.ObjectPath("/org/bluez/hci1")
+	objects[dongle] = map[string]map[string]dbus.Variant{
+		bluezAdapterIface: {
+			"Address": dbus.MakeVariant("00:1A:7D:DA:71:13"),
+			"Powered": dbus.MakeVariant(false),
+		},
+	}
+	objects[dongle+"/dev_AA_BB_CC_DD_EE_01"] = map[string]map[string]dbus.Variant{
+		bluezDeviceIface: {
+			"Address": dbus.MakeVariant("AA:BB:CC:DD:EE:01"),
+			"Name":    dbus.MakeVariant("Magic Keyboard"),
+			"Paired":  dbus.MakeVariant(true),
+		},
+	}
+
+	snap := parseBluezObjects(objects)
+
+	if len(snap.Controllers) != 2 {
+		t.Fatalf("expected 2 controllers, got %d", len(snap.Controllers))
+	}
+	if c := snap.Controllers[1]; c.Address != "00:1A:7D:DA:71:13" || c.State != "off" {
+		t.Errorf("unexpected second controller: %+v", c)
+	}
+	if snap.PowerState != "on" || snap.ControllerInfo["controller_address"] != "BC:D0:74:22:43:D6" {
+		t.Errorf("expected hci0 as default controller, got %s %v", snap.PowerState, snap.ControllerInfo)
+	}
+
+	keyboard := DevicesOn(snap.Devices, "00:1a:7d:da:71:13")
+	if len(keyboard) != 1 || keyboard[0].Name != "Magic Keyboard" {
+		t.Errorf("expected Magic Keyboard on hci1, got %+v", keyboard)
+	}
+	if builtin := DevicesOn(snap.Devices, "BC:D0:74:22:43:D6"); len(builtin) != 2 {
+		t.Errorf("expected 2 devices on hci0, got %+v", builtin)
+	}
+}
+
+func TestControllerFromInfo(t *testing.T) {
+	c := controllerFromInfo(map[string]string{
+		"controller_address":           "BC:D0:74:22:43:D6",
+		"controller_chipset":           "BCM_4387",
+		"controller_discoverable":      "attrib_off",
+		"controller_firmwareVersion":   "23.1.623.4111",
+		"controller_productID":         "0x4A09",
+		"controller_state":             "attrib_on",
+		"controller_supportedServices": "0x392039 < HFP AVRCP A2DP HID Braille AACP GATT Serial >",
+		"controller_transport":         "PCIe",
+		"controller_vendorID":          "0x004C (Apple)",
+	})
+
+	want := Controller{
+		Address:      "BC:D0:74:22:43:D6",
+		Chipset:      "BCM_4387",
+		Firmware:     "23.1.623.4111",
+		VendorID:     "0x004C",
+		VendorSource: VendorSourceBluetooth,
+		Vendor:       "Apple",
+		ProductID:    "0x4A09",
+		Transport:    "PCIe",
+		Services:     []string{"HFP", "AVRCP", "A2DP", "HID", "Braille", "AACP", "GATT", "Serial"},
+		State:        "on",
+		Info:         c.Info,
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("unexpected controller:\n got %+v\nwant %+v", c, want)
+	}
+}
+
+func TestControllerFromInfo_Legacy(t *testing.T) {
+	// Older macOS releases spell attributes attrib_Yes/attrib_No.
+	c := controllerFromInfo(map[string]string{
+		"controller_address":      "00:1A:7D:DA:71:13",
+		"controller_discoverable": "attrib_Yes",
+		"controller_state":        "attrib_No",
+		"controller_transport":    "USB",
+		"controller_vendorID":     "0x05AC",
+	})
+	if !c.Discoverable || c.State != "off" {
+		t.Errorf("expected discoverable and off, got %+v", c)
+	}
+	if c.VendorID != "0x05AC" || c.Vendor != "Apple" {
+		t.Errorf("expected vendor from product database, got %q %q", c.VendorID, c.Vendor)
+	}
+}
+
+func TestControllerFromInfo_BlueZ(t *testing.T) {
+	c := controllerFromInfo(map[string]string{
+		"controller_address":      "BC:D0:74:22:43:D6",
+		"controller_powered":      "yes",
+		"controller_discoverable": "no",
+		"controller_modalias":     "usb:v1D6Bp0246d0548",
+	})
+	if c.State != "on" || c.Discoverable {
+		t.Errorf("expected on and not discoverable, got %+v", c)
+	}
+	if c.VendorID != "0x1D6B" || c.ProductID != "0x0246" || c.Vendor != "Linux Foundation" {
+		t.Errorf("unexpected IDs from modalias: %q %q %q", c.VendorID, c.ProductID, c.Vendor)
+	}
+}
+
+func TestParseAttribBool(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   bool
+		wantOK bool
+	}{
+		{"attrib_on", true, true},
+		{"attrib_off", false, true},
+		{"attrib_Yes", true, true},
+		{"attrib_No", false, true},
+		{"yes", true, true},
+		{"no", false, true},
+		{"true", true, true},
+		{"0", false, true},
+		{"attrib_unknown", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := parseAttribBool(tt.in)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseAttribBool(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPowerStateFromInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		info map[string]string
+		want string
+	}{
+		{"attrib on", map[string]string{"controller_state": "attrib_on"}, "on"},
+		{"attrib Yes", map[string]string{"controller_state": "attrib_Yes"}, "on"},
+		{"attrib off", map[string]string{"controller_state": "attrib_off"}, "off"},
+		{"bluez powered", map[string]string{"controller_powered": "no"}, "off"},
+		{"unrecognised", map[string]string{"controller_state": "attrib_turning_on"}, "turning_on"},
+		{"missing", map[string]string{"controller_address": "BC:D0:74:22:43:D6"}, "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := powerStateFromInfo(tt.info); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package bluetooth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DeviceDetails holds the extended properties a backend reports for a device.
+// Values are kept as reported (e.g. VendorID "0x004C").
+type DeviceDetails struct {
+	FirmwareVersion     string            `json:"firmware_version,omitempty"`
+	CaseFirmwareVersion string            `json:"case_firmware_version,omitempty"`
+	VendorID            string            `json:"vendor_id,omitempty"`
+	VendorSource        string            `json:"vendor_source,omitempty"` // VendorSourceUSB for a USB vendor ID; empty or VendorSourceBluetooth otherwise
+	ProductID           string            `json:"product_id,omitempty"`
+	SerialNumber        string            `json:"serial_number,omitempty"`
+	SerialNumberLeft    string            `json:"serial_number_left,omitempty"`
+	SerialNumberRight   string            `json:"serial_number_right,omitempty"`
+	Services            []string          `json:"services,omitempty"`
+	Extra               map[string]string `json:"extra,omitempty"` // unrecognised fields, keyed as reported
+}
+
+// deviceKeys are the system_profiler fields already carried by Device.
+var deviceKeys = map[string]bool{
+	"device_address":           true,
+	"device_minorType":         true,
+	"device_rssi":              true,
+	"device_batteryLevel":      true,
+	"device_batteryLevelMain":  true,
+	"device_batteryLevelLeft":  true,
+	"device_batteryLevelRight": true,
+	"device_batteryLevelCase":  true,
+}
+
+// detailSetters map system_profiler fields to DeviceDetails fields.
+var detailSetters = map[string]func(*DeviceDetails, string){
+	"device_firmwareVersion":   func(d *DeviceDetails, v string) { d.FirmwareVersion = v },
+	"device_caseVersion":       func(d *DeviceDetails, v string) { d.CaseFirmwareVersion = v },
+	"device_vendorID":          func(d *DeviceDetails, v string) { d.VendorID = v },
+	"device_productID":         func(d *DeviceDetails, v string) { d.ProductID = v },
+	"device_serialNumber":      func(d *DeviceDetails, v string) { d.SerialNumber = v },
+	"device_serialNumberLeft":  func(d *DeviceDetails, v string) { d.SerialNumberLeft = v },
+	"device_serialNumberRight": func(d *DeviceDetails, v string) { d.SerialNumberRight = v },
+	"device_services":          func(d *DeviceDetails, v string) { d.Services = parseServices(v) },
+}
+
+// parseDeviceDetails collects the system_profiler fields not carried by Device.
+// Unrecognised fields land in Extra. Returns nil if there are none.
+func parseDeviceDetails(props map[string]interface{}) *DeviceDetails {
+	details := &DeviceDetails{}
+	found := false
+	for key, val := range props {
+		if deviceKeys[key] {
+			continue
+		}
+		found = true
+		s := detailString(val)
+		if set, ok := detailSetters[key]; ok {
+			set(details, s)
+			continue
+		}
+		if details.Extra == nil {
+			details.Extra = make(map[string]string)
+		}
+		details.Extra[key] = s
+	}
+	if !found {
+		return nil
+	}
+	return details
+}
+
+// detailString formats a system_profiler value. Strings are kept verbatim;
+// anything else is encoded as JSON.
+func detailString(val interface{}) string {
+	if s, ok := val.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(data)
+}
+
+// parseServices splits a device_services value such as
+// "0x392019 < HFP AVRCP A2DP AACP GATT ACL >" into service names.
+// Values without the angle-bracket list are split on whitespace and commas.
+func parseServices(s string) []string {
+	if open := strings.Index(s, "<"); open >= 0 {
+		if end := strings.LastIndex(s, ">"); end > open {
+			s = s[open+1 : end]
+		}
+	}
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// parseModalias extracts the vendor ID source and the vendor and product
+// IDs from a BlueZ modalias such as "bluetooth:v004Cp201Fd0000", with the
+// IDs formatted like system_profiler's "0x004C". The source is the prefix,
+// e.g. VendorSourceBluetooth or VendorSourceUSB. It returns empty strings
+// if the value does not match.
+func parseModalias(modalias string) (source, vendor, product string) {
+	source, ids, ok := strings.Cut(modalias, ":")
+	if !ok || len(ids) < 10 || ids[0] != 'v' || ids[5] != 'p' {
+		return "", "", ""
+	}
+	return strings.ToLower(source), "0x" + strings.ToUpper(ids[1:5]), "0x" + strings.ToUpper(ids[6:10])
+}
@@ -0,0 +1,113 @@
+package bluetooth
+
+import (
+	"reflect"
+	"testing"
+)
+
+const detailedJSON = `{
+  "SPBluetoothDataType" : [
+    {
+      "device_connected" : [
+        {
+          "AirPods Pro" : {
+            "device_address" : "74:15:F5:4E:D0:50",
+            "device_batteryLevelLeft" : "100%",
+            "device_caseVersion" : "1.3.0",
+            "device_firmwareVersion" : "6A326",
+            "device_minorType" : "Headphones",
+            "device_productID" : "0x2014",
+            "device_serialNumber" : "GX8DTHMK0C6L",
+            "device_serialNumberLeft" : "H2YDTHMK1059",
+            "device_serialNumberRight" : "H2YDTHMK0NWF",
+            "device_services" : "0x980019 < HFP AVRCP A2DP AACP GATT ACL >",
+            "device_vendorID" : "0x004C",
+            "device_isconfigured" : "attrib_Yes",
+            "device_otherInfo" : ["a", "b"]
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParseDevices_Details(t *testing.T) {
+	devices, err := ParseDevices([]byte(detailedJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Details == nil {
+		t.Fatalf("expected one device with details, got %+v", devices)
+	}
+
+	want := &DeviceDetails{
+		FirmwareVersion:     "6A326",
+		CaseFirmwareVersion: "1.3.0",
+		VendorID:            "0x004C",
+		ProductID:           "0x2014",
+		SerialNumber:        "GX8DTHMK0C6L",
+		SerialNumberLeft:    "H2YDTHMK1059",
+		SerialNumberRight:   "H2YDTHMK0NWF",
+		Services:            []string{"HFP", "AVRCP", "A2DP", "AACP", "GATT", "ACL"},
+		Extra: map[string]string{
+			"device_isconfigured": "attrib_Yes",
+			"device_otherInfo":    `["a","b"]`,
+		},
+	}
+	if got := devices[0].Details; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected details %+v, got %+v", want, got)
+	}
+}
+
+func TestParseDevices_NoDetails(t *testing.T) {
+	devices, err := ParseDevices([]byte(sampleJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, d := range devices {
+		switch d.Name {
+		case "AirPods Max":
+			if d.Details == nil || d.Details.FirmwareVersion != "7E108" || d.Details.VendorID != "0x004C" {
+				t.Errorf("unexpected AirPods Max details: %+v", d.Details)
+			}
+		case "iPhone":
+			if d.Details != nil {
+				t.Errorf("expected no details for iPhone, got %+v", d.Details)
+			}
+		}
+	}
+}
+
+func TestParseServices(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"0x392019 < HFP AVRCP A2DP >", []string{"HFP", "AVRCP", "A2DP"}},
+		{"HFP, A2DP", []string{"HFP", "A2DP"}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := parseServices(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseServices(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseModalias(t *testing.T) {
+	tests := []struct {
+		input                   string
+		source, vendor, product string
+	}{
+		{"bluetooth:v004Cp201Fd0000", "bluetooth", "0x004C", "0x201F"},
+		{"usb:v1d6bp0246d0548", "usb", "0x1D6B", "0x0246"},
+		{"bluetooth:", "", "", ""},
+		{"garbage", "", "", ""},
+	}
+	for _, tt := range tests {
+		source, vendor, product := parseModalias(tt.input)
+		if source != tt.source || vendor != tt.vendor || product != tt.product {
+			t.Errorf("parseModalias(%q) = %q, %q, %q, want %q, %q, %q", tt.input, source, vendor, product, tt.source, tt.vendor, tt.product)
+		}
+	}
+}
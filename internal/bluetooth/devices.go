@@ -1,9 +1,7 @@
 package bluetooth
 
 import (
-	"encoding/json"
-	"fmt"
-	"os/exec"
+	"context"
 	"strconv"
 	"strings"
 )
@@ -16,9 +14,15 @@ type Device struct {
 	Connected    bool   `json:"connected"`
 	BatteryLevel int    `json:"battery_level"` // 0-100, or -1 if unknown
 	RSSI         int    `json:"rssi"`          // signal strength, or 0 if unknown
+
+	Controller string           `json:"controller,omitempty"` // address of the controller it is paired through
+	Batteries  []BatteryReading `json:"batteries,omitempty"`  // per-component levels; BatteryLevel summarises them
+	Details    *DeviceDetails   `json:"details,omitempty"`    // extended properties, nil if none reported
+	Product    *ProductInfo     `json:"product,omitempty"`    // model and capabilities from the product database
 }
 
-// systemProfilerOutput represents the top-level system_profiler JSON.
+// systemProfilerOutput represents the top-level system_profiler JSON,
+// normalized to the modern layout by parseSystemProfiler.
 type systemProfilerOutput struct {
 	SPBluetoothDataType []bluetoothData `json:"SPBluetoothDataType"`
 }
@@ -30,42 +34,99 @@ type bluetoothData struct {
 	DeviceNotConnected   []map[string]interface{} `json:"device_not_connected"`
 }
 
-// commandRunner abstracts command execution for testing.
-var commandRunner = func(name string, args ...string) ([]byte, error) {
-	return exec.Command(name, args...).Output()
+// ListDevices returns all paired Bluetooth devices (connected and disconnected).
+func (c *Client) ListDevices(ctx context.Context) ([]Device, error) {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Devices, nil
 }
 
-// ListDevices returns all paired Bluetooth devices (connected and disconnected).
-func ListDevices() ([]Device, error) {
-	out, err := commandRunner("system_profiler", "SPBluetoothDataType", "-json")
+// ListDevices returns all paired devices using the current backend.
+func ListDevices(ctx context.Context) ([]Device, error) {
+	return defaultClient.ListDevices(ctx)
+}
+
+// snapshot returns the cached snapshot, reading the backend within the
+// snapshot timeout when it has expired. Devices are annotated from the
+// product database, and fresh snapshots are recorded in the store.
+func (c *Client) snapshot(ctx context.Context) (*Snapshot, error) {
+	return c.cache.get(ctx, snapshotTool(c.backend), func(ctx context.Context) (*Snapshot, error) {
+		ctx, cancel := c.withTimeout(ctx, DefaultSnapshotTimeout)
+		defer cancel()
+		snap, err := c.backend.Snapshot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		snap.normalizeControllers()
+		annotateProducts(Products, snap.Devices)
+		c.observe(snap)
+		return snap, nil
+	})
+}
+
+// snapshotTools names, by backend, the tool a stalled snapshot waits on.
+var snapshotTools = map[string]string{
+	"macos":        "system_profiler",
+	"bluetoothctl": "bluetoothctl",
+	"bluez":        "bluetoothd",
+}
+
+// snapshotTool returns the tool named in a snapshot TimeoutError for b.
+// Backends without an entry are named after themselves.
+func snapshotTool(b Backend) string {
+	if tool, ok := snapshotTools[b.Name()]; ok {
+		return tool
+	}
+	return b.Name()
+}
+
+// ParseSnapshot parses system_profiler SPBluetoothDataType JSON output into a snapshot.
+// Each SPBluetoothDataType entry is one controller; the first is the default.
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	report := &DiagReport{ControllerInfo: make(map[string]string)}
+	if err := parseDiagnosticData(data, report); err != nil {
+		return nil, err
+	}
+	devices, err := ParseDevices(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to run system_profiler: %w", err)
+		return nil, err
 	}
-	return ParseDevices(out)
+	return &Snapshot{
+		PowerState:     report.PowerState,
+		ControllerInfo: report.ControllerInfo,
+		Controllers:    report.Controllers,
+		Devices:        devices,
+	}, nil
 }
 
 // ParseDevices parses system_profiler SPBluetoothDataType JSON output into devices.
+// Devices from every controller are returned, tagged with the controller's address.
+// Both the Monterey-and-later and the older device_title layouts are accepted.
 func ParseDevices(data []byte) ([]Device, error) {
-	var sp systemProfilerOutput
-	if err := json.Unmarshal(data, &sp); err != nil {
-		return nil, fmt.Errorf("failed to parse bluetooth data: %w", err)
-	}
-
-	if len(sp.SPBluetoothDataType) == 0 {
-		return nil, nil
+	sp, err := parseSystemProfiler(data)
+	if err != nil {
+		return nil, err
 	}
 
-	bt := sp.SPBluetoothDataType[0]
 	var devices []Device
+	for _, bt := range sp.SPBluetoothDataType {
+		start := len(devices)
 
-	for _, entry := range bt.DeviceConnected {
-		d := parseDeviceEntry(entry, true)
-		devices = append(devices, d...)
-	}
+		for _, entry := range bt.DeviceConnected {
+			d := parseDeviceEntry(entry, true)
+			devices = append(devices, d...)
+		}
+
+		for _, entry := range bt.DeviceNotConnected {
+			d := parseDeviceEntry(entry, false)
+			devices = append(devices, d...)
+		}
 
-	for _, entry := range bt.DeviceNotConnected {
-		d := parseDeviceEntry(entry, false)
-		devices = append(devices, d...)
+		for i := start; i < len(devices); i++ {
+			devices[i].Controller = bt.ControllerProperties["controller_address"]
+		}
 	}
 
 	return devices, nil
@@ -87,34 +148,52 @@ func parseDeviceEntry(entry map[string]interface{}, connected bool) []Device {
 			Connected:    connected,
 			BatteryLevel: parseBatteryLevel(props),
 			RSSI:         parseRSSI(props),
+			Batteries:    parseBatteryComponents(props),
+			Details:      parseDeviceDetails(props),
 		}
 		devices = append(devices, d)
 	}
 	return devices
 }
 
-// parseBatteryLevel extracts battery level from device properties.
-// It checks device_batteryLevel, device_batteryLevelMain, device_batteryLevelLeft,
-// and device_batteryLevelRight fields. Returns the first found, or -1 if unknown.
+// parseBatteryLevel summarises a device's battery as one level: its
+// single or main battery if it has one, otherwise the lower of its buds,
+// so "L 100% R 40%" reads as 40, otherwise its case. Returns -1 if unknown.
 func parseBatteryLevel(props map[string]interface{}) int {
-	// Try main battery level fields in priority order
-	for _, key := range []string{
-		"device_batteryLevel",
-		"device_batteryLevelMain",
-		"device_batteryLevelLeft",
-		"device_batteryLevelCase",
-	} {
-		if val, ok := props[key]; ok {
-			if level := parseBatteryString(val); level >= 0 {
-				return level
-			}
+	if val, ok := props["device_batteryLevel"]; ok {
+		if level := parseBatteryString(val); level >= 0 {
+			return level
 		}
 	}
+	levels := make(map[BatteryComponent]int)
+	for _, r := range parseBatteryComponents(props) {
+		levels[r.Component] = r.Level
+	}
+	if level, ok := levels[BatteryMain]; ok {
+		return level
+	}
+	left, hasLeft := levels[BatteryLeft]
+	right, hasRight := levels[BatteryRight]
+	switch {
+	case hasLeft && hasRight:
+		return min(left, right)
+	case hasLeft:
+		return left
+	case hasRight:
+		return right
+	}
+	if level, ok := levels[BatteryCase]; ok {
+		return level
+	}
 	return -1
 }
 
 // parseBatteryString parses a battery level string like "75%" to an integer.
+// Older releases report plain JSON numbers, which are accepted too.
 func parseBatteryString(val interface{}) int {
+	if f, ok := val.(float64); ok {
+		val = strconv.Itoa(int(f))
+	}
 	s, ok := val.(string)
 	if !ok {
 		return -1
@@ -138,6 +217,9 @@ func parseRSSI(props map[string]interface{}) int {
 	if !ok {
 		return 0
 	}
+	if f, ok := val.(float64); ok {
+		return int(f)
+	}
 	s, ok := val.(string)
 	if !ok {
 		return 0
@@ -163,8 +245,8 @@ func getString(m map[string]interface{}, key string) string {
 }
 
 // ListConnected returns only connected Bluetooth devices.
-func ListConnected() ([]Device, error) {
-	devices, err := ListDevices()
+func (c *Client) ListConnected(ctx context.Context) ([]Device, error) {
+	devices, err := c.ListDevices(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -177,17 +259,21 @@ func ListConnected() ([]Device, error) {
 	return connected, nil
 }
 
-// GetDevice finds a device by name or address (case-insensitive).
-func GetDevice(nameOrAddr string) (*Device, error) {
-	devices, err := ListDevices()
+// GetDevice resolves an alias, name, or address to a device; see FindDevice.
+func (c *Client) GetDevice(ctx context.Context, nameOrAddr string) (*Device, error) {
+	devices, err := c.ListDevices(ctx)
 	if err != nil {
 		return nil, err
 	}
-	search := strings.ToLower(nameOrAddr)
-	for _, d := range devices {
-		if strings.ToLower(d.Name) == search || strings.ToLower(d.Address) == search {
-			return &d, nil
-		}
-	}
-	return nil, fmt.Errorf("device not found: %s", nameOrAddr)
+	return FindDevice(devices, c.config.Expand(nameOrAddr))
+}
+
+// ListConnected returns connected devices using the current backend.
+func ListConnected(ctx context.Context) ([]Device, error) {
+	return defaultClient.ListConnected(ctx)
+}
+
+// GetDevice finds a device by alias, name, or address using the current backend.
+func GetDevice(ctx context.Context, nameOrAddr string) (*Device, error) {
+	return defaultClient.GetDevice(ctx, nameOrAddr)
 }
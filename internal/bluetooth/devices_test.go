@@ -1,6 +1,11 @@
 package bluetooth
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -124,9 +129,9 @@ func TestParseDevices(t *testing.T) {
 	if airpodsPro.Connected {
 		t.Error("AirPods Pro should be disconnected")
 	}
-	// batteryLevelLeft is checked before batteryLevelCase in priority order
+	// The buds are summarised in preference to the case
 	if airpodsPro.BatteryLevel != 100 {
-		t.Errorf("expected BatteryLevel 100 (from Left), got %d", airpodsPro.BatteryLevel)
+		t.Errorf("expected BatteryLevel 100 (from the buds), got %d", airpodsPro.BatteryLevel)
 	}
 
 	// Verify device without battery
@@ -224,6 +229,7 @@ func TestParseBatteryString(t *testing.T) {
 		{"over 100", "150%", -1},
 		{"nil", nil, -1},
 		{"number type", 42, -1},
+		{"JSON number", float64(42), 42},
 	}
 
 	for _, tt := range tests {
@@ -260,14 +266,11 @@ func TestParseRSSI(t *testing.T) {
 }
 
 func TestListDevices_WithMock(t *testing.T) {
-	orig := commandRunner
-	defer func() { commandRunner = orig }()
-
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return []byte(sampleJSON), nil
 	}
 
-	devices, err := ListDevices()
+	devices, err := NewClient(newMacOSBackend(run, nil)).ListDevices(t.Context())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -277,14 +280,11 @@ func TestListDevices_WithMock(t *testing.T) {
 }
 
 func TestListConnected_WithMock(t *testing.T) {
-	orig := commandRunner
-	defer func() { commandRunner = orig }()
-
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return []byte(sampleJSON), nil
 	}
 
-	devices, err := ListConnected()
+	devices, err := NewClient(newMacOSBackend(run, nil)).ListConnected(t.Context())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -297,14 +297,11 @@ func TestListConnected_WithMock(t *testing.T) {
 }
 
 func TestGetDevice_ByName(t *testing.T) {
-	orig := commandRunner
-	defer func() { commandRunner = orig }()
-
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return []byte(sampleJSON), nil
 	}
 
-	d, err := GetDevice("airpods max")
+	d, err := NewClient(newMacOSBackend(run, nil)).GetDevice(t.Context(), "airpods max")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -314,14 +311,11 @@ func TestGetDevice_ByName(t *testing.T) {
 }
 
 func TestGetDevice_ByAddress(t *testing.T) {
-	orig := commandRunner
-	defer func() { commandRunner = orig }()
-
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return []byte(sampleJSON), nil
 	}
 
-	d, err := GetDevice("70:F9:4A:7A:8B:CA")
+	d, err := NewClient(newMacOSBackend(run, nil)).GetDevice(t.Context(), "70:F9:4A:7A:8B:CA")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -331,14 +325,11 @@ func TestGetDevice_ByAddress(t *testing.T) {
 }
 
 func TestGetDevice_NotFound(t *testing.T) {
-	orig := commandRunner
-	defer func() { commandRunner = orig }()
-
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return []byte(sampleJSON), nil
 	}
 
-	_, err := GetDevice("nonexistent")
+	_, err := NewClient(newMacOSBackend(run, nil)).GetDevice(t.Context(), "nonexistent")
 	if err == nil {
 		t.Fatal("expected error for nonexistent device")
 	}
@@ -355,15 +346,24 @@ func TestParseBatteryLevel_Priority(t *testing.T) {
 		t.Errorf("expected 50 (from device_batteryLevel), got %d", got)
 	}
 
-	// When only Left is present
+	// When buds are present, the lower one wins over the case
 	props2 := map[string]interface{}{
-		"device_batteryLevelLeft":  "80%",
-		"device_batteryLevelRight": "90%",
+		"device_batteryLevelLeft":  "90%",
+		"device_batteryLevelRight": "80%",
 		"device_batteryLevelCase":  "60%",
 	}
 	got2 := parseBatteryLevel(props2)
 	if got2 != 80 {
-		t.Errorf("expected 80 (from device_batteryLevelLeft), got %d", got2)
+		t.Errorf("expected 80 (from device_batteryLevelRight), got %d", got2)
+	}
+
+	// When only one bud is present
+	props5 := map[string]interface{}{
+		"device_batteryLevelLeft": "70%",
+		"device_batteryLevelCase": "20%",
+	}
+	if got := parseBatteryLevel(props5); got != 70 {
+		t.Errorf("expected 70 (from device_batteryLevelLeft), got %d", got)
 	}
 
 	// When only Case is present
@@ -459,3 +459,115 @@ func TestParseDevices_ConnectedOnly(t *testing.T) {
 		}
 	}
 }
+
+func TestListDevices_Replay(t *testing.T) {
+	run, err := NewReplayRunner("testdata/replay/airpods-case")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	devices, err := NewClient(newMacOSBackend(run, nil)).ListDevices(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("expected 2 devices, got %d", len(devices))
+	}
+	if devices[0].Name != "AirPods Pro" || !devices[0].Connected {
+		t.Errorf("expected connected AirPods Pro, got %+v", devices[0])
+	}
+	if got := FormatBatteries(devices[0].Batteries); got != "L 100% R 40% C 77%" {
+		t.Errorf("expected per-component batteries, got %q", got)
+	}
+}
+
+// TestParseSnapshot_Releases parses one captured system_profiler layout per
+// macOS release. Every fixture describes the same fleet.
+func TestParseSnapshot_Releases(t *testing.T) {
+	tests := []struct {
+		file    string
+		schema  profilerSchema
+		chipset string
+	}{
+		{"macos-10.15-catalina.json", schemaLegacy, "BCM_4364B3"},
+		{"macos-11-big-sur.json", schemaLegacy, "BCM_4364B3"},
+		{"macos-12-monterey.json", schemaModern, "BCM_4364B3"},
+		{"macos-13-ventura.json", schemaModern, "BCM_4378"},
+		{"macos-14-sonoma.json", schemaModern, "BCM_4387"},
+		{"macos-15-sequoia.json", schemaModern, "BCM_4387"},
+		{"macos-26-beta.json", schemaModern, "BCM_4388"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "system_profiler", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := detectFixtureSchema(t, data); got != tt.schema {
+				t.Errorf("expected %s schema, got %s", tt.schema, got)
+			}
+
+			snap, err := ParseSnapshot(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if snap.PowerState != "on" {
+				t.Errorf("expected power state on, got %s", snap.PowerState)
+			}
+			if len(snap.Controllers) != 1 {
+				t.Fatalf("expected 1 controller, got %d", len(snap.Controllers))
+			}
+			c := snap.Controllers[0]
+			if c.Address != "BC:D0:74:22:43:D6" || c.Chipset != tt.chipset || c.Vendor != "Apple" || c.Discoverable {
+				t.Errorf("unexpected controller: %+v", c)
+			}
+
+			if len(snap.Devices) != 3 {
+				t.Fatalf("expected 3 paired devices, got %d: %+v", len(snap.Devices), snap.Devices)
+			}
+			airpods, keyboard, flex := snap.Devices[0], snap.Devices[1], snap.Devices[2]
+
+			if airpods.Name != "AirPods Pro" || airpods.Address != "74:15:F5:4E:D0:50" || !airpods.Connected {
+				t.Errorf("unexpected AirPods Pro: %+v", airpods)
+			}
+			if airpods.RSSI != -52 || airpods.MinorType != "Headphones" || airpods.Controller != c.Address {
+				t.Errorf("unexpected AirPods Pro properties: %+v", airpods)
+			}
+			wantBuds := []BatteryReading{{BatteryLeft, 100}, {BatteryRight, 40}, {BatteryCase, 77}}
+			if airpods.BatteryLevel != 40 || !reflect.DeepEqual(airpods.Batteries, wantBuds) {
+				t.Errorf("unexpected AirPods Pro batteries: %d %+v", airpods.BatteryLevel, airpods.Batteries)
+			}
+			if airpods.Details == nil || airpods.Details.VendorID != "0x004C" {
+				t.Errorf("expected AirPods Pro vendor ID, got %+v", airpods.Details)
+			}
+			if extra := airpods.Details.Extra; extra["device_isconnected"] != "" || extra["device_ispaired"] != "" {
+				t.Errorf("expected legacy connection keys consumed, got %v", extra)
+			}
+
+			if keyboard.Name != "Magic Keyboard" || keyboard.Address != "AA:BB:CC:DD:EE:01" || keyboard.Connected || keyboard.BatteryLevel != 64 {
+				t.Errorf("unexpected Magic Keyboard: %+v", keyboard)
+			}
+			if keyboard.HasComponentBatteries() {
+				t.Errorf("expected no component batteries for Magic Keyboard, got %+v", keyboard.Batteries)
+			}
+
+			if flex.Name != "Beats Flex" || flex.Address != "A8:91:3D:DE:91:C6" || flex.Connected || flex.BatteryLevel != -1 {
+				t.Errorf("unexpected Beats Flex: %+v", flex)
+			}
+		})
+	}
+}
+
+// detectFixtureSchema reports the schema of a fixture's first controller.
+func detectFixtureSchema(t *testing.T, data []byte) profilerSchema {
+	t.Helper()
+	var raw struct {
+		SPBluetoothDataType []map[string]json.RawMessage `json:"SPBluetoothDataType"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil || len(raw.SPBluetoothDataType) == 0 {
+		t.Fatalf("invalid fixture: %v", err)
+	}
+	return detectSchema(raw.SPBluetoothDataType[0])
+}
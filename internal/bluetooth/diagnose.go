@@ -1,7 +1,8 @@
 package bluetooth
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -9,58 +10,108 @@ import (
 // DiagReport contains Bluetooth diagnostic information.
 type DiagReport struct {
 	PowerState       string            `json:"power_state"`
-	ControllerInfo   map[string]string `json:"controller_info"`
+	ControllerInfo   map[string]string `json:"controller_info"` // raw properties of the default controller
+	Controllers      []Controller      `json:"controllers,omitempty"`
 	ConnectedDevices []Device          `json:"connected_devices"`
 	RecentErrors     []string          `json:"recent_errors"`
+	// LogUnavailable says why there is no log to check, for backends that
+	// keep none; RecentErrors and RecentDisconnects are then empty.
+	LogUnavailable string `json:"log_unavailable,omitempty"`
+	// RecentDisconnects are the disconnections in the same window, with
+	// their reasons, so a device turned off can be told from a lost link.
+	RecentDisconnects []HistoryEvent `json:"recent_disconnects,omitempty"`
+
+	devices []Device // every paired device, to attribute disconnects to a controller
+	offline bool     // the log is another machine's, so disconnects are not this machine's devices
 }
 
 // Diagnose performs a comprehensive Bluetooth diagnostic check.
-func Diagnose() (*DiagReport, error) {
-	report := &DiagReport{
-		ControllerInfo: make(map[string]string),
-	}
-
-	// Get power state from system_profiler
-	spOut, err := commandRunner("system_profiler", "SPBluetoothDataType", "-json")
+func (c *Client) Diagnose(ctx context.Context) (*DiagReport, error) {
+	snap, err := c.snapshot(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to run system_profiler: %w", err)
-	}
-
-	if err := parseDiagnosticData(spOut, report); err != nil {
 		return nil, err
 	}
 
-	// Get connected devices
-	devices, err := ParseDevices(spOut)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse devices: %w", err)
+	report := &DiagReport{
+		PowerState:     snap.PowerState,
+		ControllerInfo: snap.ControllerInfo,
+		Controllers:    snap.Controllers,
+		devices:        snap.Devices,
+		offline:        !c.logSource.IsLive(),
 	}
-	for _, d := range devices {
+	if report.ControllerInfo == nil {
+		report.ControllerInfo = make(map[string]string)
+	}
+
+	for _, d := range snap.Devices {
 		if d.Connected {
 			report.ConnectedDevices = append(report.ConnectedDevices, d)
 		}
 	}
 
 	// Get recent Bluetooth errors from log
-	logOut, err := commandRunner("log", "show",
-		"--predicate", `subsystem == "com.apple.bluetooth"`,
-		"--last", "5m",
-		"--style", "compact")
-	if err != nil {
+	logOut, err := c.readLog(ctx, LogQuery{Last: "5m"})
+	switch {
+	case errors.Is(err, ErrUnsupported):
+		// Not a Bluetooth fault: this backend has no log to read.
+		report.LogUnavailable = err.Error()
+	case err != nil:
 		// Log collection is best-effort; don't fail the whole report
 		report.RecentErrors = append(report.RecentErrors, fmt.Sprintf("could not collect logs: %v", err))
-	} else {
+	default:
 		report.RecentErrors = parseLogErrors(string(logOut))
+		report.RecentDisconnects = HistoryFilter{EventType: "disconnected"}.Filter(ParseHistoryEvents(string(logOut)))
+		if !report.offline {
+			nameEvents(report.RecentDisconnects, snap.Devices)
+		}
 	}
 
 	return report, nil
 }
 
+// ForController narrows the report to the controller matching sel
+// (an index, address, or chipset; see SelectController). Disconnects in
+// the live log are kept only for the devices paired to it; those in
+// another machine's log are all kept, as this machine's devices say
+// nothing about them.
+func (r *DiagReport) ForController(sel string) (*DiagReport, error) {
+	c, err := SelectController(r.Controllers, sel)
+	if err != nil {
+		return nil, err
+	}
+	out := *r
+	out.PowerState = c.State
+	out.ControllerInfo = c.Info
+	out.Controllers = []Controller{*c}
+	out.ConnectedDevices = DevicesOn(r.ConnectedDevices, c.Address)
+	out.devices = DevicesOn(r.devices, c.Address)
+	if r.offline {
+		return &out, nil
+	}
+	out.RecentDisconnects = nil
+	for _, ev := range r.RecentDisconnects {
+		for _, d := range out.devices {
+			if (HistoryFilter{Name: d.Name, Address: d.Address}).Match(ev) {
+				out.RecentDisconnects = append(out.RecentDisconnects, ev)
+				break
+			}
+		}
+	}
+	return &out, nil
+}
+
+// Diagnose runs a diagnostic check using the current backend.
+func Diagnose(ctx context.Context) (*DiagReport, error) {
+	return defaultClient.Diagnose(ctx)
+}
+
 // parseDiagnosticData extracts controller info and power state from system_profiler JSON.
+// PowerState and ControllerInfo describe the first (default) controller;
+// Controllers lists every controller.
 func parseDiagnosticData(data []byte, report *DiagReport) error {
-	var sp systemProfilerOutput
-	if err := json.Unmarshal(data, &sp); err != nil {
-		return fmt.Errorf("failed to parse bluetooth data: %w", err)
+	sp, err := parseSystemProfiler(data)
+	if err != nil {
+		return err
 	}
 
 	if len(sp.SPBluetoothDataType) == 0 {
@@ -68,29 +119,27 @@ func parseDiagnosticData(data []byte, report *DiagReport) error {
 		return nil
 	}
 
+	for _, bt := range sp.SPBluetoothDataType {
+		if len(bt.ControllerProperties) == 0 {
+			continue
+		}
+		report.Controllers = append(report.Controllers, controllerFromInfo(bt.ControllerProperties))
+	}
+
 	bt := sp.SPBluetoothDataType[0]
 	for k, v := range bt.ControllerProperties {
 		report.ControllerInfo[k] = v
 	}
-
-	// Extract power state from controller_state
-	if state, ok := bt.ControllerProperties["controller_state"]; ok {
-		switch state {
-		case "attrib_on":
-			report.PowerState = "on"
-		case "attrib_off":
-			report.PowerState = "off"
-		default:
-			report.PowerState = state
-		}
-	} else {
-		report.PowerState = "unknown"
-	}
+	report.PowerState = controllerFromInfo(bt.ControllerProperties).State
 
 	return nil
 }
 
-// parseLogErrors extracts error lines from Bluetooth log output.
+// parseLogErrors extracts error lines from Bluetooth log output. ndjson
+// records count if their type is Error or Fault or their message looks like
+// an error, and are rendered like compact lines. A disconnect that gives a
+// reason counts only if the reason is a fault, such as a supervision
+// timeout; one the user or the device chose is not an error.
 func parseLogErrors(logOutput string) []string {
 	var errors []string
 	lines := strings.Split(logOutput, "\n")
@@ -99,10 +148,25 @@ func parseLogErrors(logOutput string) []string {
 		if line == "" {
 			continue
 		}
-		lower := strings.ToLower(line)
+		text := line
+		if entry, ok := parseLogEntry(line); ok {
+			if entry.MessageType == "Error" || entry.MessageType == "Fault" {
+				errors = append(errors, entry.String())
+				continue
+			}
+			text, line = entry.EventMessage, entry.String()
+		}
+		if classifyMessage(text) == "disconnected" {
+			if reason, _ := parseDisconnectReason(text); reason != "" {
+				if reason.IsError() {
+					errors = append(errors, line)
+				}
+				continue
+			}
+		}
+		lower := strings.ToLower(text)
 		if strings.Contains(lower, "error") ||
 			strings.Contains(lower, "fail") ||
-			strings.Contains(lower, "disconnect") ||
 			strings.Contains(lower, "timeout") {
 			errors = append(errors, line)
 		}
@@ -110,12 +174,16 @@ func parseLogErrors(logOutput string) []string {
 	return errors
 }
 
-// Reset kills the Bluetooth daemon, which macOS auto-restarts.
+// Reset restarts the Bluetooth stack.
 // Requires sudo; returns a clear error if not root.
-func Reset() error {
-	_, err := commandRunner("sudo", "pkill", "bluetoothd")
-	if err != nil {
-		return fmt.Errorf("failed to reset bluetooth (sudo required): %w", err)
-	}
-	return nil
+func (c *Client) Reset(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx, DefaultPowerTimeout)
+	defer cancel()
+	defer c.cache.invalidate()
+	return c.backend.Reset(ctx)
+}
+
+// Reset restarts the Bluetooth stack using the current backend.
+func Reset(ctx context.Context) error {
+	return defaultClient.Reset(ctx)
 }
@@ -1,7 +1,10 @@
 package bluetooth
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -107,7 +110,17 @@ func TestParseLogErrors(t *testing.T) {
 		{
 			"error and fail",
 			"error: something\nfailed to connect\ntimeout waiting\nnormal line\ndisconnected\n",
-			4,
+			3,
+		},
+		{
+			"disconnect reasons",
+			`Disconnected from "AirPods Max", reason 0x13 (Remote User Terminated Connection)
+Disconnected from "AirPods Max" with error 0x15
+Disconnected from "Magic Mouse", reason 0x08 (Connection Timeout)
+link loss detected for 70:F9:4A:7A:8B:CA
+Disconnected from "Magic Keyboard": authentication failure
+`,
+			3,
 		},
 		{
 			"empty",
@@ -137,11 +150,8 @@ func TestParseLogErrors(t *testing.T) {
 }
 
 func TestDiagnose_Success(t *testing.T) {
-	origCmd := commandRunner
-	defer func() { commandRunner = origCmd }()
-
 	callCount := 0
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		callCount++
 		if name == "system_profiler" {
 			return []byte(diagJSON), nil
@@ -152,7 +162,7 @@ func TestDiagnose_Success(t *testing.T) {
 		return nil, nil
 	}
 
-	report, err := Diagnose()
+	report, err := NewClient(newMacOSBackend(run, nil)).Diagnose(t.Context())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -170,11 +180,87 @@ func TestDiagnose_Success(t *testing.T) {
 	}
 }
 
-func TestDiagnose_LogError(t *testing.T) {
-	origCmd := commandRunner
-	defer func() { commandRunner = origCmd }()
+func TestDiagnose_Disconnects(t *testing.T) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "system_profiler" {
+			return []byte(diagJSON), nil
+		}
+		return []byte(ndjsonLine("2024-07-15 10:30:00.000000-0700", "Default", `Disconnected from "AirPods Max", reason 0x13`) + "\n" +
+			ndjsonLine("2024-07-15 10:31:00.000000-0700", "Default", "Disconnection complete for 11:22:33:44:55:66, status 0x00, reason 0x08") + "\n" +
+			ndjsonLine("2024-07-15 10:32:00.000000-0700", "Default", `Connected to "AirPods Max"`)), nil
+	}
+
+	report, err := NewClient(newMacOSBackend(run, nil)).Diagnose(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.RecentDisconnects) != 2 {
+		t.Fatalf("expected 2 disconnects, got %+v", report.RecentDisconnects)
+	}
+	if ev := report.RecentDisconnects[0]; ev.Reason != ReasonRemoteUserTerminated || ev.ReasonCode != 0x13 {
+		t.Errorf("expected the headphones turned off, got %+v", ev)
+	}
+	if ev := report.RecentDisconnects[1]; ev.Reason != ReasonConnectionTimeout || ev.ReasonCode != 0x08 {
+		t.Errorf("expected a supervision timeout, got %+v", ev)
+	}
+	if len(report.RecentErrors) != 1 || !strings.Contains(report.RecentErrors[0], "reason 0x08") {
+		t.Errorf("expected only the timeout reported as an error, got %v", report.RecentErrors)
+	}
+}
+
+func TestDiagnose_Input(t *testing.T) {
+	fake := newFakeBackend()
+	c := NewClient(fake)
+	c.SetLogSource(LogSource{Input: []byte(ndjsonLine("2024-07-15 10:20:00.000000-0700", "Error", "old failure") + "\n" +
+		ndjsonLine("2024-07-15 10:28:00.000000-0700", "Error", "recent failure") + "\n" +
+		ndjsonLine("2024-07-15 10:30:00.000000-0700", "Default", `Disconnected from "AirPods Max", reason 0x13`))})
+
+	report, err := c.Diagnose(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.RecentErrors) != 1 || !strings.Contains(report.RecentErrors[0], "recent failure") {
+		t.Errorf("expected the last 5 minutes of the input, got %v", report.RecentErrors)
+	}
+	if len(report.RecentDisconnects) != 1 {
+		t.Errorf("expected 1 disconnect, got %+v", report.RecentDisconnects)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("expected the live log left alone, got %v", fake.calls)
+	}
+}
+
+func TestDiagnose_InputDisconnects(t *testing.T) {
+	snap, err := ParseSnapshot([]byte(multiControllerJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fake := newFakeBackend()
+	fake.snap = snap
+	c := NewClient(fake)
+	c.SetLogSource(LogSource{Input: []byte(ndjsonLine("2024-07-15 10:30:00.000000-0700", "Default",
+		"Disconnection complete for 70:F9:4A:7A:8B:CA, status 0x00, reason 0x08") + "\n" +
+		ndjsonLine("2024-07-15 10:31:00.000000-0700", "Default", `Disconnected from "Studio Speaker", reason 0x13`))})
 
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	report, err := c.Diagnose(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.RecentDisconnects) != 2 || report.RecentDisconnects[0].Device != "70:F9:4A:7A:8B:CA" {
+		t.Errorf("expected another machine's address left unnamed, got %+v", report.RecentDisconnects)
+	}
+
+	dongle, err := report.ForController("CSR8510")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dongle.RecentDisconnects) != 2 {
+		t.Errorf("expected another machine's disconnects kept, got %+v", dongle.RecentDisconnects)
+	}
+}
+
+func TestDiagnose_LogError(t *testing.T) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		if name == "system_profiler" {
 			return []byte(diagJSON), nil
 		}
@@ -184,7 +270,7 @@ func TestDiagnose_LogError(t *testing.T) {
 		return nil, nil
 	}
 
-	report, err := Diagnose()
+	report, err := NewClient(newMacOSBackend(run, nil)).Diagnose(t.Context())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -198,34 +284,44 @@ func TestDiagnose_LogError(t *testing.T) {
 	}
 }
 
-func TestDiagnose_SystemProfilerError(t *testing.T) {
-	origCmd := commandRunner
-	defer func() { commandRunner = origCmd }()
+func TestDiagnose_NoLog(t *testing.T) {
+	fake := newFakeBackend()
+	fake.logErr = fmt.Errorf("%w: log retrieval (fake)", ErrUnsupported)
 
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	report, err := NewClient(fake).Diagnose(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.RecentErrors) != 0 {
+		t.Errorf("expected a missing log not reported as an error, got %v", report.RecentErrors)
+	}
+	if !strings.Contains(report.LogUnavailable, "log retrieval (fake)") {
+		t.Errorf("expected the missing log noted, got %q", report.LogUnavailable)
+	}
+}
+
+func TestDiagnose_SystemProfilerError(t *testing.T) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		if name == "system_profiler" {
 			return nil, errors.New("command failed")
 		}
 		return nil, nil
 	}
 
-	_, err := Diagnose()
+	_, err := NewClient(newMacOSBackend(run, nil)).Diagnose(t.Context())
 	if err == nil {
 		t.Fatal("expected error when system_profiler fails")
 	}
 }
 
 func TestReset_Success(t *testing.T) {
-	origCmd := commandRunner
-	defer func() { commandRunner = origCmd }()
-
 	var gotArgs []string
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		gotArgs = append([]string{name}, args...)
 		return nil, nil
 	}
 
-	err := Reset()
+	err := NewClient(newMacOSBackend(run, nil)).Reset(t.Context())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -236,15 +332,33 @@ func TestReset_Success(t *testing.T) {
 }
 
 func TestReset_Error(t *testing.T) {
-	origCmd := commandRunner
-	defer func() { commandRunner = origCmd }()
-
-	commandRunner = func(name string, args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return nil, errors.New("permission denied")
 	}
 
-	err := Reset()
+	err := NewClient(newMacOSBackend(run, nil)).Reset(t.Context())
 	if err == nil {
 		t.Fatal("expected error when reset fails")
 	}
 }
+
+func TestDiagnose_Replay(t *testing.T) {
+	run, err := NewReplayRunner("testdata/replay/airpods-case")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report, err := NewClient(newMacOSBackend(run, nil)).Diagnose(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.ControllerInfo["controller_chipset"] != "BCM_4387" {
+		t.Errorf("expected chipset BCM_4387, got %s", report.ControllerInfo["controller_chipset"])
+	}
+	if len(report.ConnectedDevices) != 1 {
+		t.Errorf("expected 1 connected device, got %d", len(report.ConnectedDevices))
+	}
+	if len(report.RecentErrors) != 1 || !strings.Contains(report.RecentErrors[0], "bluetoothd[412] [com.apple.bluetooth:Server] page timeout") {
+		t.Errorf("expected the page timeout error, got %d: %v", len(report.RecentErrors), report.RecentErrors)
+	}
+}
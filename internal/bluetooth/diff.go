@@ -0,0 +1,162 @@
+package bluetooth
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ChangeKind names what changed between two snapshots.
+type ChangeKind string
+
+// Kinds of change DiffSnapshots reports.
+const (
+	ChangePaired     ChangeKind = "paired"     // a device was paired
+	ChangeUnpaired   ChangeKind = "unpaired"   // a device was unpaired
+	ChangeConnection ChangeKind = "connection" // a device connected or disconnected
+	ChangeBattery    ChangeKind = "battery"    // a battery component's level changed
+	ChangeFirmware   ChangeKind = "firmware"   // a device's firmware changed
+	ChangeController ChangeKind = "controller" // a controller appeared, went, or changed
+	ChangeBlueUtil   ChangeKind = "blueutil"   // blueutil was installed or removed
+)
+
+// Change is one difference between two snapshots.
+type Change struct {
+	Kind    ChangeKind `json:"kind"`
+	Device  string     `json:"device,omitempty"`
+	Address string     `json:"address,omitempty"` // of the device or controller
+	Field   string     `json:"field,omitempty"`   // e.g. "state" or "firmware", or the battery component
+	From    string     `json:"from,omitempty"`
+	To      string     `json:"to,omitempty"`
+	Delta   int        `json:"delta,omitempty"` // battery change in percentage points
+}
+
+// DiffSnapshots returns the changes from a to b: controllers first, then
+// devices in a's order followed by devices new in b. Devices and
+// controllers are matched by address.
+func DiffSnapshots(a, b *SnapshotFile) []Change {
+	var changes []Change
+	changes = append(changes, diffControllers(a, b)...)
+	if a.BlueUtilInstalled != b.BlueUtilInstalled {
+		changes = append(changes, Change{Kind: ChangeBlueUtil,
+			From: installedString(a.BlueUtilInstalled), To: installedString(b.BlueUtilInstalled)})
+	}
+
+	after := make(map[string]Device, len(b.Devices))
+	for _, d := range b.Devices {
+		after[normalizeAddress(d.Address)] = d
+	}
+	seen := make(map[string]bool, len(a.Devices))
+	for _, before := range a.Devices {
+		key := normalizeAddress(before.Address)
+		seen[key] = true
+		now, ok := after[key]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeUnpaired, Device: before.Name, Address: before.Address})
+			continue
+		}
+		changes = append(changes, diffDevice(before, now)...)
+	}
+	for _, d := range b.Devices {
+		if !seen[normalizeAddress(d.Address)] {
+			changes = append(changes, Change{Kind: ChangePaired, Device: d.Name, Address: d.Address})
+		}
+	}
+	return changes
+}
+
+// diffControllers compares the controllers, or the power state when
+// neither snapshot lists controllers.
+func diffControllers(a, b *SnapshotFile) []Change {
+	if len(a.Controllers) == 0 && len(b.Controllers) == 0 {
+		if a.PowerState != b.PowerState {
+			return []Change{{Kind: ChangeController, Field: "state", From: a.PowerState, To: b.PowerState}}
+		}
+		return nil
+	}
+
+	var changes []Change
+	after := make(map[string]Controller, len(b.Controllers))
+	for _, c := range b.Controllers {
+		after[normalizeAddress(c.Address)] = c
+	}
+	seen := make(map[string]bool, len(a.Controllers))
+	for _, before := range a.Controllers {
+		key := normalizeAddress(before.Address)
+		seen[key] = true
+		now, ok := after[key]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeController, Address: before.Address, Field: "removed"})
+			continue
+		}
+		for _, f := range []struct{ field, from, to string }{
+			{"state", before.State, now.State},
+			{"firmware", before.Firmware, now.Firmware},
+			{"chipset", before.Chipset, now.Chipset},
+			{"discoverable", strconv.FormatBool(before.Discoverable), strconv.FormatBool(now.Discoverable)},
+		} {
+			if f.from != f.to {
+				changes = append(changes, Change{Kind: ChangeController, Address: before.Address, Field: f.field, From: f.from, To: f.to})
+			}
+		}
+	}
+	for _, c := range b.Controllers {
+		if !seen[normalizeAddress(c.Address)] {
+			changes = append(changes, Change{Kind: ChangeController, Address: c.Address, Field: "added"})
+		}
+	}
+	return changes
+}
+
+// diffDevice compares one device in two snapshots. Battery levels are
+// compared only for components reported in both.
+func diffDevice(a, b Device) []Change {
+	var changes []Change
+	if a.Connected != b.Connected {
+		changes = append(changes, Change{Kind: ChangeConnection, Device: b.Name, Address: b.Address,
+			From: connectedString(a.Connected), To: connectedString(b.Connected)})
+	}
+
+	var fwA, fwB, caseA, caseB string
+	if a.Details != nil {
+		fwA, caseA = a.Details.FirmwareVersion, a.Details.CaseFirmwareVersion
+	}
+	if b.Details != nil {
+		fwB, caseB = b.Details.FirmwareVersion, b.Details.CaseFirmwareVersion
+	}
+	if fwA != fwB {
+		changes = append(changes, Change{Kind: ChangeFirmware, Device: b.Name, Address: b.Address, Field: "firmware", From: fwA, To: fwB})
+	}
+	if caseA != caseB {
+		changes = append(changes, Change{Kind: ChangeFirmware, Device: b.Name, Address: b.Address, Field: "case firmware", From: caseA, To: caseB})
+	}
+
+	levels := make(map[BatteryComponent]int)
+	for _, r := range batteryReadings(a) {
+		levels[r.Component] = r.Level
+	}
+	for _, r := range batteryReadings(b) {
+		before, ok := levels[r.Component]
+		if !ok || before == r.Level {
+			continue
+		}
+		changes = append(changes, Change{Kind: ChangeBattery, Device: b.Name, Address: b.Address, Field: string(r.Component),
+			From: fmt.Sprintf("%d%%", before), To: fmt.Sprintf("%d%%", r.Level), Delta: r.Level - before})
+	}
+	return changes
+}
+
+// connectedString describes a connection state for a Change.
+func connectedString(connected bool) string {
+	if connected {
+		return "connected"
+	}
+	return "disconnected"
+}
+
+// installedString describes whether blueutil is installed for a Change.
+func installedString(installed bool) string {
+	if installed {
+		return "installed"
+	}
+	return "not installed"
+}
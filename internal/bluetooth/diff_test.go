@@ -0,0 +1,137 @@
+package bluetooth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func diffFixture() *SnapshotFile {
+	return &SnapshotFile{
+		Version:    SnapshotFileVersion,
+		PowerState: "on",
+		Controllers: []Controller{
+			{Address: "BC:D0:74:22:43:D6", Chipset: "BCM_4387", Firmware: "20.1.4", State: "on"},
+		},
+		Devices: []Device{
+			{Name: "AirPods Pro", Address: "74:15:F5:4E:D0:50", Connected: true, BatteryLevel: 100,
+				Batteries: []BatteryReading{{BatteryLeft, 100}, {BatteryRight, 90}, {BatteryCase, 77}},
+				Details:   &DeviceDetails{FirmwareVersion: "6A300", CaseFirmwareVersion: "2.0.1"}},
+			{Name: "Magic Keyboard", Address: "AA:BB:CC:DD:EE:01", BatteryLevel: 64},
+			{Name: "Beats Flex", Address: "A8:91:3D:DE:91:C6", BatteryLevel: -1},
+		},
+		BlueUtilInstalled: true,
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	a := diffFixture()
+	if changes := DiffSnapshots(a, diffFixture()); len(changes) != 0 {
+		t.Fatalf("expected identical snapshots to have no changes, got %+v", changes)
+	}
+
+	b := diffFixture()
+	b.Controllers[0].Firmware = "21.0.0"
+	b.Controllers[0].Address = "bc-d0-74-22-43-d6" // still the same controller
+	b.BlueUtilInstalled = false
+	b.Devices[0].Connected = false
+	b.Devices[0].Batteries = []BatteryReading{{BatteryLeft, 80}, {BatteryRight, 90}}
+	b.Devices[0].Details = &DeviceDetails{FirmwareVersion: "6F8", CaseFirmwareVersion: "2.0.1"}
+	b.Devices = append(b.Devices[:2], Device{Name: "Magic Mouse", Address: "AA:BB:CC:DD:EE:02", BatteryLevel: -1})
+
+	want := []Change{
+		{Kind: ChangeController, Address: "BC:D0:74:22:43:D6", Field: "firmware", From: "20.1.4", To: "21.0.0"},
+		{Kind: ChangeBlueUtil, From: "installed", To: "not installed"},
+		{Kind: ChangeConnection, Device: "AirPods Pro", Address: "74:15:F5:4E:D0:50", From: "connected", To: "disconnected"},
+		{Kind: ChangeFirmware, Device: "AirPods Pro", Address: "74:15:F5:4E:D0:50", Field: "firmware", From: "6A300", To: "6F8"},
+		{Kind: ChangeBattery, Device: "AirPods Pro", Address: "74:15:F5:4E:D0:50", Field: "left", From: "100%", To: "80%", Delta: -20},
+		{Kind: ChangeUnpaired, Device: "Beats Flex", Address: "A8:91:3D:DE:91:C6"},
+		{Kind: ChangePaired, Device: "Magic Mouse", Address: "AA:BB:CC:DD:EE:02"},
+	}
+	if got := DiffSnapshots(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected changes:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestDiffSnapshots_Controllers(t *testing.T) {
+	a := diffFixture()
+	b := diffFixture()
+	b.Controllers[0].State = "off"
+	b.Controllers = append(b.Controllers, Controller{Address: "00:1A:7D:DA:71:13", State: "on"})
+
+	want := []Change{
+		{Kind: ChangeController, Address: "BC:D0:74:22:43:D6", Field: "state", From: "on", To: "off"},
+		{Kind: ChangeController, Address: "00:1A:7D:DA:71:13", Field: "added"},
+	}
+	if got := DiffSnapshots(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected changes:\n got %+v\nwant %+v", got, want)
+	}
+	if got := DiffSnapshots(b, a); len(got) != 2 || got[1].Field != "removed" {
+		t.Errorf("expected the USB controller removed, got %+v", got)
+	}
+}
+
+func TestSnapshotFile_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "before.json")
+	want := diffFixture()
+	if err := want.WriteFile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := ReadSnapshotFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changes := DiffSnapshots(want, got); len(changes) != 0 {
+		t.Errorf("expected the snapshot to survive a round trip, got %+v", changes)
+	}
+}
+
+func TestParseSnapshotFile_Version(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"current", `{"version":1,"devices":[]}`, false},
+		{"no version", `{"devices":[]}`, true},
+		{"newer", `{"version":2,"devices":[]}`, true},
+		{"not JSON", "Bluetooth:", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSnapshotFile([]byte(tt.data)); (err != nil) != tt.wantErr {
+				t.Errorf("ParseSnapshotFile() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_CaptureSnapshotFile(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "system_profiler", "macos-15-sequoia.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewFileBackend(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := NewClient(b).CaptureSnapshotFile(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Version != SnapshotFileVersion || f.Time.IsZero() || f.Backend != "file" || len(f.Controllers) != 1 || len(f.Devices) != 3 || f.BlueUtilInstalled {
+		t.Errorf("unexpected snapshot: %+v", f)
+	}
+
+	// blueutil presence comes from the backend's tools, whatever the backend.
+	fake := newFakeBackend()
+	fake.tools = map[string]bool{"blueutil": true}
+	f, err = NewClient(fake).CaptureSnapshotFile(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !f.BlueUtilInstalled || f.Backend != "fake" {
+		t.Errorf("expected blueutil reported installed, got %+v", f)
+	}
+}
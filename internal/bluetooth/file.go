@@ -0,0 +1,77 @@
+package bluetooth
+
+import (
+	"context"
+	"fmt"
+)
+
+// fileBackend serves a captured `system_profiler SPBluetoothDataType -json`
+// document, so another machine's report can be inspected offline. It has
+// no log and cannot change anything.
+type fileBackend struct {
+	data []byte
+}
+
+// NewFileBackend returns a backend that reads devices and controllers from
+// a captured system_profiler document. It fails if data does not parse.
+func NewFileBackend(data []byte) (Backend, error) {
+	if _, err := ParseSnapshot(data); err != nil {
+		return nil, fmt.Errorf("failed to read system_profiler document: %w", err)
+	}
+	return &fileBackend{data: data}, nil
+}
+
+// Name returns "file".
+func (b *fileBackend) Name() string {
+	return "file"
+}
+
+// Snapshot parses the captured document.
+func (b *fileBackend) Snapshot(ctx context.Context) (*Snapshot, error) {
+	return ParseSnapshot(b.data)
+}
+
+// CanControl returns ErrUnsupported; a captured report is read-only.
+func (b *fileBackend) CanControl(ctx context.Context) error {
+	return fmt.Errorf("%w: device control (offline input)", ErrUnsupported)
+}
+
+// Tools returns nil; a captured report runs nothing.
+func (b *fileBackend) Tools(ctx context.Context) map[string]bool {
+	return nil
+}
+
+// Connect is not supported.
+func (b *fileBackend) Connect(ctx context.Context, address string) error {
+	return b.CanControl(ctx)
+}
+
+// Disconnect is not supported.
+func (b *fileBackend) Disconnect(ctx context.Context, address string) error {
+	return b.CanControl(ctx)
+}
+
+// Remove is not supported.
+func (b *fileBackend) Remove(ctx context.Context, address string) error {
+	return b.CanControl(ctx)
+}
+
+// SetPower is not supported.
+func (b *fileBackend) SetPower(ctx context.Context, on bool) error {
+	return fmt.Errorf("%w: power control (offline input)", ErrUnsupported)
+}
+
+// Reset is not supported.
+func (b *fileBackend) Reset(ctx context.Context) error {
+	return fmt.Errorf("%w: reset (offline input)", ErrUnsupported)
+}
+
+// ReadLog is not supported; the log comes from a log source instead.
+func (b *fileBackend) ReadLog(ctx context.Context, q LogQuery) ([]byte, error) {
+	return nil, fmt.Errorf("%w: log retrieval (offline input)", ErrUnsupported)
+}
+
+// StreamLog is not supported.
+func (b *fileBackend) StreamLog(ctx context.Context, fn func(line string)) error {
+	return fmt.Errorf("%w: log streaming (offline input)", ErrUnsupported)
+}
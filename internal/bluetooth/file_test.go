@@ -0,0 +1,75 @@
+package bluetooth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileBackend_Golden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "system_profiler", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("expected system_profiler fixtures, got %v (%v)", files, err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := NewFileBackend(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c := NewClient(b)
+
+			devices, err := c.ListDevices(t.Context())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(devices) != 3 {
+				t.Errorf("expected 3 devices, got %d", len(devices))
+			}
+			d, err := c.GetDevice(t.Context(), "airpods pro")
+			if err != nil || d.Address != "74:15:F5:4E:D0:50" {
+				t.Errorf("expected AirPods Pro by name, got %+v (%v)", d, err)
+			}
+
+			report, err := c.Diagnose(t.Context())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if report.PowerState != "on" || len(report.Controllers) != 1 {
+				t.Errorf("unexpected report: %+v", report)
+			}
+		})
+	}
+}
+
+func TestFileBackend_ReadOnly(t *testing.T) {
+	b, err := NewFileBackend([]byte(sampleJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := NewClient(b)
+	for name, err := range map[string]error{
+		"connect":   c.Connect(t.Context(), "70:F9:4A:7A:8B:CA"),
+		"power off": c.PowerOff(t.Context()),
+		"reset":     c.Reset(t.Context()),
+	} {
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: expected ErrUnsupported, got %v", name, err)
+		}
+	}
+	if _, err := c.FetchHistory(t.Context(), LogQuery{Last: "1h"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected no live log, got %v", err)
+	}
+}
+
+func TestFileBackend_Invalid(t *testing.T) {
+	if _, err := NewFileBackend([]byte("Bluetooth:\n  State: On\n")); err == nil {
+		t.Error("expected a non-JSON document to be rejected")
+	}
+}
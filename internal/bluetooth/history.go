@@ -1,7 +1,10 @@
 package bluetooth
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"path"
 	"regexp"
 	"strings"
 	"time"
@@ -9,29 +12,313 @@ import (
 
 // HistoryEvent represents a Bluetooth connect/disconnect event from system logs.
 type HistoryEvent struct {
-	Timestamp time.Time `json:"timestamp"`
-	Device    string    `json:"device"`
-	EventType string    `json:"event_type"` // "connected" or "disconnected"
-	RawLine   string    `json:"raw_line,omitempty"`
-}
-
-// logRunner abstracts log command execution for testing.
-var logRunner = func(args ...string) ([]byte, error) {
-	return commandRunner("log", args...)
+	Timestamp  time.Time        `json:"timestamp"`
+	Device     string           `json:"device"`
+	Address    string           `json:"address,omitempty"`
+	EventType  string           `json:"event_type"`            // "connected" or "disconnected"
+	Reason     DisconnectReason `json:"reason,omitempty"`      // why a disconnect happened, if logged
+	ReasonCode int              `json:"reason_code,omitempty"` // HCI reason code, 0 if not logged
+	Process    string           `json:"process,omitempty"`
+	Subsystem  string           `json:"subsystem,omitempty"`
+	Category   string           `json:"category,omitempty"`
+	RawLine    string           `json:"raw_line,omitempty"` // log message (ndjson) or whole line (compact)
 }
 
 // FetchHistory retrieves Bluetooth connect/disconnect events from the system log.
-func FetchHistory(duration string) ([]HistoryEvent, error) {
-	out, err := logRunner("show",
-		"--predicate", `subsystem == "com.apple.bluetooth"`,
-		"--style", "compact",
-		"--last", duration)
+// Events from the live log that only carry an address are named from the
+// paired devices; this machine's devices say nothing about another's log.
+func (c *Client) FetchHistory(ctx context.Context, q LogQuery) ([]HistoryEvent, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	out, err := c.readLog(ctx, q)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read system log: %w", err)
 	}
-	return ParseHistoryEvents(string(out)), nil
+	events := ParseHistoryEvents(string(out))
+	if !q.Since.IsZero() {
+		// log show works to the second; trim to the exact range.
+		events = HistoryFilter{Since: q.Since, Until: q.Until}.Filter(events)
+	}
+	if c.logSource.IsLive() && hasAddresses(events) {
+		// Naming is best-effort; the events are still useful without it.
+		if devices, err := c.ListDevices(ctx); err == nil {
+			nameEvents(events, devices)
+		}
+	}
+	return events, nil
+}
+
+// FetchHistory retrieves events using the current backend.
+func FetchHistory(ctx context.Context, q LogQuery) ([]HistoryEvent, error) {
+	return defaultClient.FetchHistory(ctx, q)
+}
+
+// lastPattern matches the windows `log show --last` accepts: a count of
+// minutes, hours, or days.
+var lastPattern = regexp.MustCompile(`^[0-9]+[mhd]$`)
+
+// Validate checks that q is a window `log show` accepts.
+func (q LogQuery) Validate() error {
+	switch {
+	case q.Since.IsZero() && !q.Until.IsZero():
+		return fmt.Errorf("invalid log range: an end time needs a start time")
+	case !q.Since.IsZero() && !q.Until.IsZero() && !q.Until.After(q.Since):
+		return fmt.Errorf("invalid log range: end %s is not after start %s",
+			q.Until.Format(time.DateTime), q.Since.Format(time.DateTime))
+	case q.Since.IsZero() && !lastPattern.MatchString(q.Last):
+		return fmt.Errorf("invalid log window %q: use minutes, hours, or days, e.g. 30m, 24h, 7d", q.Last)
+	}
+	return nil
+}
+
+// FollowHistory calls fn with each connect/disconnect event as it is
+// logged, until ctx is done. Events that only carry an address are named
+// from the paired devices, listed when the first such event arrives.
+func (c *Client) FollowHistory(ctx context.Context, fn func(HistoryEvent)) error {
+	if !c.logSource.IsLive() {
+		return fmt.Errorf("cannot follow history: only the live system log can be followed")
+	}
+	var devices []Device
+	listed := false
+	return c.backend.StreamLog(ctx, func(line string) {
+		ev, ok := parseHistoryLine(line)
+		if !ok {
+			return
+		}
+		if ev.Address != "" {
+			if !listed {
+				// Naming is best-effort, as in FetchHistory.
+				devices, _ = c.ListDevices(ctx)
+				listed = true
+			}
+			events := []HistoryEvent{ev}
+			nameEvents(events, devices)
+			ev = events[0]
+		}
+		fn(ev)
+	})
+}
+
+// FollowHistory follows events using the current backend.
+func FollowHistory(ctx context.Context, fn func(HistoryEvent)) error {
+	return defaultClient.FollowHistory(ctx, fn)
+}
+
+// HistoryFilter selects history events. Zero fields match everything.
+type HistoryFilter struct {
+	Name      string    // device name, matched case-insensitively
+	Address   string    // device address in any format
+	EventType string    // "connected", "disconnected", or "link-loss" (disconnections that lost the link)
+	Since     time.Time // inclusive
+	Until     time.Time // exclusive
+}
+
+// Match reports whether ev is selected by f. When f has a full address,
+// an event that logs an address matches the device only by it, so another
+// device with the same name is left out; other events match by name or by
+// an address in their device column.
+func (f HistoryFilter) Match(ev HistoryEvent) bool {
+	switch f.EventType {
+	case "":
+	case "link-loss":
+		if ev.EventType != "disconnected" || !ev.Reason.LinkLoss() {
+			return false
+		}
+	default:
+		if ev.EventType != f.EventType {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && ev.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !ev.Timestamp.Before(f.Until) {
+		return false
+	}
+	if f.Name == "" && f.Address == "" {
+		return true
+	}
+	addr := normalizeAddress(f.Address)
+	if len(addr) == 12 && ev.Address != "" { // six bytes in hex
+		return addr == normalizeAddress(ev.Address)
+	}
+	if f.Name != "" && strings.EqualFold(ev.Device, f.Name) {
+		return true
+	}
+	return addr != "" && (addr == normalizeAddress(ev.Address) || addr == normalizeAddress(ev.Device))
+}
+
+// Filter returns the events selected by f.
+func (f HistoryFilter) Filter(events []HistoryEvent) []HistoryEvent {
+	var out []HistoryEvent
+	for _, ev := range events {
+		if f.Match(ev) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// readLog reads the backend log within the log timeout, or the client's
+// log source if one is set.
+func (c *Client) readLog(ctx context.Context, q LogQuery) ([]byte, error) {
+	if c.logSource.Input != nil {
+		return windowLog(c.logSource.Input, q)
+	}
+	q.Archive = c.logSource.Archive
+	ctx, cancel := c.withTimeout(ctx, DefaultLogTimeout)
+	defer cancel()
+	return c.backend.ReadLog(ctx, q)
+}
+
+// logEntry is one record of `log show --style ndjson`.
+type logEntry struct {
+	Timestamp        string `json:"timestamp"` // e.g. "2024-07-15 10:30:45.123456-0700"
+	MessageType      string `json:"messageType"`
+	Subsystem        string `json:"subsystem"`
+	Category         string `json:"category"`
+	ProcessImagePath string `json:"processImagePath"`
+	ProcessID        int    `json:"processID"`
+	EventMessage     string `json:"eventMessage"`
+}
+
+// parseLogEntry decodes one ndjson log line. It returns false for lines
+// that are not JSON objects, such as compact-style output.
+func parseLogEntry(line string) (logEntry, bool) {
+	var e logEntry
+	if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &e) != nil {
+		return logEntry{}, false
+	}
+	return e, true
+}
+
+// Time parses the entry's timestamp, keeping its UTC offset.
+func (e logEntry) Time() time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05-0700", e.Timestamp)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Process returns the name of the logging process, e.g. "bluetoothd".
+func (e logEntry) Process() string {
+	if e.ProcessImagePath == "" {
+		return ""
+	}
+	return path.Base(e.ProcessImagePath)
 }
 
+// String renders the entry like a compact log line.
+func (e logEntry) String() string {
+	return fmt.Sprintf("%s %s[%d] [%s:%s] %s",
+		e.Timestamp, e.Process(), e.ProcessID, e.Subsystem, e.Category, e.EventMessage)
+}
+
+// messageDisconnectPattern matches log messages reporting a disconnection.
+var messageDisconnectPattern = regexp.MustCompile(`(?i)\b(?:disconnected|disconnection complete|link loss|connection lost|supervision timeout)\b`)
+
+// messageConnectPattern matches log messages reporting a new connection.
+var messageConnectPattern = regexp.MustCompile(`(?i)\b(?:connected|connection complete|connection established|pairing successful)\b`)
+
+// messageStatePattern matches messages that mention a connection state
+// without reporting a change, e.g. "device is connected" or "not connected".
+var messageStatePattern = regexp.MustCompile(`(?i)\b(?:not|is|already|still)\s+(?:dis)?connected\b|\bisconnected\b|\bconnected devices\b`)
+
+// addressPattern matches a Bluetooth address with colons or dashes.
+var addressPattern = regexp.MustCompile(`\b[0-9A-Fa-f]{2}(?:[:-][0-9A-Fa-f]{2}){5}\b`)
+
+// classifyMessage returns "connected" or "disconnected" for a log message
+// reporting a connection change, or "" otherwise.
+func classifyMessage(msg string) string {
+	if messageStatePattern.MatchString(msg) {
+		return ""
+	}
+	switch {
+	case messageDisconnectPattern.MatchString(msg):
+		return "disconnected"
+	case messageConnectPattern.MatchString(msg):
+		return "connected"
+	default:
+		return ""
+	}
+}
+
+// parseEntryEvent turns an ndjson entry into an event. Only messages that
+// report a connection change and identify a device, by address or by a
+// quoted name, count.
+func parseEntryEvent(e logEntry) (HistoryEvent, bool) {
+	eventType := classifyMessage(e.EventMessage)
+	if eventType == "" {
+		return HistoryEvent{}, false
+	}
+	ev := HistoryEvent{
+		Timestamp: e.Time(),
+		EventType: eventType,
+		Process:   e.Process(),
+		Subsystem: e.Subsystem,
+		Category:  e.Category,
+		RawLine:   e.EventMessage,
+	}
+	if eventType == "disconnected" {
+		ev.Reason, ev.ReasonCode = parseDisconnectReason(e.EventMessage)
+	}
+	if addr := addressPattern.FindString(e.EventMessage); addr != "" {
+		ev.Address = canonicalAddress(addr)
+	}
+	if m := deviceNamePattern.FindStringSubmatch(e.EventMessage); len(m) >= 2 {
+		ev.Device = m[1]
+	}
+	switch {
+	case ev.Device != "":
+	case ev.Address != "":
+		ev.Device = ev.Address
+	default:
+		return HistoryEvent{}, false
+	}
+	return ev, true
+}
+
+// hasAddresses reports whether any event carries a device address.
+func hasAddresses(events []HistoryEvent) bool {
+	for _, ev := range events {
+		if ev.Address != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// LastEventTime returns the latest timestamp among events, or the zero
+// time if none has one. For a captured log it stands in for now, so
+// sessions still open when the log was taken end there.
+func LastEventTime(events []HistoryEvent) time.Time {
+	var last time.Time
+	for _, ev := range events {
+		if ev.Timestamp.After(last) {
+			last = ev.Timestamp
+		}
+	}
+	return last
+}
+
+// nameEvents sets Device from the paired device with the event's address.
+func nameEvents(events []HistoryEvent, devices []Device) {
+	names := make(map[string]string, len(devices))
+	for _, d := range devices {
+		names[normalizeAddress(d.Address)] = d.Name
+	}
+	for i, ev := range events {
+		if name, ok := names[normalizeAddress(ev.Address)]; ok && ev.Address != "" {
+			events[i].Device = name
+		}
+	}
+}
+
+// The patterns below parse the older compact style (`log show --style
+// compact`), still accepted for saved logs and recordings.
+
 // connectPattern matches log lines indicating a device connection.
 // Examples:
 //
@@ -48,38 +335,48 @@ var deviceNamePattern = regexp.MustCompile(`"([^"]+)"`)
 // timestampPattern matches the compact log timestamp format: YYYY-MM-DD HH:MM:SS.ffffff
 var timestampPattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}\s+\d{2}:\d{2}:\d{2}\.\d+)`)
 
-// ParseHistoryEvents parses raw system log output into structured Bluetooth events.
+// ParseHistoryEvents parses raw system log output into structured Bluetooth
+// events. Lines are read as `log show --style ndjson` records, or as
+// compact-style text when they are not JSON.
 func ParseHistoryEvents(logOutput string) []HistoryEvent {
 	var events []HistoryEvent
-	lines := strings.Split(logOutput, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		var eventType string
-		if disconnectPattern.MatchString(line) {
-			eventType = "disconnected"
-		} else if connectPattern.MatchString(line) {
-			eventType = "connected"
-		} else {
-			continue
+	for _, line := range strings.Split(logOutput, "\n") {
+		if ev, ok := parseHistoryLine(line); ok {
+			events = append(events, ev)
 		}
+	}
+	return events
+}
 
-		ts := parseTimestamp(line)
-		device := parseDeviceName(line)
+// parseHistoryLine parses one ndjson record or compact-style line.
+func parseHistoryLine(line string) (HistoryEvent, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return HistoryEvent{}, false
+	}
+	if entry, ok := parseLogEntry(line); ok {
+		return parseEntryEvent(entry)
+	}
 
-		events = append(events, HistoryEvent{
-			Timestamp: ts,
-			Device:    device,
-			EventType: eventType,
-			RawLine:   line,
-		})
+	var eventType string
+	if disconnectPattern.MatchString(line) {
+		eventType = "disconnected"
+	} else if connectPattern.MatchString(line) {
+		eventType = "connected"
+	} else {
+		return HistoryEvent{}, false
 	}
 
-	return events
+	ev := HistoryEvent{
+		Timestamp: parseTimestamp(line),
+		Device:    parseDeviceName(line),
+		EventType: eventType,
+		RawLine:   line,
+	}
+	if eventType == "disconnected" {
+		ev.Reason, ev.ReasonCode = parseDisconnectReason(line)
+	}
+	return ev, true
 }
 
 // parseTimestamp extracts the timestamp from a compact log line.
@@ -112,3 +409,24 @@ func parseDeviceName(line string) string {
 	}
 	return "unknown"
 }
+
+// logClock is implemented by backends whose log runs on a clock of their
+// own, such as the simulation, which runs ahead of the wall clock.
+type logClock interface {
+	Now() time.Time
+}
+
+// Now returns the current time on the backend's log clock: the wall clock
+// unless the backend keeps its own. Sessions still open in the live log
+// run until then.
+func (c *Client) Now() time.Time {
+	if clock, ok := c.backend.(logClock); ok {
+		return clock.Now()
+	}
+	return time.Now()
+}
+
+// Now returns the current time on the current backend's log clock.
+func Now() time.Time {
+	return defaultClient.Now()
+}
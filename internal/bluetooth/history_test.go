@@ -1,7 +1,10 @@
 package bluetooth
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -117,6 +120,215 @@ func TestParseHistoryEvents(t *testing.T) {
 	}
 }
 
+// ndjsonLine renders a `log show --style ndjson` record for tests.
+func ndjsonLine(ts, messageType, msg string) string {
+	return fmt.Sprintf(`{"timestamp":%q,"messageType":%q,"subsystem":"com.apple.bluetooth","category":"Server","processImagePath":"/usr/sbin/bluetoothd","processID":412,"eventMessage":%q}`,
+		ts, messageType, msg)
+}
+
+func TestParseHistoryEvents_NDJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     string
+		want    string // event type, or "" for no event
+		device  string
+		address string
+	}{
+		{"connected with name and address", `Connected to "AirPods Max" (70:F9:4A:7A:8B:CA)`, "connected", "AirPods Max", "70:F9:4A:7A:8B:CA"},
+		{"connection complete with address", "HCI connection complete for 70-f9-4a-7a-8b-ca", "connected", "70:F9:4A:7A:8B:CA", "70:F9:4A:7A:8B:CA"},
+		{"disconnected", `Disconnected from "AirPods Max"`, "disconnected", "AirPods Max", ""},
+		{"link loss", "link loss detected for 70:F9:4A:7A:8B:CA", "disconnected", "70:F9:4A:7A:8B:CA", "70:F9:4A:7A:8B:CA"},
+		{"supervision timeout", "Supervision timeout for 70:F9:4A:7A:8B:CA", "disconnected", "70:F9:4A:7A:8B:CA", "70:F9:4A:7A:8B:CA"},
+		{"state, not a change", `Device "AirPods Max" is connected`, "", "", ""},
+		{"not connected", "Cannot send, 70:F9:4A:7A:8B:CA not connected", "", "", ""},
+		{"property dump", "isConnected: 1 for 70:F9:4A:7A:8B:CA", "", "", ""},
+		{"connected devices count", "Connected devices: 2", "", "", ""},
+		{"no device", "Controller connected to host", "", "", ""},
+		{"unrelated", `Starting scan for "AirPods Max"`, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := ParseHistoryEvents(ndjsonLine("2024-07-15 10:30:45.123456-0700", "Default", tt.msg))
+			if tt.want == "" {
+				if len(events) != 0 {
+					t.Errorf("expected no event, got %+v", events)
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("expected 1 event, got %d", len(events))
+			}
+			ev := events[0]
+			if ev.EventType != tt.want || ev.Device != tt.device || ev.Address != tt.address {
+				t.Errorf("unexpected event: %+v", ev)
+			}
+			if ev.Process != "bluetoothd" || ev.Subsystem != "com.apple.bluetooth" || ev.Category != "Server" || ev.RawLine != tt.msg {
+				t.Errorf("unexpected structured fields: %+v", ev)
+			}
+		})
+	}
+}
+
+func TestParseHistoryEvents_NDJSONTimezone(t *testing.T) {
+	events := ParseHistoryEvents(ndjsonLine("2024-07-15 10:30:45.123456-0700", "Default", `Connected to "AirPods Max"`))
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	want := time.Date(2024, 7, 15, 17, 30, 45, 123456000, time.UTC)
+	if !events[0].Timestamp.Equal(want) {
+		t.Errorf("expected %v, got %v", want, events[0].Timestamp)
+	}
+	if _, offset := events[0].Timestamp.Zone(); offset != -7*3600 {
+		t.Errorf("expected the logged -0700 offset kept, got %d", offset)
+	}
+}
+
+func TestFetchHistory_NamesFromAddress(t *testing.T) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "system_profiler" {
+			return []byte(sampleJSON), nil
+		}
+		return []byte(ndjsonLine("2024-07-15 10:30:45.123456-0700", "Default", "HCI connection complete for 70:F9:4A:7A:8B:CA") + "\n" +
+			ndjsonLine("2024-07-15 10:31:00.000000-0700", "Default", "link loss detected for 11:22:33:44:55:66")), nil
+	}
+
+	events, err := NewClient(newMacOSBackend(run, nil)).FetchHistory(t.Context(), LogQuery{Last: "1h"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Device != "AirPods Max" {
+		t.Errorf("expected known address named, got %q", events[0].Device)
+	}
+	if events[1].Device != "11:22:33:44:55:66" {
+		t.Errorf("expected unknown address kept, got %q", events[1].Device)
+	}
+}
+
+func TestClient_FollowHistory(t *testing.T) {
+	fake := newFakeBackend()
+	fake.log = strings.Join([]string{
+		"Filtering the log data using \"subsystem == \"com.apple.bluetooth\"\"",
+		ndjsonLine("2024-07-15 10:30:45.000000-0700", "Default", "HCI connection complete for 70:F9:4A:7A:8B:CA"),
+		ndjsonLine("2024-07-15 10:30:46.000000-0700", "Default", `Device "Magic Keyboard" is connected`),
+		ndjsonLine("2024-07-15 10:31:00.000000-0700", "Default", `Disconnected from "Magic Keyboard"`),
+	}, "\n")
+
+	var events []HistoryEvent
+	err := NewClient(fake).FollowHistory(t.Context(), func(ev HistoryEvent) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %+v", len(events), events)
+	}
+	if events[0].Device != "AirPods Max" || events[0].EventType != "connected" {
+		t.Errorf("expected address named from paired devices, got %+v", events[0])
+	}
+	if events[1].Device != "Magic Keyboard" || events[1].EventType != "disconnected" {
+		t.Errorf("unexpected second event: %+v", events[1])
+	}
+}
+
+func TestHistoryFilter(t *testing.T) {
+	max := HistoryEvent{Device: "AirPods Max", Address: "70:F9:4A:7A:8B:CA", EventType: "connected"}
+	named := HistoryEvent{Device: "AirPods Max", EventType: "disconnected"}
+	bare := HistoryEvent{Device: "70:F9:4A:7A:8B:CA", EventType: "disconnected"}
+
+	tests := []struct {
+		name   string
+		filter HistoryFilter
+		ev     HistoryEvent
+		want   bool
+	}{
+		{"empty filter", HistoryFilter{}, max, true},
+		{"type", HistoryFilter{EventType: "disconnected"}, max, false},
+		{"name", HistoryFilter{Name: "airpods max"}, named, true},
+		{"address", HistoryFilter{Address: "70-f9-4a-7a-8b-ca"}, max, true},
+		{"address in device column", HistoryFilter{Address: "70:F9:4A:7A:8B:CA"}, bare, true},
+		{"name or address", HistoryFilter{Name: "AirPods Max", Address: "70:F9:4A:7A:8B:CA"}, named, true},
+		{"other device", HistoryFilter{Name: "Beats Flex", Address: "A8:91:3D:DE:91:C6"}, max, false},
+		{"same name, other address", HistoryFilter{Name: "AirPods Max", Address: "A8:91:3D:DE:91:C6"}, max, false},
+		{"name looking like a partial address", HistoryFilter{Name: "Beef", Address: "Beef"}, HistoryEvent{Device: "Beef", Address: "A8:91:3D:DE:91:C6"}, true},
+		{"link loss", HistoryFilter{EventType: "link-loss"}, HistoryEvent{EventType: "disconnected", Reason: ReasonConnectionTimeout}, true},
+		{"user disconnect is not link loss", HistoryFilter{EventType: "link-loss"}, HistoryEvent{EventType: "disconnected", Reason: ReasonRemoteUserTerminated}, false},
+		{"since", HistoryFilter{Since: time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)}, HistoryEvent{Timestamp: time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)}, true},
+		{"before since", HistoryFilter{Since: time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)}, HistoryEvent{Timestamp: time.Date(2024, 7, 15, 9, 59, 59, 0, time.UTC)}, false},
+		{"at until", HistoryFilter{Until: time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)}, HistoryEvent{Timestamp: time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.ev); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogQuery_Validate(t *testing.T) {
+	since := time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		q       LogQuery
+		wantErr bool
+	}{
+		{"minutes", LogQuery{Last: "30m"}, false},
+		{"days", LogQuery{Last: "7d"}, false},
+		{"empty", LogQuery{}, true},
+		{"compound duration", LogQuery{Last: "1h30m"}, true},
+		{"seconds", LogQuery{Last: "90s"}, true},
+		{"negative", LogQuery{Last: "-1h"}, true},
+		{"since", LogQuery{Since: since}, false},
+		{"since ignores last", LogQuery{Last: "bogus", Since: since}, false},
+		{"range", LogQuery{Since: since, Until: since.Add(time.Hour)}, false},
+		{"empty range", LogQuery{Since: since, Until: since}, true},
+		{"until without since", LogQuery{Last: "24h", Until: since}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.q.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchHistory_Range(t *testing.T) {
+	var gotArgs []string
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte(ndjsonLine("2024-07-15 09:59:59.500000+0000", "Default", `Connected to "AirPods Max"`) + "\n" +
+			ndjsonLine("2024-07-15 10:30:00.000000+0000", "Default", `Disconnected from "AirPods Max"`) + "\n" +
+			ndjsonLine("2024-07-15 11:00:00.000000+0000", "Default", `Connected to "AirPods Max"`)), nil
+	}
+
+	since := time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)
+	q := LogQuery{Since: since, Until: since.Add(time.Hour)}
+	events, err := NewClient(newMacOSBackend(run, nil)).FetchHistory(t.Context(), q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := strings.Join(gotArgs, " ")
+	wantStart := "--start " + since.Local().Format(time.DateTime)
+	wantEnd := "--end " + since.Add(time.Hour).Local().Format(time.DateTime)
+	if !strings.Contains(args, wantStart) || !strings.Contains(args, wantEnd) || strings.Contains(args, "--last") {
+		t.Errorf("expected %q and %q without --last, got %q", wantStart, wantEnd, args)
+	}
+	if len(events) != 1 || events[0].EventType != "disconnected" {
+		t.Errorf("expected events trimmed to the range, got %+v", events)
+	}
+
+	if _, err := NewClient(newMacOSBackend(run, nil)).FetchHistory(t.Context(), LogQuery{Last: "1h30m"}); err == nil {
+		t.Error("expected an invalid window to be rejected")
+	}
+}
+
 func TestParseTimestamp(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -174,10 +386,7 @@ func TestParseDeviceName(t *testing.T) {
 }
 
 func TestFetchHistory_WithMock(t *testing.T) {
-	orig := logRunner
-	defer func() { logRunner = orig }()
-
-	logRunner = func(args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		// Verify correct arguments
 		if len(args) < 6 {
 			t.Errorf("expected at least 6 args, got %d: %v", len(args), args)
@@ -189,7 +398,7 @@ func TestFetchHistory_WithMock(t *testing.T) {
 2024-07-15 10:31:00.654321 0x1234 Default com.apple.bluetooth: Disconnected from "AirPods Max"`), nil
 	}
 
-	events, err := FetchHistory("24h")
+	events, err := NewClient(newMacOSBackend(run, nil)).FetchHistory(t.Context(), LogQuery{Last: "24h"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -205,15 +414,111 @@ func TestFetchHistory_WithMock(t *testing.T) {
 }
 
 func TestFetchHistory_Error(t *testing.T) {
-	orig := logRunner
-	defer func() { logRunner = orig }()
-
-	logRunner = func(args ...string) ([]byte, error) {
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return nil, fmt.Errorf("permission denied")
 	}
 
-	_, err := FetchHistory("24h")
+	_, err := NewClient(newMacOSBackend(run, nil)).FetchHistory(t.Context(), LogQuery{Last: "24h"})
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
+
+func TestFetchHistory_Archive(t *testing.T) {
+	var gotArgs []string
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "log" {
+			gotArgs = args
+		}
+		return nil, nil
+	}
+
+	c := NewClient(newMacOSBackend(run, nil))
+	c.SetLogSource(LogSource{Archive: "/tmp/user.logarchive"})
+	if _, err := c.FetchHistory(t.Context(), LogQuery{Last: "7d"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gotArgs) == 0 || gotArgs[len(gotArgs)-1] != "/tmp/user.logarchive" || !strings.Contains(strings.Join(gotArgs, " "), "--last 7d") {
+		t.Errorf("expected the archive passed to log show, got %v", gotArgs)
+	}
+	if err := c.FollowHistory(t.Context(), func(HistoryEvent) {}); err == nil {
+		t.Error("expected following an archive to be rejected")
+	}
+}
+
+func TestFetchHistory_Input(t *testing.T) {
+	input := strings.Join([]string{
+		"Timestamp                       Ty Process[PID:TID]",
+		`2024-07-15 08:00:00.000000 0x1234 Default com.apple.bluetooth: Connected to "AirPods Max"`,
+		`2024-07-15 09:30:00.000000 0x1234 Default com.apple.bluetooth: Disconnected from "AirPods Max"`,
+		`2024-07-15 10:00:00.000000 0x1234 Default com.apple.bluetooth: Connected to "Magic Keyboard"`,
+	}, "\n")
+
+	fake := newFakeBackend()
+	fake.logErr = fmt.Errorf("no live log here")
+	fake.snapErr = fmt.Errorf("no live devices here")
+	c := NewClient(fake)
+	c.SetLogSource(LogSource{Input: []byte(input)})
+
+	// A relative window ends at the last line of the input, not now.
+	events, err := c.FetchHistory(t.Context(), LogQuery{Last: "1h"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[0].EventType != "disconnected" || events[1].Device != "Magic Keyboard" {
+		t.Errorf("expected the last hour of the input, got %+v", events)
+	}
+
+	since := time.Date(2024, 7, 15, 7, 0, 0, 0, time.Local)
+	events, err = c.FetchHistory(t.Context(), LogQuery{Since: since, Until: since.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].Device != "AirPods Max" || events[0].EventType != "connected" {
+		t.Errorf("expected the range of the input, got %+v", events)
+	}
+
+	// Another machine's addresses are not named from this machine's devices.
+	c.SetLogSource(LogSource{Input: []byte(ndjsonLine("2024-07-15 10:30:45.123456-0700", "Default", "Connection complete for 70:F9:4A:7A:8B:CA"))})
+	fake.snapErr = nil
+	events, err = c.FetchHistory(t.Context(), LogQuery{Last: "1h"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].Device != "70:F9:4A:7A:8B:CA" {
+		t.Errorf("expected the event left unnamed, got %+v", events)
+	}
+}
+
+func TestFetchHistory_InputOpenSession(t *testing.T) {
+	input, err := os.ReadFile("testdata/log/unterminated.ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(newFakeBackend())
+	c.SetLogSource(LogSource{Input: input})
+
+	events, err := c.FetchHistory(t.Context(), LogQuery{Last: "1d"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	end := LastEventTime(events)
+	if want := time.Date(2024, 7, 15, 17, 45, 0, 0, time.UTC); !end.Equal(want) {
+		t.Fatalf("expected the log to end at %v, got %v", want, end)
+	}
+
+	// Sessions still open when the log was captured end with the log.
+	want := map[string]time.Duration{"AirPods Max": 0, "Magic Keyboard": 45 * time.Minute}
+	for _, s := range BuildSessions(events, end) {
+		if s.EndedBy != SessionConnected {
+			continue
+		}
+		if d, ok := want[s.Device]; !ok || s.Duration != d {
+			t.Errorf("open session of %s: expected %v, got %v", s.Device, d, s.Duration)
+		}
+		delete(want, s.Device)
+	}
+	if len(want) != 0 {
+		t.Errorf("expected open sessions for %v", want)
+	}
+}
@@ -0,0 +1,70 @@
+package bluetooth
+
+import (
+	"strings"
+	"time"
+)
+
+// LogSource is where history and diagnostics read the Bluetooth log from
+// when not from the live system log, e.g. another machine's sysdiagnose.
+type LogSource struct {
+	Archive string // a .logarchive for `log show` to read
+	Input   []byte // captured `log show` output, compact or ndjson
+}
+
+// IsLive reports whether the source is the live system log.
+func (s LogSource) IsLive() bool {
+	return s.Archive == "" && s.Input == nil
+}
+
+// SetLogSource makes history and diagnostics read src instead of the live
+// system log. The zero LogSource restores the live log.
+func (c *Client) SetLogSource(src LogSource) {
+	c.logSource = src
+}
+
+// SetLogSource sets the log source used by the package-level functions.
+func SetLogSource(src LogSource) {
+	defaultClient.SetLogSource(src)
+}
+
+// windowLog returns the lines of captured log output within q. A relative
+// window ends at the last timestamp in the output, as `log show --last`
+// does for an archive. Lines without a timestamp, such as headers, are kept.
+func windowLog(data []byte, q LogQuery) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+	since, until := q.Since, q.Until
+	if since.IsZero() {
+		window, err := parseLogDuration(q.Last)
+		if err != nil {
+			return nil, err
+		}
+		var end time.Time
+		for _, line := range lines {
+			if t := logLineTime(line); t.After(end) {
+				end = t
+			}
+		}
+		since = end.Add(-window)
+	}
+
+	var out strings.Builder
+	for _, line := range lines {
+		t := logLineTime(line)
+		if t.IsZero() || (!t.Before(since) && (until.IsZero() || !t.After(until))) {
+			out.WriteString(line)
+			out.WriteString("\n")
+		}
+	}
+	return []byte(out.String()), nil
+}
+
+// logLineTime returns the timestamp of an ndjson record or compact line,
+// or the zero time if it has none.
+func logLineTime(line string) time.Time {
+	line = strings.TrimSpace(line)
+	if entry, ok := parseLogEntry(line); ok {
+		return entry.Time()
+	}
+	return parseTimestamp(line)
+}
@@ -0,0 +1,164 @@
+package bluetooth
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// macOSBackend drives Bluetooth through system_profiler, blueutil, and log.
+type macOSBackend struct {
+	run      CommandRunner
+	stream   LineStreamer
+	lookPath func(file string) (string, error)
+}
+
+// NewMacOSBackend returns the macOS backend. A nil runner uses ExecRunner.
+func NewMacOSBackend(run CommandRunner) Backend {
+	return newMacOSBackend(run, nil)
+}
+
+// newMacOSBackend returns the macOS backend with an injectable lookPath.
+// Log streams go through run when it is set, so replays serve them; a
+// recording backend sets stream to a recording LineStreamer instead.
+func newMacOSBackend(run CommandRunner, lookPath func(string) (string, error)) *macOSBackend {
+	stream := ExecStreamer
+	if run == nil {
+		run = ExecRunner
+	} else {
+		stream = runnerStreamer(run)
+	}
+	if lookPath == nil {
+		lookPath = exec.LookPath
+	}
+	return &macOSBackend{run: run, stream: stream, lookPath: lookPath}
+}
+
+// Name returns "macos".
+func (b *macOSBackend) Name() string {
+	return "macos"
+}
+
+// Snapshot runs system_profiler and parses its JSON output.
+func (b *macOSBackend) Snapshot(ctx context.Context) (*Snapshot, error) {
+	out, err := b.run(ctx, "system_profiler", "SPBluetoothDataType", "-json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run system_profiler: %w", err)
+	}
+	return ParseSnapshot(out)
+}
+
+// blueUtilInstalled reports whether blueutil is on the PATH.
+func (b *macOSBackend) blueUtilInstalled() bool {
+	_, err := b.lookPath("blueutil")
+	return err == nil
+}
+
+// CanControl returns ErrBlueUtilNotInstalled if blueutil is missing.
+func (b *macOSBackend) CanControl(ctx context.Context) error {
+	if !b.blueUtilInstalled() {
+		return ErrBlueUtilNotInstalled
+	}
+	return nil
+}
+
+// Tools reports whether blueutil is installed.
+func (b *macOSBackend) Tools(ctx context.Context) map[string]bool {
+	return map[string]bool{"blueutil": b.blueUtilInstalled()}
+}
+
+// Connect connects to a device by address using blueutil.
+func (b *macOSBackend) Connect(ctx context.Context, address string) error {
+	if err := b.CanControl(ctx); err != nil {
+		return err
+	}
+	if _, err := b.run(ctx, "blueutil", "--connect", address); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	return nil
+}
+
+// Disconnect disconnects a device by address using blueutil.
+func (b *macOSBackend) Disconnect(ctx context.Context, address string) error {
+	if err := b.CanControl(ctx); err != nil {
+		return err
+	}
+	if _, err := b.run(ctx, "blueutil", "--disconnect", address); err != nil {
+		return fmt.Errorf("failed to disconnect %s: %w", address, err)
+	}
+	return nil
+}
+
+// Remove unpairs a device by address using blueutil.
+func (b *macOSBackend) Remove(ctx context.Context, address string) error {
+	if err := b.CanControl(ctx); err != nil {
+		return err
+	}
+	if _, err := b.run(ctx, "blueutil", "--unpair", address); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", address, err)
+	}
+	return nil
+}
+
+// SetPower writes the controller power preference and restarts bluetoothd.
+// Requires sudo.
+func (b *macOSBackend) SetPower(ctx context.Context, on bool) error {
+	stateStr := "0"
+	if on {
+		stateStr = "1"
+	}
+	_, err := b.run(ctx, "defaults", "write",
+		"/Library/Preferences/com.apple.Bluetooth",
+		"ControllerPowerState", "-int", stateStr)
+	if err != nil {
+		return fmt.Errorf("failed to set power state (sudo required): %w", err)
+	}
+
+	_, err = b.run(ctx, "killall", "-HUP", "bluetoothd")
+	if err != nil {
+		return fmt.Errorf("failed to restart bluetoothd: %w", err)
+	}
+
+	return nil
+}
+
+// Reset kills the Bluetooth daemon, which macOS auto-restarts.
+func (b *macOSBackend) Reset(ctx context.Context) error {
+	_, err := b.run(ctx, "sudo", "pkill", "bluetoothd")
+	if err != nil {
+		return fmt.Errorf("failed to reset bluetooth (sudo required): %w", err)
+	}
+	return nil
+}
+
+// ReadLog runs log show for the Bluetooth subsystem, on q.Archive if set.
+func (b *macOSBackend) ReadLog(ctx context.Context, q LogQuery) ([]byte, error) {
+	args := []string{"show",
+		"--predicate", `subsystem == "com.apple.bluetooth"`,
+		"--style", "ndjson"}
+	if q.Since.IsZero() {
+		args = append(args, "--last", q.Last)
+	} else {
+		// log show reads --start and --end in local time.
+		args = append(args, "--start", q.Since.Local().Format(time.DateTime))
+		if !q.Until.IsZero() {
+			args = append(args, "--end", q.Until.Local().Format(time.DateTime))
+		}
+	}
+	if q.Archive != "" {
+		args = append(args, q.Archive)
+	}
+	return b.run(ctx, "log", args...)
+}
+
+// StreamLog follows log stream for the Bluetooth subsystem.
+func (b *macOSBackend) StreamLog(ctx context.Context, fn func(line string)) error {
+	err := b.stream(ctx, fn, "log", "stream",
+		"--predicate", `subsystem == "com.apple.bluetooth"`,
+		"--style", "ndjson")
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to run log stream: %w", err)
+	}
+	return err
+}
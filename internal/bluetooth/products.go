@@ -0,0 +1,223 @@
+package bluetooth
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed products.yaml
+var defaultProductData []byte
+
+// Vendor ID sources. Bluetooth SIG company IDs and USB-IF vendor IDs reuse
+// the same numbers, so an ID is only meaningful with its source, named as
+// in a modalias prefix.
+const (
+	VendorSourceBluetooth = "bluetooth" // Bluetooth SIG company ID
+	VendorSourceUSB       = "usb"       // USB-IF vendor ID
+)
+
+// ProductInfo is what the product database knows about a device model.
+type ProductInfo struct {
+	Vendor      string `json:"vendor,omitempty"`
+	Model       string `json:"model,omitempty"`
+	CaseBattery bool   `json:"case_battery,omitempty"` // reports a charging case battery
+	BudBattery  bool   `json:"bud_battery,omitempty"`  // reports left and right bud batteries
+	ANC         bool   `json:"anc,omitempty"`          // supports active noise cancellation
+}
+
+// Capabilities returns the capability hints as short labels.
+func (p ProductInfo) Capabilities() []string {
+	var caps []string
+	if p.BudBattery {
+		caps = append(caps, "per-bud battery")
+	}
+	if p.CaseBattery {
+		caps = append(caps, "case battery")
+	}
+	if p.ANC {
+		caps = append(caps, "ANC")
+	}
+	return caps
+}
+
+// productFile is the YAML layout of products.yaml.
+type productFile struct {
+	Vendors    map[string]string `yaml:"vendors"`     // Bluetooth SIG company IDs
+	USBVendors map[string]string `yaml:"usb_vendors"` // USB-IF vendor IDs
+	Products   []struct {
+		Source      string `yaml:"source"` // "bluetooth" (default) or "usb"
+		Vendor      string `yaml:"vendor"`
+		Product     string `yaml:"product"`
+		Model       string `yaml:"model"`
+		CaseBattery bool   `yaml:"case_battery"`
+		BudBattery  bool   `yaml:"bud_battery"`
+		ANC         bool   `yaml:"anc"`
+	} `yaml:"products"`
+}
+
+// vendorKey identifies a vendor by ID source and vendor ID.
+type vendorKey struct {
+	source string
+	vendor uint16
+}
+
+// productKey identifies a model by ID source, vendor, and product ID.
+type productKey struct {
+	source          string
+	vendor, product uint16
+}
+
+// ProductDB maps vendor and product IDs to model names and capabilities.
+type ProductDB struct {
+	mu       sync.RWMutex
+	vendors  map[vendorKey]string
+	products map[productKey]ProductInfo
+}
+
+// ParseProductDB parses a products.yaml table.
+func ParseProductDB(data []byte) (*ProductDB, error) {
+	var f productFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse product database: %w", err)
+	}
+	db := &ProductDB{
+		vendors:  make(map[vendorKey]string),
+		products: make(map[productKey]ProductInfo),
+	}
+	for source, vendors := range map[string]map[string]string{
+		VendorSourceBluetooth: f.Vendors,
+		VendorSourceUSB:       f.USBVendors,
+	} {
+		for id, name := range vendors {
+			vendor, err := parseHexID(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse product database: vendor %q: %w", id, err)
+			}
+			db.vendors[vendorKey{source, vendor}] = name
+		}
+	}
+	for i, p := range f.Products {
+		source, err := parseVendorSource(p.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse product database: product %d: %w", i, err)
+		}
+		vendor, err := parseHexID(p.Vendor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse product database: product %d vendor: %w", i, err)
+		}
+		product, err := parseHexID(p.Product)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse product database: product %d: %w", i, err)
+		}
+		db.products[productKey{source, vendor, product}] = ProductInfo{
+			Model:       p.Model,
+			CaseBattery: p.CaseBattery,
+			BudBattery:  p.BudBattery,
+			ANC:         p.ANC,
+		}
+	}
+	return db, nil
+}
+
+// Merge adds other's entries, replacing any with the same IDs.
+func (db *ProductDB) Merge(other *ProductDB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	for k, v := range other.vendors {
+		db.vendors[k] = v
+	}
+	for k, v := range other.products {
+		db.products[k] = v
+	}
+}
+
+// Lookup returns what is known about the device with the given IDs, as
+// reported by system_profiler (e.g. "0x004C", "0x2014"). source is
+// VendorSourceBluetooth or VendorSourceUSB; empty means Bluetooth. A known
+// vendor with an unknown product returns only the vendor name.
+func (db *ProductDB) Lookup(source, vendorID, productID string) (ProductInfo, bool) {
+	source, err := parseVendorSource(source)
+	if err != nil {
+		return ProductInfo{}, false
+	}
+	vendor, err := parseHexID(vendorID)
+	if err != nil {
+		return ProductInfo{}, false
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var info ProductInfo
+	if product, err := parseHexID(productID); err == nil {
+		info = db.products[productKey{source, vendor, product}]
+	}
+	info.Vendor = db.vendors[vendorKey{source, vendor}]
+	return info, info.Vendor != "" || info.Model != ""
+}
+
+// parseVendorSource checks a vendor ID source. Empty means Bluetooth.
+func parseVendorSource(s string) (string, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "", VendorSourceBluetooth:
+		return VendorSourceBluetooth, nil
+	case VendorSourceUSB:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown vendor ID source %q", s)
+	}
+}
+
+// parseHexID parses a 16-bit ID such as "0x004C" or "004c".
+func parseHexID(s string) (uint16, error) {
+	s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "0x")
+	id, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID %q", s)
+	}
+	return uint16(id), nil
+}
+
+// Products is the built-in product database, plus any loaded overrides.
+var Products = mustParseProductDB(defaultProductData)
+
+func mustParseProductDB(data []byte) *ProductDB {
+	db, err := ParseProductDB(data)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// LoadProductOverrides merges a products.yaml file over the built-in database.
+func LoadProductOverrides(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read product database: %w", err)
+	}
+	db, err := ParseProductDB(data)
+	if err != nil {
+		return err
+	}
+	Products.Merge(db)
+	return nil
+}
+
+// annotateProducts sets Device.Product from each device's vendor and product IDs.
+func annotateProducts(db *ProductDB, devices []Device) {
+	for i, d := range devices {
+		if d.Details == nil || d.Details.VendorID == "" {
+			continue
+		}
+		if info, ok := db.Lookup(d.Details.VendorSource, d.Details.VendorID, d.Details.ProductID); ok {
+			devices[i].Product = &info
+		}
+	}
+}
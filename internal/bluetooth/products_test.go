@@ -0,0 +1,136 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultProductDB(t *testing.T) {
+	tests := []struct {
+		vendor, product string
+		want            ProductInfo
+	}{
+		{"0x004C", "0x2014", ProductInfo{Vendor: "Apple", Model: "AirPods Pro (2nd gen)", CaseBattery: true, BudBattery: true, ANC: true}},
+		{"0x004c", "0x201f", ProductInfo{Vendor: "Apple", Model: "AirPods Max (USB-C)", ANC: true}},
+		{"0x004C", "0x029A", ProductInfo{Vendor: "Apple", Model: "Magic Keyboard with Touch ID"}},
+		{"0x004C", "0xFFFF", ProductInfo{Vendor: "Apple"}},
+		{"004C", "2024", ProductInfo{Vendor: "Apple", Model: "AirPods Pro (2nd gen, USB-C)", CaseBattery: true, BudBattery: true, ANC: true}},
+	}
+	for _, tt := range tests {
+		got, ok := Products.Lookup("", tt.vendor, tt.product)
+		if !ok || got != tt.want {
+			t.Errorf("Lookup(%s, %s) = %+v, %v; want %+v", tt.vendor, tt.product, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := Products.Lookup("", "0xBEEF", "0x0001"); ok {
+		t.Error("expected unknown vendor to miss")
+	}
+	if _, ok := Products.Lookup("", "", ""); ok {
+		t.Error("expected empty IDs to miss")
+	}
+}
+
+func TestDefaultProductDB_VendorSources(t *testing.T) {
+	tests := []struct {
+		source, vendor string
+		want           string
+	}{
+		{VendorSourceBluetooth, "0x0006", "Microsoft"},
+		{VendorSourceBluetooth, "0x01DA", "Logitech"},
+		{VendorSourceUSB, "0x046D", "Logitech"},
+		{VendorSourceUSB, "0x05AC", "Apple"},
+		// The same number names different vendors in each registry.
+		{VendorSourceBluetooth, "0x046D", ""},
+		{VendorSourceUSB, "0x004C", ""},
+	}
+	for _, tt := range tests {
+		got, _ := Products.Lookup(tt.source, tt.vendor, "")
+		if got.Vendor != tt.want {
+			t.Errorf("Lookup(%s, %s) vendor = %q, want %q", tt.source, tt.vendor, got.Vendor, tt.want)
+		}
+	}
+	if _, ok := Products.Lookup("pci", "0x004C", ""); ok {
+		t.Error("expected unknown source to miss")
+	}
+}
+
+func TestProductDB_Merge(t *testing.T) {
+	t.Parallel()
+	db, err := ParseProductDB([]byte(`
+vendors:
+  "0x004C": Apple
+products:
+  - {vendor: "0x004C", product: "0x2010", model: Beats Flex}
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	override, err := ParseProductDB([]byte(`
+products:
+  - {vendor: "0x004C", product: "0x2010", model: Beats Flex (2020)}
+  - {vendor: "0x004C", product: "0x9999", model: Prototype, anc: true}
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db.Merge(override)
+
+	if got, _ := db.Lookup("", "0x004C", "0x2010"); got.Model != "Beats Flex (2020)" {
+		t.Errorf("expected override to replace entry, got %+v", got)
+	}
+	if got, _ := db.Lookup("", "0x004C", "0x9999"); got.Model != "Prototype" || !got.ANC || got.Vendor != "Apple" {
+		t.Errorf("expected merged entry, got %+v", got)
+	}
+}
+
+func TestParseProductDB_Errors(t *testing.T) {
+	t.Parallel()
+	for _, data := range []string{
+		"vendors: [",
+		`vendors: {"apple": Apple}`,
+		`products: [{vendor: "0x004C", product: "0x12345", model: X}]`,
+		`products: [{source: pci, vendor: "0x004C", product: "0x2010", model: X}]`,
+	} {
+		if _, err := ParseProductDB([]byte(data)); err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
+
+func TestLoadProductOverrides_Missing(t *testing.T) {
+	t.Parallel()
+	if err := LoadProductOverrides(filepath.Join(t.TempDir(), "products.yaml")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestClient_AnnotatesProducts(t *testing.T) {
+	t.Parallel()
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(sampleJSON), nil
+	}
+	devices, err := NewClient(newMacOSBackend(run, nil)).ListDevices(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, d := range devices {
+		switch d.Name {
+		case "AirPods Max":
+			if d.Product == nil || d.Product.Model != "AirPods Max (USB-C)" {
+				t.Errorf("expected AirPods Max model, got %+v", d.Product)
+			}
+		case "Beats Flex":
+			if d.Product == nil || d.Product.Model != "Beats Flex" {
+				t.Errorf("expected Beats Flex model, got %+v", d.Product)
+			}
+		case "iPhone":
+			if d.Product != nil {
+				t.Errorf("expected no product without IDs, got %+v", d.Product)
+			}
+		}
+	}
+}
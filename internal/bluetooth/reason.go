@@ -0,0 +1,115 @@
+package bluetooth
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DisconnectReason is why a connection ended, taken from the HCI reason
+// code or phrase in the log message.
+type DisconnectReason string
+
+// Disconnect reasons, with the HCI reason codes they cover.
+const (
+	ReasonAuthenticationFailure DisconnectReason = "authentication-failure" // 0x05, 0x06
+	ReasonConnectionTimeout     DisconnectReason = "connection-timeout"     // 0x08, link supervision timeout
+	ReasonRemoteUserTerminated  DisconnectReason = "remote-user-terminated" // 0x13
+	ReasonRemoteLowResources    DisconnectReason = "remote-low-resources"   // 0x14
+	ReasonRemotePowerOff        DisconnectReason = "remote-power-off"       // 0x15
+	ReasonLocalHostTerminated   DisconnectReason = "local-host-terminated"  // 0x16
+	ReasonResponseTimeout       DisconnectReason = "response-timeout"       // 0x22, LMP/LL response timeout
+	ReasonPowerOff              DisconnectReason = "power-off"              // the local controller powered off
+	ReasonOther                 DisconnectReason = "other"                  // any other HCI code
+)
+
+// LinkLoss reports whether the radio link was lost rather than closed.
+func (r DisconnectReason) LinkLoss() bool {
+	return r == ReasonConnectionTimeout || r == ReasonResponseTimeout
+}
+
+// Intentional reports whether one side chose to end the connection, e.g.
+// the user turned their headphones off.
+func (r DisconnectReason) Intentional() bool {
+	switch r {
+	case ReasonRemoteUserTerminated, ReasonRemotePowerOff, ReasonLocalHostTerminated, ReasonPowerOff:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsError reports whether the reason points at a fault worth diagnosing.
+func (r DisconnectReason) IsError() bool {
+	return r != "" && !r.Intentional()
+}
+
+// disconnectReasons maps HCI codes and log phrases to reasons. Phrases are
+// tried in order, so the more specific ones come first.
+var disconnectReasons = []struct {
+	reason DisconnectReason
+	codes  []int
+	phrase *regexp.Regexp
+}{
+	{ReasonRemotePowerOff, []int{0x15}, regexp.MustCompile(`(?i)\bremote (?:device )?(?:terminated connection due to )?power(?:ed)? off\b`)},
+	{ReasonRemoteUserTerminated, []int{0x13}, regexp.MustCompile(`(?i)\bremote user terminated|\bterminated by (?:the )?remote\b`)},
+	{ReasonLocalHostTerminated, []int{0x16}, regexp.MustCompile(`(?i)\blocal host terminated|\bterminated by (?:the )?local host\b`)},
+	{ReasonRemoteLowResources, []int{0x14}, regexp.MustCompile(`(?i)\blow resources\b`)},
+	{ReasonAuthenticationFailure, []int{0x05, 0x06}, regexp.MustCompile(`(?i)\bauthentication fail|\bpin or key missing\b`)},
+	{ReasonResponseTimeout, []int{0x22}, regexp.MustCompile(`(?i)\b(?:lmp|ll) response timeout\b`)},
+	{ReasonConnectionTimeout, []int{0x08}, regexp.MustCompile(`(?i)\b(?:connection timeout|supervision timeout|link loss|connection lost)\b`)},
+	{ReasonPowerOff, nil, regexp.MustCompile(`(?i)\bpower(?:ed|ing)?\s*off\b`)},
+}
+
+// reasonCodePattern matches an HCI code such as "reason 0x13",
+// "reason: 19", or "status = 0x08".
+var reasonCodePattern = regexp.MustCompile(`(?i)\b(reason|status|error)(?:\s+code)?\s*[:=]?\s*\(?(0x[0-9a-f]{1,2}|[0-9]{1,3})\b`)
+
+// parseDisconnectReason returns the reason a disconnect message gives and
+// its HCI code, or 0 if the message names none. An unknown code is
+// ReasonOther unless the message also has a known phrase.
+func parseDisconnectReason(msg string) (DisconnectReason, int) {
+	code := reasonCode(msg)
+	if code > 0 {
+		for _, r := range disconnectReasons {
+			if slices.Contains(r.codes, code) {
+				return r.reason, code
+			}
+		}
+	}
+	for _, r := range disconnectReasons {
+		if r.phrase.MatchString(msg) {
+			return r.reason, code
+		}
+	}
+	if code > 0 {
+		return ReasonOther, code
+	}
+	return "", 0
+}
+
+// reasonCode returns the HCI code in msg, preferring one labelled "reason"
+// over a status or error code, or 0 if there is none. A zero status means
+// success, so it is skipped.
+func reasonCode(msg string) int {
+	code := 0
+	for _, m := range reasonCodePattern.FindAllStringSubmatch(msg, -1) {
+		// Decimal codes may be zero-padded, so they are not read as octal.
+		digits, base := m[2], 10
+		if len(digits) > 2 && strings.EqualFold(digits[:2], "0x") {
+			digits, base = digits[2:], 16
+		}
+		n, err := strconv.ParseInt(digits, base, 16)
+		if err != nil || n <= 0 || n > 0xFF {
+			continue
+		}
+		if strings.EqualFold(m[1], "reason") {
+			return int(n)
+		}
+		if code == 0 {
+			code = int(n)
+		}
+	}
+	return code
+}
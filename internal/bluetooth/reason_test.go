@@ -0,0 +1,75 @@
+package bluetooth
+
+import "testing"
+
+func TestParseDisconnectReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    string
+		reason DisconnectReason
+		code   int
+	}{
+		{"remote user terminated", "Disconnection complete, status 0x00, reason 0x13 (Remote User Terminated Connection)", ReasonRemoteUserTerminated, 0x13},
+		{"connection timeout", "Disconnected from 70:F9:4A:7A:8B:CA, reason 0x08", ReasonConnectionTimeout, 0x08},
+		{"local host terminated", "Disconnected from \"AirPods Max\" reason: 22", ReasonLocalHostTerminated, 0x16},
+		{"zero-padded decimal", "Disconnected from \"Magic Mouse\", reason 08", ReasonConnectionTimeout, 8},
+		{"zero-padded three-digit decimal", "Disconnected, reason 019", ReasonRemoteUserTerminated, 19},
+		{"zero-padded decimal not octal", "Disconnected, reason 010", ReasonOther, 10},
+		{"upper-case hex prefix", "Disconnected, reason 0X13", ReasonRemoteUserTerminated, 0x13},
+		{"remote power off", "Disconnected from \"AirPods Max\" with error 0x15", ReasonRemotePowerOff, 0x15},
+		{"authentication failure code", "Disconnected, status = 0x05", ReasonAuthenticationFailure, 0x05},
+		{"reason preferred over status", "Disconnected, status 0x0c, reason 0x13", ReasonRemoteUserTerminated, 0x13},
+		{"unknown code", "Disconnected, reason 0x3e", ReasonOther, 0x3e},
+		{"unknown code with phrase", "Disconnected, reason 0x3e, supervision timeout", ReasonConnectionTimeout, 0x3e},
+		{"supervision timeout phrase", "Supervision timeout for 70:F9:4A:7A:8B:CA", ReasonConnectionTimeout, 0},
+		{"link loss phrase", "link loss detected for \"Magic Mouse\"", ReasonConnectionTimeout, 0},
+		{"lmp response timeout", "Disconnected: LMP response timeout", ReasonResponseTimeout, 0},
+		{"remote user phrase", "Disconnected from \"AirPods Max\" (remote user terminated connection)", ReasonRemoteUserTerminated, 0},
+		{"remote powered off phrase", "Disconnected from \"AirPods Max\": remote device powered off", ReasonRemotePowerOff, 0},
+		{"local power off phrase", "Powered off, disconnected from \"Magic Keyboard\"", ReasonPowerOff, 0},
+		{"authentication phrase", "Disconnected from \"Magic Keyboard\": authentication failure", ReasonAuthenticationFailure, 0},
+		{"no reason", "Disconnected from \"AirPods Max\"", "", 0},
+		{"success status only", "Disconnection complete, status 0x00", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, code := parseDisconnectReason(tt.msg)
+			if reason != tt.reason || code != tt.code {
+				t.Errorf("parseDisconnectReason(%q) = %q, %#x; want %q, %#x", tt.msg, reason, code, tt.reason, tt.code)
+			}
+		})
+	}
+}
+
+func TestDisconnectReason_Classes(t *testing.T) {
+	tests := []struct {
+		reason      DisconnectReason
+		linkLoss    bool
+		intentional bool
+		isError     bool
+	}{
+		{ReasonConnectionTimeout, true, false, true},
+		{ReasonResponseTimeout, true, false, true},
+		{ReasonAuthenticationFailure, false, false, true},
+		{ReasonRemoteLowResources, false, false, true},
+		{ReasonOther, false, false, true},
+		{ReasonRemoteUserTerminated, false, true, false},
+		{ReasonRemotePowerOff, false, true, false},
+		{ReasonLocalHostTerminated, false, true, false},
+		{ReasonPowerOff, false, true, false},
+		{"", false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.reason), func(t *testing.T) {
+			if got := tt.reason.LinkLoss(); got != tt.linkLoss {
+				t.Errorf("LinkLoss() = %v, want %v", got, tt.linkLoss)
+			}
+			if got := tt.reason.Intentional(); got != tt.intentional {
+				t.Errorf("Intentional() = %v, want %v", got, tt.intentional)
+			}
+			if got := tt.reason.IsError(); got != tt.isError {
+				t.Errorf("IsError() = %v, want %v", got, tt.isError)
+			}
+		})
+	}
+}
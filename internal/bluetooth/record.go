@@ -0,0 +1,343 @@
+package bluetooth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Interaction is one recorded external command invocation, or one lookup
+// of whether a tool is installed.
+type Interaction struct {
+	Seq       int      `json:"seq"`
+	Argv      []string `json:"argv"`
+	LookPath  bool     `json:"look_path,omitempty"` // a PATH lookup of Argv[0]; Stdout holds the path found
+	Stdout    string   `json:"stdout"`
+	Stderr    string   `json:"stderr,omitempty"`
+	ExitCode  int      `json:"exit_code"`       // -1 if the command could not be started
+	Error     string   `json:"error,omitempty"` // start failure, e.g. executable not found
+	LatencyMS int64    `json:"latency_ms"`
+	TimeoutMS int64    `json:"timeout_ms,omitempty"` // set if the command was killed at its deadline
+}
+
+// CommandError is a replayed command failure. Its message matches exec.ExitError
+// so errors read the same during replay as they did live.
+type CommandError struct {
+	Argv     []string
+	ExitCode int
+	Stderr   string
+	Message  string
+}
+
+// Error returns "exit status N", or the start failure message.
+func (e *CommandError) Error() string {
+	if e.ExitCode < 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("exit status %d", e.ExitCode)
+}
+
+// Recorder writes the external command interactions of one session to a
+// directory as NNNN-<command>.json, numbered in the order they start.
+type Recorder struct {
+	dir string
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecorder returns a recorder that writes to dir. dir must be new or
+// empty, as numbering restarts with each recording and would mix sessions.
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create record directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record directory: %w", err)
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("failed to create record directory: %s is not empty; record into a new directory", dir)
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// NewRecordingRunner returns a runner that calls next and records every
+// invocation to dir. A nil next uses ExecRunner.
+func NewRecordingRunner(dir string, next CommandRunner) (CommandRunner, error) {
+	r, err := NewRecorder(dir)
+	if err != nil {
+		return nil, err
+	}
+	return r.Runner(next), nil
+}
+
+// next reserves the sequence number of an interaction.
+func (r *Recorder) next() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	return r.seq
+}
+
+// Runner returns a runner that calls next and records every invocation.
+// A nil next uses ExecRunner.
+func (r *Recorder) Runner(next CommandRunner) CommandRunner {
+	if next == nil {
+		next = ExecRunner
+	}
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		start := time.Now()
+		out, runErr := next(ctx, name, args...)
+
+		ia := Interaction{
+			Argv:      append([]string{name}, args...),
+			Stdout:    string(out),
+			LatencyMS: time.Since(start).Milliseconds(),
+		}
+		ia.setResult(runErr)
+		ia.Seq = r.next()
+
+		if err := writeInteraction(r.dir, ia); err != nil {
+			return out, err
+		}
+		return out, runErr
+	}
+}
+
+// Streamer returns a streamer that passes each line from next to fn as it
+// arrives and records the whole stream once it ends. A stream stopped by
+// ctx is recorded as ending normally, so its replay ends the same way.
+// A nil next uses ExecStreamer.
+func (r *Recorder) Streamer(next LineStreamer) LineStreamer {
+	if next == nil {
+		next = ExecStreamer
+	}
+	return func(ctx context.Context, fn func(line string), name string, args ...string) error {
+		seq := r.next()
+		start := time.Now()
+		var out strings.Builder
+		streamErr := next(ctx, func(line string) {
+			out.WriteString(line)
+			out.WriteByte('\n')
+			fn(line)
+		}, name, args...)
+
+		ia := Interaction{
+			Seq:       seq,
+			Argv:      append([]string{name}, args...),
+			Stdout:    out.String(),
+			LatencyMS: time.Since(start).Milliseconds(),
+		}
+		if ctx.Err() == nil {
+			ia.setResult(streamErr)
+		}
+		if err := writeInteraction(r.dir, ia); err != nil && streamErr == nil {
+			return err
+		}
+		return streamErr
+	}
+}
+
+// LookPath returns a PATH lookup that calls next and records the result,
+// so a replay sees the tools the recording host had. A nil next uses
+// exec.LookPath.
+func (r *Recorder) LookPath(next func(file string) (string, error)) func(file string) (string, error) {
+	if next == nil {
+		next = exec.LookPath
+	}
+	return func(file string) (string, error) {
+		path, lookErr := next(file)
+		ia := Interaction{
+			Argv:     []string{file},
+			LookPath: true,
+			Stdout:   path,
+		}
+		ia.setResult(lookErr)
+		ia.Seq = r.next()
+
+		if err := writeInteraction(r.dir, ia); err != nil {
+			return path, err
+		}
+		return path, lookErr
+	}
+}
+
+// setResult records how a command ended.
+func (ia *Interaction) setResult(runErr error) {
+	var exitErr *exec.ExitError
+	var cmdErr *CommandError
+	var timeoutErr *TimeoutError
+	switch {
+	case runErr == nil:
+	case errors.As(runErr, &timeoutErr):
+		ia.ExitCode = -1
+		ia.Error = timeoutErr.Error()
+		ia.TimeoutMS = timeoutErr.Timeout.Milliseconds()
+	case errors.As(runErr, &exitErr):
+		ia.ExitCode = exitErr.ExitCode()
+		ia.Stderr = string(exitErr.Stderr)
+	case errors.As(runErr, &cmdErr):
+		ia.ExitCode = cmdErr.ExitCode
+		ia.Stderr = cmdErr.Stderr
+		ia.Error = cmdErr.Message
+	default:
+		ia.ExitCode = -1
+		ia.Error = runErr.Error()
+	}
+}
+
+// writeInteraction stores an interaction as indented JSON.
+func writeInteraction(dir string, ia Interaction) error {
+	data, err := json.MarshalIndent(ia, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode interaction: %w", err)
+	}
+	command := filepath.Base(ia.Argv[0])
+	if ia.LookPath {
+		command = "lookpath-" + command
+	}
+	name := fmt.Sprintf("%04d-%s.json", ia.Seq, command)
+	if err := os.WriteFile(filepath.Join(dir, name), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to record interaction: %w", err)
+	}
+	return nil
+}
+
+// LoadInteractions reads all interactions recorded in dir, ordered by sequence.
+func LoadInteractions(dir string) ([]Interaction, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	var interactions []Interaction
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recording: %w", err)
+		}
+		var ia Interaction
+		if err := json.Unmarshal(data, &ia); err != nil {
+			return nil, fmt.Errorf("failed to parse recording %s: %w", filepath.Base(path), err)
+		}
+		if len(ia.Argv) == 0 {
+			return nil, fmt.Errorf("failed to parse recording %s: empty argv", filepath.Base(path))
+		}
+		interactions = append(interactions, ia)
+	}
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("no recordings found in %s", dir)
+	}
+	sort.SliceStable(interactions, func(i, j int) bool { return interactions[i].Seq < interactions[j].Seq })
+	return interactions, nil
+}
+
+// NewReplayRunner returns a runner that serves the interactions recorded in dir.
+func NewReplayRunner(dir string) (CommandRunner, error) {
+	interactions, err := LoadInteractions(dir)
+	if err != nil {
+		return nil, err
+	}
+	return ReplayInteractions(interactions), nil
+}
+
+// ReplayInteractions returns a runner that answers each call with the next
+// unused interaction whose argv matches, in recorded order. Once a command's
+// recordings are used up, its last recording is repeated so polling loops
+// such as the TUI and battery --watch keep working.
+func ReplayInteractions(interactions []Interaction) CommandRunner {
+	m := newInteractionMatcher(interactions, false)
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		argv := append([]string{name}, args...)
+		ia, ok := m.next(argv)
+		if !ok {
+			return nil, fmt.Errorf("no recorded interaction for: %s", strings.Join(argv, " "))
+		}
+		return replay(ia)
+	}
+}
+
+// ReplayLookPath returns a PATH lookup that answers from the lookups in
+// interactions, matched like ReplayInteractions, so tool presence follows
+// the recording host rather than the replaying one. A tool that was never
+// looked up is reported as not found.
+func ReplayLookPath(interactions []Interaction) func(file string) (string, error) {
+	m := newInteractionMatcher(interactions, true)
+	return func(file string) (string, error) {
+		ia, ok := m.next([]string{file})
+		if !ok {
+			return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+		}
+		out, err := replay(ia)
+		return string(out), err
+	}
+}
+
+// interactionMatcher hands out recorded interactions of one kind, each
+// once in recorded order and then the last one repeatedly.
+type interactionMatcher struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// newInteractionMatcher matches the lookups in interactions if lookPath
+// is set, and the commands otherwise.
+func newInteractionMatcher(interactions []Interaction, lookPath bool) *interactionMatcher {
+	var kept []Interaction
+	for _, ia := range interactions {
+		if ia.LookPath == lookPath {
+			kept = append(kept, ia)
+		}
+	}
+	return &interactionMatcher{interactions: kept, used: make([]bool, len(kept))}
+}
+
+// next returns the interaction that answers argv.
+func (m *interactionMatcher) next(argv []string) (Interaction, bool) {
+	key := strings.Join(argv, "\x00")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last := -1
+	for i, ia := range m.interactions {
+		if strings.Join(ia.Argv, "\x00") != key {
+			continue
+		}
+		last = i
+		if !m.used[i] {
+			m.used[i] = true
+			return ia, true
+		}
+	}
+	if last >= 0 {
+		return m.interactions[last], true
+	}
+	return Interaction{}, false
+}
+
+// replay converts an interaction back into runner results. A recorded
+// timeout is returned as a TimeoutError without waiting.
+func replay(ia Interaction) ([]byte, error) {
+	out := []byte(ia.Stdout)
+	if ia.ExitCode == 0 && ia.Error == "" {
+		return out, nil
+	}
+	if ia.TimeoutMS > 0 {
+		return out, &TimeoutError{Tool: ia.Argv[0], Timeout: time.Duration(ia.TimeoutMS) * time.Millisecond}
+	}
+	return out, &CommandError{
+		Argv:     ia.Argv,
+		ExitCode: ia.ExitCode,
+		Stderr:   ia.Stderr,
+		Message:  ia.Error,
+	}
+}
@@ -0,0 +1,264 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordingRunner_RoundTrip(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	live := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "system_profiler" {
+			return []byte(sampleJSON), nil
+		}
+		return []byte("partial"), &CommandError{ExitCode: 1, Stderr: "log: permission denied"}
+	}
+	rec, err := NewRecordingRunner(dir, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want, err := NewClient(newMacOSBackend(rec, nil)).ListDevices(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := rec(t.Context(), "log", "show"); err == nil {
+		t.Fatal("expected recorded failure to be returned")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 || filepath.Base(files[0]) != "0001-system_profiler.json" {
+		t.Fatalf("unexpected recordings: %v", files)
+	}
+
+	interactions, err := LoadInteractions(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if interactions[1].ExitCode != 1 || interactions[1].Stderr != "log: permission denied" {
+		t.Errorf("unexpected failure recording: %+v", interactions[1])
+	}
+
+	replayRun, err := NewReplayRunner(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := NewClient(newMacOSBackend(replayRun, nil)).ListDevices(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d devices on replay, got %d", len(want), len(got))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected replayed devices %+v, got %+v", want, got)
+	}
+
+	out, err := replayRun(t.Context(), "log", "show")
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != 1 || string(out) != "partial" {
+		t.Errorf("expected replayed exit status 1, got %v %q", err, out)
+	}
+	if err.Error() != "exit status 1" {
+		t.Errorf("expected exec-style message, got %q", err.Error())
+	}
+}
+
+func TestReplayInteractions_OrderAndRepeat(t *testing.T) {
+	t.Parallel()
+	run := ReplayInteractions([]Interaction{
+		{Seq: 1, Argv: []string{"blueutil", "--connect", "A"}, ExitCode: 1},
+		{Seq: 2, Argv: []string{"blueutil", "--connect", "A"}},
+	})
+
+	if _, err := run(t.Context(), "blueutil", "--connect", "A"); err == nil {
+		t.Error("expected first attempt to fail as recorded")
+	}
+	if _, err := run(t.Context(), "blueutil", "--connect", "A"); err != nil {
+		t.Errorf("expected second attempt to succeed, got %v", err)
+	}
+	if _, err := run(t.Context(), "blueutil", "--connect", "A"); err != nil {
+		t.Errorf("expected last recording to repeat, got %v", err)
+	}
+	if _, err := run(t.Context(), "blueutil", "--connect", "B"); err == nil {
+		t.Error("expected error for unrecorded argv")
+	}
+}
+
+func TestReplayInteractions_StartFailure(t *testing.T) {
+	t.Parallel()
+	run := ReplayInteractions([]Interaction{
+		{Seq: 1, Argv: []string{"blueutil"}, ExitCode: -1, Error: `exec: "blueutil": executable file not found in $PATH`},
+	})
+
+	_, err := run(t.Context(), "blueutil")
+	if err == nil || err.Error() != `exec: "blueutil": executable file not found in $PATH` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadInteractions_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := LoadInteractions(t.TempDir()); err == nil {
+		t.Error("expected error for empty directory")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0001-x.json"), []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadInteractions(dir); err == nil {
+		t.Error("expected error for invalid recording")
+	}
+}
+
+func TestRecordingRunner_Timeout(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	stalled := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, &TimeoutError{Tool: name, Timeout: 15 * time.Second}
+	}
+	rec, err := NewRecordingRunner(dir, stalled)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := rec(t.Context(), "system_profiler", "SPBluetoothDataType", "-json"); err == nil {
+		t.Fatal("expected recorded timeout to be returned")
+	}
+
+	replayRun, err := NewReplayRunner(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = replayRun(t.Context(), "system_profiler", "SPBluetoothDataType", "-json")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Tool != "system_profiler" || timeoutErr.Timeout != 15*time.Second {
+		t.Errorf("expected replayed system_profiler timeout, got %v", err)
+	}
+}
+
+func TestRecordingRunner_NonEmptyDir(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	live := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("ok"), nil
+	}
+
+	rec, err := NewRecordingRunner(dir, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := rec(t.Context(), "bluetoothctl", "show"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A second recording would restart at 0001 and interleave with the first.
+	if _, err := NewRecordingRunner(dir, live); err == nil {
+		t.Fatal("expected recording into a non-empty directory to be refused")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected the first recording left intact, got %d files, %v", len(entries), err)
+	}
+}
+
+func TestRecorder_Streamer(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var lines []string
+	live := func(ctx context.Context, fn func(line string), name string, args ...string) error {
+		fn("first")
+		// The line must reach the caller before the stream ends.
+		if len(lines) != 1 {
+			t.Errorf("expected the first line delivered live, got %q", lines)
+		}
+		fn("second")
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	err = rec.Streamer(live)(ctx, func(line string) { lines = append(lines, line) }, "log", "stream")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the stream's own error, got %v", err)
+	}
+	if len(lines) != 2 {
+		t.Errorf("expected both lines, got %q", lines)
+	}
+
+	replayRun, err := NewReplayRunner(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var replayed []string
+	err = runnerStreamer(replayRun)(t.Context(), func(line string) { replayed = append(replayed, line) }, "log", "stream")
+	if err != nil || !reflect.DeepEqual(replayed, []string{"first", "second"}) {
+		t.Errorf("expected the stream replayed as a clean end, got %q, %v", replayed, err)
+	}
+}
+
+func TestReplay_ConnectUsesRecordedLookPath(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	live := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, nil
+	}
+	found := func(file string) (string, error) {
+		return "/opt/homebrew/bin/" + file, nil
+	}
+	recording, err := NewBackend("macos", BackendOptions{Runner: rec.Runner(live), LookPath: rec.LookPath(found)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := NewClient(recording).Connect(t.Context(), "AA:BB:CC:DD:EE:FF"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The replaying host has no blueutil; the recording's lookup is used.
+	interactions, err := LoadInteractions(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replaying, err := NewBackend("macos", BackendOptions{
+		Runner:   ReplayInteractions(interactions),
+		LookPath: ReplayLookPath(interactions),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := NewClient(replaying).Connect(t.Context(), "AA:BB:CC:DD:EE:FF"); err != nil {
+		t.Errorf("expected replayed connect to succeed, got %v", err)
+	}
+	if got := replaying.Tools(t.Context()); !got["blueutil"] {
+		t.Errorf("expected recorded blueutil presence, got %v", got)
+	}
+}
+
+func TestReplayLookPath_Unrecorded(t *testing.T) {
+	t.Parallel()
+	lookPath := ReplayLookPath([]Interaction{
+		{Seq: 1, Argv: []string{"blueutil"}, Stdout: "/opt/homebrew/bin/blueutil"},
+	})
+	if _, err := lookPath("blueutil"); err == nil {
+		t.Error("expected a command recording not to answer a lookup")
+	}
+}
@@ -0,0 +1,174 @@
+package bluetooth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDeviceNotFound is returned when no device matches a query.
+var ErrDeviceNotFound = errors.New("device not found")
+
+// AmbiguousDeviceError is returned when a query matches more than one
+// device equally well, e.g. two devices both named "AirPods".
+type AmbiguousDeviceError struct {
+	Query      string
+	Candidates []Device
+}
+
+func (e *AmbiguousDeviceError) Error() string {
+	names := make([]string, 0, len(e.Candidates))
+	for _, d := range e.Candidates {
+		names = append(names, fmt.Sprintf("%s (%s)", d.Name, d.Address))
+	}
+	return fmt.Sprintf("%q matches %d devices: %s", e.Query, len(e.Candidates), strings.Join(names, ", "))
+}
+
+// deviceMatchers are tried in order; the first that matches any device
+// decides the result. Earlier matchers are stricter.
+var deviceMatchers = []func(d Device, name, addr string) bool{
+	// Full address, in any format.
+	func(d Device, name, addr string) bool {
+		return len(addr) == 12 && normalizeAddress(d.Address) == addr
+	},
+	// Exact name.
+	func(d Device, name, addr string) bool {
+		return strings.ToLower(d.Name) == name
+	},
+	// Address prefix, e.g. "70:F9" or "70f94a".
+	func(d Device, name, addr string) bool {
+		return len(addr) >= 4 && strings.HasPrefix(normalizeAddress(d.Address), addr)
+	},
+	// Name substring.
+	func(d Device, name, addr string) bool {
+		return strings.Contains(strings.ToLower(d.Name), name)
+	},
+	// Fuzzy name: a typo or two, or the query's letters in order ("apmax").
+	func(d Device, name, addr string) bool {
+		candidate := strings.ToLower(d.Name)
+		return isSubsequence(name, candidate) || editDistance(name, candidate) <= max(1, len(name)/4)
+	},
+}
+
+// FindDevice resolves a query to a single device. The query may be a name,
+// an address in any format (colons, dashes, or no separators), an address
+// prefix, or a name substring or near-miss. If several devices match at the
+// best level, an *AmbiguousDeviceError lists them.
+func FindDevice(devices []Device, query string) (*Device, error) {
+	name := strings.ToLower(strings.TrimSpace(query))
+	addr := normalizeAddress(query)
+	if name == "" {
+		return nil, fmt.Errorf("%w: %q", ErrDeviceNotFound, query)
+	}
+
+	for _, match := range deviceMatchers {
+		var candidates []Device
+		for _, d := range devices {
+			if match(d, name, addr) {
+				candidates = append(candidates, d)
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return &candidates[0], nil
+		default:
+			return nil, &AmbiguousDeviceError{Query: query, Candidates: candidates}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrDeviceNotFound, query)
+}
+
+// ExactMatch reports whether query names d by its full address, in any
+// format, or its whole name, rather than by one of the looser matches
+// FindDevice also accepts. Destructive commands use it to decide whether
+// to confirm first.
+func ExactMatch(d Device, query string) bool {
+	name := strings.ToLower(strings.TrimSpace(query))
+	addr := normalizeAddress(query)
+	switch {
+	case name == "":
+		return false
+	case len(addr) == 12 && normalizeAddress(d.Address) == addr:
+		return true
+	default:
+		return strings.ToLower(d.Name) == name
+	}
+}
+
+// findExact resolves a query to the one device ExactMatch accepts. A name
+// shared by several devices returns an *AmbiguousDeviceError.
+func findExact(devices []Device, query string) (*Device, error) {
+	var candidates []Device
+	for _, d := range devices {
+		if ExactMatch(d, query) {
+			candidates = append(candidates, d)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrDeviceNotFound, query)
+	case 1:
+		return &candidates[0], nil
+	default:
+		return nil, &AmbiguousDeviceError{Query: query, Candidates: candidates}
+	}
+}
+
+// normalizeAddress strips separators from an address and upper-cases it,
+// so "70:f9:4a:7a:8b:ca", "70-F9-4A-7A-8B-CA", and "70F94A7A8BCA" compare
+// equal. It returns "" if s contains anything other than hex digits and
+// separators.
+func normalizeAddress(s string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r == ':' || r == '-' || r == '.':
+			continue
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'f', r >= 'A' && r <= 'F':
+			b.WriteRune(r)
+		default:
+			return ""
+		}
+	}
+	return strings.ToUpper(b.String())
+}
+
+// isSubsequence reports whether the letters of sub appear in s in order.
+// Queries shorter than three letters never match, as they match too much.
+func isSubsequence(sub, s string) bool {
+	rs := []rune(sub)
+	if len(rs) < 3 {
+		return false
+	}
+	i := 0
+	for _, r := range s {
+		if i < len(rs) && rs[i] == r {
+			i++
+		}
+	}
+	return i == len(rs)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,117 @@
+package bluetooth
+
+import (
+	"errors"
+	"testing"
+)
+
+var resolveDevices = []Device{
+	{Name: "AirPods", Address: "98:DD:60:D2:4C:FF"},
+	{Name: "AirPods", Address: "98:DD:60:11:22:33"},
+	{Name: "AirPods Max", Address: "70:F9:4A:7A:8B:CA"},
+	{Name: "Beats Flex", Address: "A8:91:3D:DE:91:C6"},
+	{Name: "Magic Keyboard", Address: "AA:BB:CC:DD:EE:01"},
+	{Name: "Magic Mouse", Address: "AA:BB:CC:DD:EE:02"},
+}
+
+func TestFindDevice(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string // address
+	}{
+		{"exact name", "airpods max", "70:F9:4A:7A:8B:CA"},
+		{"address with colons", "70:f9:4a:7a:8b:ca", "70:F9:4A:7A:8B:CA"},
+		{"address with dashes", "70-F9-4A-7A-8B-CA", "70:F9:4A:7A:8B:CA"},
+		{"address without separators", "70f94a7a8bca", "70:F9:4A:7A:8B:CA"},
+		{"address prefix", "A8:91", "A8:91:3D:DE:91:C6"},
+		{"full address beats duplicate name", "98DD60D24CFF", "98:DD:60:D2:4C:FF"},
+		{"name substring", "flex", "A8:91:3D:DE:91:C6"},
+		{"typo", "beats flx", "A8:91:3D:DE:91:C6"},
+		{"subsequence", "mgkbd", "AA:BB:CC:DD:EE:01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := FindDevice(resolveDevices, tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d.Address != tt.want {
+				t.Errorf("expected %s, got %s (%s)", tt.want, d.Address, d.Name)
+			}
+		})
+	}
+}
+
+func TestFindDevice_Ambiguous(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"duplicate name", "AirPods", 2},
+		{"shared address prefix", "98:DD:60", 2},
+		{"shared substring", "magic", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FindDevice(resolveDevices, tt.query)
+			var ambiguous *AmbiguousDeviceError
+			if !errors.As(err, &ambiguous) {
+				t.Fatalf("expected AmbiguousDeviceError, got %v", err)
+			}
+			if ambiguous.Query != tt.query || len(ambiguous.Candidates) != tt.want {
+				t.Errorf("expected %d candidates for %q, got %+v", tt.want, tt.query, ambiguous)
+			}
+		})
+	}
+}
+
+func TestFindDevice_NotFound(t *testing.T) {
+	for _, query := range []string{"Speaker", "", "00:11"} {
+		if _, err := FindDevice(resolveDevices, query); !errors.Is(err, ErrDeviceNotFound) {
+			t.Errorf("expected ErrDeviceNotFound for %q, got %v", query, err)
+		}
+	}
+}
+
+func TestExactMatch(t *testing.T) {
+	flex := Device{Name: "Beats Flex", Address: "A8:91:3D:DE:91:C6"}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"Beats Flex", true},
+		{"  beats flex ", true},
+		{"A8:91:3D:DE:91:C6", true},
+		{"a8-91-3d-de-91-c6", true},
+		{"a8913dde91c6", true},
+		{"flex", false},
+		{"beats flx", false},
+		{"bflex", false},
+		{"A8:91", false},
+		{"A8:91:3D:DE:91:C7", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ExactMatch(flex, tt.query); got != tt.want {
+			t.Errorf("ExactMatch(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"70:f9:4a:7a:8b:ca", "70F94A7A8BCA"},
+		{"70-F9-4A-7A-8B-CA", "70F94A7A8BCA"},
+		{" 70f9 ", "70F9"},
+		{"AirPods", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeAddress(tt.in); got != tt.want {
+			t.Errorf("normalizeAddress(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package bluetooth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// profilerSchema identifies a system_profiler SPBluetoothDataType layout.
+type profilerSchema int
+
+const (
+	// schemaModern is the Monterey (12) and later layout: controller_properties
+	// plus device_connected / device_not_connected lists of single-key maps.
+	schemaModern profilerSchema = iota
+	// schemaLegacy is the Big Sur (11) and earlier layout: local_device_title
+	// for the controller and one device_title list with device_isconnected.
+	schemaLegacy
+)
+
+func (s profilerSchema) String() string {
+	switch s {
+	case schemaLegacy:
+		return "legacy"
+	default:
+		return "modern"
+	}
+}
+
+// detectSchema reports the layout of one SPBluetoothDataType entry.
+func detectSchema(entry map[string]json.RawMessage) profilerSchema {
+	if _, ok := entry["device_title"]; ok {
+		return schemaLegacy
+	}
+	if _, ok := entry["local_device_title"]; ok {
+		return schemaLegacy
+	}
+	return schemaModern
+}
+
+// deviceKeyAliases maps device property names used by other macOS releases
+// to the names the parser expects.
+var deviceKeyAliases = map[string]string{
+	// Big Sur and earlier.
+	"device_addr":                 "device_address",
+	"device_RSSI":                 "device_rssi",
+	"device_fwVersion":            "device_firmwareVersion",
+	"device_batteryPercent":       "device_batteryLevel",
+	"device_batteryPercentSingle": "device_batteryLevelMain",
+	"device_batteryPercentLeft":   "device_batteryLevelLeft",
+	"device_batteryPercentRight":  "device_batteryLevelRight",
+	"device_batteryPercentCase":   "device_batteryLevelCase",
+	// Later releases name the bud and case batteries explicitly.
+	"device_batteryLevelBudLeft":      "device_batteryLevelLeft",
+	"device_batteryLevelBudRight":     "device_batteryLevelRight",
+	"device_batteryLevelChargingCase": "device_batteryLevelCase",
+}
+
+// controllerKeyAliases maps Big Sur's local_device_title fields to
+// controller_* names. Unlisted general_* fields keep their suffix.
+var controllerKeyAliases = map[string]string{
+	"general_address":            "controller_address",
+	"general_power":              "controller_state",
+	"general_chipset":            "controller_chipset",
+	"general_fw_version":         "controller_firmwareVersion",
+	"general_vendorID":           "controller_vendorID",
+	"general_productID":          "controller_productID",
+	"general_discoverable":       "controller_discoverable",
+	"general_transport":          "controller_transport",
+	"general_supported_services": "controller_supportedServices",
+}
+
+// parseSystemProfiler decodes SPBluetoothDataType JSON in any supported
+// layout and normalizes every entry to the modern one.
+func parseSystemProfiler(data []byte) (*systemProfilerOutput, error) {
+	var raw struct {
+		SPBluetoothDataType []map[string]json.RawMessage `json:"SPBluetoothDataType"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse bluetooth data: %w", err)
+	}
+
+	sp := &systemProfilerOutput{}
+	for i, entry := range raw.SPBluetoothDataType {
+		var bt bluetoothData
+		var err error
+		switch detectSchema(entry) {
+		case schemaLegacy:
+			bt, err = normalizeLegacy(entry)
+		default:
+			bt, err = normalizeModern(entry)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bluetooth data: controller %d: %w", i, err)
+		}
+		sp.SPBluetoothDataType = append(sp.SPBluetoothDataType, bt)
+	}
+	return sp, nil
+}
+
+// normalizeModern decodes a Monterey-or-later entry, renaming any aliased
+// device keys.
+func normalizeModern(entry map[string]json.RawMessage) (bluetoothData, error) {
+	var bt bluetoothData
+	props, err := decodeProperties(entry["controller_properties"])
+	if err != nil {
+		return bt, err
+	}
+	bt.ControllerProperties = props
+	for _, list := range []struct {
+		key string
+		dst *[]map[string]interface{}
+	}{
+		{"device_connected", &bt.DeviceConnected},
+		{"device_not_connected", &bt.DeviceNotConnected},
+	} {
+		raw, ok := entry[list.key]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, list.dst); err != nil {
+			return bt, fmt.Errorf("%s: %w", list.key, err)
+		}
+		for _, named := range *list.dst {
+			for name, p := range named {
+				if m, ok := p.(map[string]interface{}); ok {
+					named[name] = canonicalDeviceProps(m)
+				}
+			}
+		}
+	}
+	return bt, nil
+}
+
+// normalizeLegacy converts a Big-Sur-or-earlier entry. Devices are split on
+// device_isconnected; entries marked unpaired are dropped.
+func normalizeLegacy(entry map[string]json.RawMessage) (bluetoothData, error) {
+	var bt bluetoothData
+	general, err := decodeProperties(entry["local_device_title"])
+	if err != nil {
+		return bt, err
+	}
+	if len(general) > 0 {
+		bt.ControllerProperties = make(map[string]string, len(general))
+		for k, v := range general {
+			if alias, ok := controllerKeyAliases[k]; ok {
+				k = alias
+			} else {
+				k = "controller_" + strings.TrimPrefix(k, "general_")
+			}
+			if k == "controller_address" {
+				v = canonicalAddress(v)
+			}
+			bt.ControllerProperties[k] = v
+		}
+	}
+
+	var titles []map[string]interface{}
+	if raw, ok := entry["device_title"]; ok {
+		if err := json.Unmarshal(raw, &titles); err != nil {
+			return bt, fmt.Errorf("device_title: %w", err)
+		}
+	}
+	for _, named := range titles {
+		for name, p := range named {
+			m, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			m = canonicalDeviceProps(m)
+			if paired, ok := parseAttribBool(getString(m, "device_ispaired")); ok && !paired {
+				continue
+			}
+			connected, _ := parseAttribBool(getString(m, "device_isconnected"))
+			delete(m, "device_ispaired")
+			delete(m, "device_isconnected")
+			if addr := getString(m, "device_address"); addr != "" {
+				m["device_address"] = canonicalAddress(addr)
+			}
+			device := map[string]interface{}{name: m}
+			if connected {
+				bt.DeviceConnected = append(bt.DeviceConnected, device)
+			} else {
+				bt.DeviceNotConnected = append(bt.DeviceNotConnected, device)
+			}
+		}
+	}
+	return bt, nil
+}
+
+// decodeProperties decodes a flat property object, formatting non-string
+// values with detailString. A missing object yields nil.
+func decodeProperties(raw json.RawMessage) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, nil
+	}
+	props := make(map[string]string, len(m))
+	for k, v := range m {
+		props[k] = detailString(v)
+	}
+	return props, nil
+}
+
+// canonicalDeviceProps renames aliased keys in place. A canonical key
+// already present wins over its alias.
+func canonicalDeviceProps(props map[string]interface{}) map[string]interface{} {
+	for alias, key := range deviceKeyAliases {
+		v, ok := props[alias]
+		if !ok {
+			continue
+		}
+		delete(props, alias)
+		if _, exists := props[key]; !exists {
+			props[key] = v
+		}
+	}
+	return props
+}
+
+// canonicalAddress formats Big Sur's "74-15-f5-4e-d0-50" addresses as
+// "74:15:F5:4E:D0:50".
+func canonicalAddress(addr string) string {
+	return strings.ToUpper(strings.ReplaceAll(addr, "-", ":"))
+}
@@ -0,0 +1,248 @@
+package bluetooth
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// SessionEnd describes how a connection session ended.
+type SessionEnd string
+
+// Ways a session can end.
+const (
+	SessionUserDisconnect SessionEnd = "user-disconnect"
+	SessionLinkLoss       SessionEnd = "link-loss"
+	SessionPowerOff       SessionEnd = "power-off"
+	SessionConnected      SessionEnd = "still-connected"
+)
+
+// Session is one connection of a device, from its connect event to its
+// disconnect event. In JSON, Duration is duration_seconds.
+type Session struct {
+	Device   string        `json:"device"`
+	Address  string        `json:"address,omitempty"`
+	Start    time.Time     `json:"start"` // zero if connected before the log window
+	End      time.Time     `json:"end"`   // zero if still connected
+	Duration time.Duration `json:"-"`     // zero if Start is unknown
+	EndedBy  SessionEnd    `json:"ended_by"`
+}
+
+// MarshalJSON encodes s with its duration in seconds.
+func (s Session) MarshalJSON() ([]byte, error) {
+	type session Session
+	return json.Marshal(struct {
+		session
+		Duration float64 `json:"duration_seconds"`
+	}{session(s), s.Duration.Seconds()})
+}
+
+// DeviceSummary aggregates the sessions of one device. In JSON, Connected
+// and Median are connected_seconds and median_session_seconds.
+type DeviceSummary struct {
+	Device    string        `json:"device"`
+	Address   string        `json:"address,omitempty"`
+	Sessions  int           `json:"sessions"`
+	Connected time.Duration `json:"-"`
+	Median    time.Duration `json:"-"`
+	Drops     int           `json:"drops"` // sessions ended by link loss
+}
+
+// MarshalJSON encodes d with its durations in seconds.
+func (d DeviceSummary) MarshalJSON() ([]byte, error) {
+	type summary DeviceSummary
+	return json.Marshal(struct {
+		summary
+		Connected float64 `json:"connected_seconds"`
+		Median    float64 `json:"median_session_seconds"`
+	}{summary(d), d.Connected.Seconds(), d.Median.Seconds()})
+}
+
+// sessionEnd classifies how a disconnect event ended its session.
+func sessionEnd(ev HistoryEvent) SessionEnd {
+	switch {
+	case ev.Reason.LinkLoss():
+		return SessionLinkLoss
+	case ev.Reason == ReasonPowerOff || ev.Reason == ReasonRemotePowerOff:
+		return SessionPowerOff
+	default:
+		return SessionUserDisconnect
+	}
+}
+
+// BuildSessions groups events into per-device sessions, ordered by start.
+// A repeated connect event extends the open session; a disconnect without
+// one yields a session with an unknown start. Sessions still open at the
+// end run until now.
+func BuildSessions(events []HistoryEvent, now time.Time) []Session {
+	sorted := make([]HistoryEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var sessions []Session
+	open := make(map[string]int) // session key -> index into sessions
+	for _, ev := range sorted {
+		key := sessionKey(ev)
+		switch ev.EventType {
+		case "connected":
+			if _, ok := open[key]; ok {
+				continue
+			}
+			open[key] = len(sessions)
+			sessions = append(sessions, Session{
+				Device:  ev.Device,
+				Address: ev.Address,
+				Start:   ev.Timestamp,
+				EndedBy: SessionConnected,
+			})
+		case "disconnected":
+			i, ok := open[key]
+			if !ok {
+				key, i, ok = openByName(open, sessions, ev.Device)
+			}
+			if !ok {
+				sessions = append(sessions, Session{
+					Device:  ev.Device,
+					Address: ev.Address,
+					End:     ev.Timestamp,
+					EndedBy: sessionEnd(ev),
+				})
+				continue
+			}
+			delete(open, key)
+			s := &sessions[i]
+			s.End = ev.Timestamp
+			s.Duration = s.End.Sub(s.Start)
+			s.EndedBy = sessionEnd(ev)
+		}
+	}
+
+	for _, i := range open {
+		if s := &sessions[i]; now.After(s.Start) {
+			s.Duration = now.Sub(s.Start)
+		}
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessionTime(sessions[i]).Before(sessionTime(sessions[j]))
+	})
+	return sessions
+}
+
+// sessionKey identifies a device across events: by address when the log
+// names one, otherwise by name.
+func sessionKey(ev HistoryEvent) string {
+	if ev.Address != "" {
+		return normalizeAddress(ev.Address)
+	}
+	return ev.Device
+}
+
+// sessionDeviceKeys returns the device each session belongs to: its
+// normalized address, or for a session without one, the address of the
+// only device with its name, or else the name itself.
+func sessionDeviceKeys(sessions []Session) []string {
+	addresses := make(map[string]map[string]bool)
+	for _, s := range sessions {
+		if s.Address == "" {
+			continue
+		}
+		if addresses[s.Device] == nil {
+			addresses[s.Device] = make(map[string]bool)
+		}
+		addresses[s.Device][normalizeAddress(s.Address)] = true
+	}
+	keys := make([]string, len(sessions))
+	for i, s := range sessions {
+		keys[i] = s.Device
+		switch {
+		case s.Address != "":
+			keys[i] = normalizeAddress(s.Address)
+		case len(addresses[s.Device]) == 1:
+			for address := range addresses[s.Device] {
+				keys[i] = address
+			}
+		}
+	}
+	return keys
+}
+
+// openByName finds an open session for a device by name, for disconnect
+// events that lack the address their connect event had.
+func openByName(open map[string]int, sessions []Session, device string) (string, int, bool) {
+	for key, i := range open {
+		if sessions[i].Device == device {
+			return key, i, true
+		}
+	}
+	return "", 0, false
+}
+
+// sessionTime is the time a session is ordered by: its start, or its end
+// if the start is unknown.
+func sessionTime(s Session) time.Time {
+	if s.Start.IsZero() {
+		return s.End
+	}
+	return s.Start
+}
+
+// SummarizeSessions aggregates sessions per device, ordered by device name.
+// Devices are told apart by address, so two with the same name get a
+// summary each; a session without an address counts towards the only
+// device with its name, or is grouped by name if there is none or several.
+// Sessions with an unknown start count towards Sessions and Drops but not
+// towards Connected or Median.
+func SummarizeSessions(sessions []Session) []DeviceSummary {
+	var summaries []DeviceSummary
+	index := make(map[string]int)
+	var durations [][]time.Duration
+	keys := sessionDeviceKeys(sessions)
+	for n, s := range sessions {
+		key := keys[n]
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, DeviceSummary{Device: s.Device})
+			durations = append(durations, nil)
+		}
+		sum := &summaries[i]
+		if sum.Address == "" {
+			sum.Address = s.Address
+		}
+		sum.Sessions++
+		if s.EndedBy == SessionLinkLoss {
+			sum.Drops++
+		}
+		if !s.Start.IsZero() {
+			sum.Connected += s.Duration
+			durations[i] = append(durations[i], s.Duration)
+		}
+	}
+
+	for i := range summaries {
+		summaries[i].Median = median(durations[i])
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Device < summaries[j].Device
+	})
+	return summaries
+}
+
+// median returns the median of durations, or zero if there are none.
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
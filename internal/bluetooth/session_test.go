@@ -0,0 +1,168 @@
+package bluetooth
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+var sessionBase = time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)
+
+func sessionEvent(minute int, eventType, device, address, msg string) HistoryEvent {
+	ev := HistoryEvent{
+		Timestamp: sessionBase.Add(time.Duration(minute) * time.Minute),
+		Device:    device,
+		Address:   address,
+		EventType: eventType,
+		RawLine:   msg,
+	}
+	if eventType == "disconnected" {
+		ev.Reason, ev.ReasonCode = parseDisconnectReason(msg)
+	}
+	return ev
+}
+
+var sessionEvents = []HistoryEvent{
+	// Out of order on purpose: sessions are built in time order.
+	sessionEvent(30, "disconnected", "AirPods Max", "70:F9:4A:7A:8B:CA", "link loss detected for 70:F9:4A:7A:8B:CA"),
+	sessionEvent(0, "connected", "AirPods Max", "70:F9:4A:7A:8B:CA", `Connected to "AirPods Max" (70:F9:4A:7A:8B:CA)`),
+	sessionEvent(5, "connected", "AirPods Max", "70:F9:4A:7A:8B:CA", `Connected to "AirPods Max" (70:F9:4A:7A:8B:CA)`),
+	sessionEvent(40, "connected", "AirPods Max", "70:F9:4A:7A:8B:CA", `Connected to "AirPods Max" (70:F9:4A:7A:8B:CA)`),
+	sessionEvent(50, "disconnected", "AirPods Max", "", `Disconnected from "AirPods Max"`),
+	sessionEvent(2, "disconnected", "Magic Keyboard", "", `Disconnected from "Magic Keyboard"`),
+	sessionEvent(10, "connected", "Magic Keyboard", "", `Connected to "Magic Keyboard"`),
+	sessionEvent(20, "disconnected", "Magic Keyboard", "", `Powered off, disconnected from "Magic Keyboard"`),
+	sessionEvent(55, "connected", "Magic Keyboard", "", `Connected to "Magic Keyboard"`),
+}
+
+func TestBuildSessions(t *testing.T) {
+	now := sessionBase.Add(time.Hour)
+	sessions := BuildSessions(sessionEvents, now)
+
+	want := []struct {
+		device   string
+		start    int // minutes, -1 for unknown
+		duration time.Duration
+		endedBy  SessionEnd
+	}{
+		{"AirPods Max", 0, 30 * time.Minute, SessionLinkLoss},
+		{"Magic Keyboard", -1, 0, SessionUserDisconnect},
+		{"Magic Keyboard", 10, 10 * time.Minute, SessionPowerOff},
+		{"AirPods Max", 40, 10 * time.Minute, SessionUserDisconnect},
+		{"Magic Keyboard", 55, 5 * time.Minute, SessionConnected},
+	}
+	if len(sessions) != len(want) {
+		t.Fatalf("expected %d sessions, got %d: %+v", len(want), len(sessions), sessions)
+	}
+	for i, w := range want {
+		s := sessions[i]
+		if s.Device != w.device || s.Duration != w.duration || s.EndedBy != w.endedBy {
+			t.Errorf("session %d: expected %s %v %s, got %+v", i, w.device, w.duration, w.endedBy, s)
+		}
+		if w.start < 0 {
+			if !s.Start.IsZero() {
+				t.Errorf("session %d: expected unknown start, got %v", i, s.Start)
+			}
+		} else if want := sessionBase.Add(time.Duration(w.start) * time.Minute); !s.Start.Equal(want) {
+			t.Errorf("session %d: expected start %v, got %v", i, want, s.Start)
+		}
+	}
+	if !sessions[4].End.IsZero() {
+		t.Errorf("expected open session to have no end, got %v", sessions[4].End)
+	}
+}
+
+func TestSummarizeSessions(t *testing.T) {
+	summaries := SummarizeSessions(BuildSessions(sessionEvents, sessionBase.Add(time.Hour)))
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 summaries, got %d", len(summaries))
+	}
+
+	max := summaries[0]
+	if max.Device != "AirPods Max" || max.Address != "70:F9:4A:7A:8B:CA" {
+		t.Errorf("unexpected first summary: %+v", max)
+	}
+	if max.Sessions != 2 || max.Connected != 40*time.Minute || max.Median != 20*time.Minute || max.Drops != 1 {
+		t.Errorf("unexpected AirPods Max summary: %+v", max)
+	}
+
+	kb := summaries[1]
+	if kb.Sessions != 3 || kb.Connected != 15*time.Minute || kb.Median != 7*time.Minute+30*time.Second || kb.Drops != 0 {
+		t.Errorf("unexpected Magic Keyboard summary: %+v", kb)
+	}
+}
+
+func TestSummarizeSessions_SameName(t *testing.T) {
+	events := []HistoryEvent{
+		sessionEvent(0, "connected", "AirPods", "74:15:F5:4E:D0:50", `Connected to "AirPods" (74:15:F5:4E:D0:50)`),
+		sessionEvent(5, "connected", "AirPods", "F4:34:F0:96:DD:A0", `Connected to "AirPods" (F4:34:F0:96:DD:A0)`),
+		sessionEvent(10, "disconnected", "AirPods", "74:15:F5:4E:D0:50", "link loss detected for 74:15:F5:4E:D0:50"),
+		sessionEvent(25, "disconnected", "AirPods", "f4:34:f0:96:dd:a0", `Disconnected from "AirPods" (f4:34:f0:96:dd:a0)`),
+		sessionEvent(30, "connected", "Magic Keyboard", "AA:BB:CC:DD:EE:01", `Connected to "Magic Keyboard" (AA:BB:CC:DD:EE:01)`),
+		sessionEvent(40, "disconnected", "Magic Keyboard", "", `Disconnected from "Magic Keyboard"`),
+		sessionEvent(45, "disconnected", "Magic Keyboard", "", `Disconnected from "Magic Keyboard"`),
+	}
+	summaries := SummarizeSessions(BuildSessions(events, sessionBase.Add(time.Hour)))
+	if len(summaries) != 3 {
+		t.Fatalf("expected 3 summaries, got %+v", summaries)
+	}
+	first, second := summaries[0], summaries[1]
+	if first.Address != "74:15:F5:4E:D0:50" || first.Sessions != 1 || first.Connected != 10*time.Minute || first.Drops != 1 {
+		t.Errorf("unexpected first AirPods summary: %+v", first)
+	}
+	if second.Address != "F4:34:F0:96:DD:A0" || second.Sessions != 1 || second.Connected != 20*time.Minute || second.Drops != 0 {
+		t.Errorf("unexpected second AirPods summary: %+v", second)
+	}
+	// The disconnect without an address or a connect joins the only
+	// Magic Keyboard.
+	if kb := summaries[2]; kb.Address != "AA:BB:CC:DD:EE:01" || kb.Sessions != 2 || kb.Connected != 10*time.Minute {
+		t.Errorf("unexpected Magic Keyboard summary: %+v", kb)
+	}
+}
+
+func TestSessionsJSON(t *testing.T) {
+	sessions := BuildSessions(sessionEvents, sessionBase.Add(time.Hour))
+	data, err := json.Marshal(sessions[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["duration_seconds"] != 1800.0 || got["device"] != "AirPods Max" || got["ended_by"] != "link-loss" {
+		t.Errorf("unexpected session JSON: %s", data)
+	}
+	if _, ok := got["Duration"]; ok {
+		t.Errorf("expected only duration_seconds, got %s", data)
+	}
+
+	data, err = json.Marshal(SummarizeSessions(sessions)[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got = nil
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["connected_seconds"] != 900.0 || got["median_session_seconds"] != 450.0 || got["sessions"] != 3.0 {
+		t.Errorf("unexpected summary JSON: %s", data)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		in   []time.Duration
+		want time.Duration
+	}{
+		{nil, 0},
+		{[]time.Duration{3}, 3},
+		{[]time.Duration{5, 1, 3}, 3},
+		{[]time.Duration{4, 1, 3, 2}, 2},
+	}
+	for _, tt := range tests {
+		if got := median(tt.in); got != tt.want {
+			t.Errorf("median(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+package bluetooth
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrSimConnectFailed is returned when a scripted connection attempt fails.
+var ErrSimConnectFailed = errors.New("simulated connection failure")
+
+//go:embed sim_default.yaml
+var defaultSimScenario []byte
+
+func init() {
+	RegisterBackend("sim", func(opts BackendOptions) (Backend, error) {
+		if opts.Runner != nil {
+			return nil, fmt.Errorf("%w: command recording (sim)", ErrUnsupported)
+		}
+		path := opts.Scenario
+		if path == "" {
+			path = os.Getenv("BLTCTL_SIM_SCENARIO")
+		}
+		if path == "" {
+			scenario, err := ParseSimScenario(defaultSimScenario)
+			if err != nil {
+				return nil, err
+			}
+			return NewSimBackend(scenario), nil
+		}
+		scenario, err := LoadSimScenario(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewSimBackend(scenario), nil
+	})
+}
+
+// SimScenario describes a simulated fleet of adapters and devices.
+// Rates are per simulated minute.
+type SimScenario struct {
+	Seed     int64        `yaml:"seed" json:"seed"`
+	Speed    float64      `yaml:"speed" json:"speed"` // simulated seconds per real second; default 1
+	Adapters []SimAdapter `yaml:"adapters" json:"adapters"`
+	Devices  []SimDevice  `yaml:"devices" json:"devices"`
+}
+
+// SimAdapter is a simulated Bluetooth controller.
+type SimAdapter struct {
+	Address  string `yaml:"address" json:"address"`
+	Chipset  string `yaml:"chipset" json:"chipset"`
+	Firmware string `yaml:"firmware" json:"firmware"`
+	Powered  *bool  `yaml:"powered" json:"powered"` // default true
+}
+
+// SimDevice is a simulated paired device and its scripted behavior.
+type SimDevice struct {
+	Name           string        `yaml:"name" json:"name"`
+	Address        string        `yaml:"address" json:"address"`
+	Type           string        `yaml:"type" json:"type"`
+	VendorID       string        `yaml:"vendor_id" json:"vendor_id"`   // e.g. "0x004C", for the product database
+	ProductID      string        `yaml:"product_id" json:"product_id"` // e.g. "0x2014"
+	Adapter        string        `yaml:"adapter" json:"adapter"`       // adapter address; default the first adapter
+	Connected      bool          `yaml:"connected" json:"connected"`
+	Battery        *int          `yaml:"battery" json:"battery"` // nil if the device reports no battery
+	RSSI           int           `yaml:"rssi" json:"rssi"`
+	DrainPerMin    float64       `yaml:"drain_per_min" json:"drain_per_min"`
+	ChargePerMin   float64       `yaml:"charge_per_min" json:"charge_per_min"`
+	LinkLossPerMin float64       `yaml:"link_loss_per_min" json:"link_loss_per_min"` // probability per minute
+	ConnectFailure float64       `yaml:"connect_failure" json:"connect_failure"`     // probability per attempt
+	AppearAfter    time.Duration `yaml:"appear_after" json:"appear_after"`
+	DisappearAfter time.Duration `yaml:"disappear_after" json:"disappear_after"`
+}
+
+// LoadSimScenario reads a YAML or JSON scenario file.
+func LoadSimScenario(path string) (*SimScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	return ParseSimScenario(data)
+}
+
+// ParseSimScenario parses a YAML or JSON scenario.
+func ParseSimScenario(data []byte) (*SimScenario, error) {
+	var s SimScenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario: %w", err)
+	}
+	if len(s.Adapters) == 0 {
+		s.Adapters = []SimAdapter{{Address: "00:00:00:00:00:00", Chipset: "SIM"}}
+	}
+	for i := range s.Devices {
+		d := &s.Devices[i]
+		if d.Name == "" || d.Address == "" {
+			return nil, fmt.Errorf("failed to parse scenario: device %d needs a name and address", i)
+		}
+		if d.Adapter != "" {
+			address, ok := s.adapterAddress(d.Adapter)
+			if !ok {
+				return nil, fmt.Errorf("failed to parse scenario: device %q: unknown adapter %s", d.Name, d.Adapter)
+			}
+			d.Adapter = address
+		}
+	}
+	if s.Speed <= 0 {
+		s.Speed = 1
+	}
+	return &s, nil
+}
+
+// adapterAddress returns the address of the adapter matching address
+// (case-insensitive) as the scenario spells it.
+func (s *SimScenario) adapterAddress(address string) (string, bool) {
+	for _, a := range s.Adapters {
+		if strings.EqualFold(a.Address, address) {
+			return a.Address, true
+		}
+	}
+	return "", false
+}
+
+// simDeviceState is the live state of one simulated device.
+type simDeviceState struct {
+	spec      SimDevice
+	present   bool
+	removed   bool
+	connected bool
+	battery   float64 // -1 if unknown
+}
+
+// simLogEntry is one line of the simulated system log.
+type simLogEntry struct {
+	at   time.Time
+	line string
+}
+
+// simBackend is an in-memory Backend driven by a SimScenario.
+// Every call except StreamLog completes immediately, so contexts are
+// accepted but not otherwise consulted.
+type simBackend struct {
+	mu       sync.Mutex
+	scenario *SimScenario
+	rng      *rand.Rand
+	now      func() time.Time
+	start    time.Time // real time the simulation started
+	simStart time.Time // simulated time at start
+	last     time.Duration
+	powered  bool
+	devices  []*simDeviceState
+	log      []simLogEntry
+}
+
+// NewSimBackend returns a simulated backend that starts now. Its state is
+// held in memory only, so each one starts from the scenario.
+func NewSimBackend(scenario *SimScenario) Backend {
+	return newSimBackend(scenario, time.Now)
+}
+
+// newSimBackend returns a simulated backend with an injectable clock.
+func newSimBackend(scenario *SimScenario, now func() time.Time) *simBackend {
+	seed := scenario.Seed
+	if seed == 0 {
+		seed = now().UnixNano()
+	}
+	start := now()
+	b := &simBackend{
+		scenario: scenario,
+		rng:      rand.New(rand.NewSource(seed)),
+		now:      now,
+		start:    start,
+		simStart: start,
+		powered:  scenario.Adapters[0].Powered == nil || *scenario.Adapters[0].Powered,
+	}
+	for _, spec := range scenario.Devices {
+		st := &simDeviceState{spec: spec, battery: -1}
+		if spec.Battery != nil {
+			st.battery = float64(*spec.Battery)
+		}
+		b.devices = append(b.devices, st)
+	}
+	b.advance()
+	return b
+}
+
+// Name returns "sim".
+func (b *simBackend) Name() string {
+	return "sim"
+}
+
+// elapsed returns simulated time since the start.
+func (b *simBackend) elapsed() time.Duration {
+	return time.Duration(float64(b.now().Sub(b.start)) * b.scenario.Speed)
+}
+
+// simNow returns the current simulated wall-clock time.
+func (b *simBackend) simNow() time.Time {
+	return b.simStart.Add(b.elapsed())
+}
+
+// Now returns the current simulated time, which the simulated log's
+// timestamps are taken from.
+func (b *simBackend) Now() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.simNow()
+}
+
+// advance applies scripted behavior up to the current simulated time.
+// Callers must hold b.mu, except during construction.
+func (b *simBackend) advance() {
+	offset := b.elapsed()
+	minutes := (offset - b.last).Minutes()
+	b.last = offset
+
+	for _, d := range b.devices {
+		if d.removed {
+			continue
+		}
+		visible := offset >= d.spec.AppearAfter &&
+			(d.spec.DisappearAfter == 0 || offset < d.spec.DisappearAfter)
+
+		switch {
+		case visible && !d.present:
+			d.present = true
+			if d.spec.Connected && b.powered {
+				b.setConnected(d, true, "Connected to")
+			}
+			continue
+		case !visible && d.present:
+			d.present = false
+			if d.connected {
+				b.setConnected(d, false, "link loss detected for")
+			}
+			continue
+		case !visible:
+			continue
+		}
+
+		if d.battery >= 0 {
+			if d.connected {
+				d.battery -= d.spec.DrainPerMin * minutes
+			} else {
+				d.battery += d.spec.ChargePerMin * minutes
+			}
+			d.battery = math.Max(0, math.Min(100, d.battery))
+		}
+
+		if d.connected && d.spec.LinkLossPerMin > 0 && minutes > 0 {
+			p := 1 - math.Pow(1-math.Min(d.spec.LinkLossPerMin, 1), minutes)
+			if b.rng.Float64() < p {
+				b.setConnected(d, false, "link loss detected for")
+			}
+		}
+	}
+}
+
+// setConnected changes a device's connection state and logs the transition.
+func (b *simBackend) setConnected(d *simDeviceState, connected bool, verb string) {
+	d.connected = connected
+	b.logf("%s %q (%s)", verb, d.spec.Name, d.spec.Address)
+}
+
+// logf appends an ndjson log record, as `log show --style ndjson` prints
+// it, at the current simulated time.
+func (b *simBackend) logf(format string, args ...any) {
+	at := b.simNow()
+	line, _ := json.Marshal(logEntry{
+		Timestamp:        at.Format("2006-01-02 15:04:05.000000-0700"),
+		MessageType:      "Default",
+		Subsystem:        "com.apple.bluetooth",
+		Category:         "Server",
+		ProcessImagePath: "/usr/sbin/bluetoothd",
+		EventMessage:     fmt.Sprintf(format, args...),
+	})
+	b.log = append(b.log, simLogEntry{at: at, line: string(line)})
+}
+
+// Snapshot advances the simulation and reports its state.
+func (b *simBackend) Snapshot(ctx context.Context) (*Snapshot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	// Power commands drive the first adapter; the others keep their
+	// scripted state.
+	snap := &Snapshot{}
+	for i, adapter := range b.scenario.Adapters {
+		powered := adapter.Powered == nil || *adapter.Powered
+		if i == 0 {
+			powered = b.powered
+		}
+		info := map[string]string{
+			"controller_address": adapter.Address,
+			"controller_chipset": adapter.Chipset,
+			"controller_state":   "attrib_off",
+		}
+		if adapter.Firmware != "" {
+			info["controller_firmwareVersion"] = adapter.Firmware
+		}
+		if powered {
+			info["controller_state"] = "attrib_on"
+		}
+		snap.Controllers = append(snap.Controllers, controllerFromInfo(info))
+	}
+	snap.ControllerInfo = snap.Controllers[0].Info
+	snap.PowerState = snap.Controllers[0].State
+
+	for _, d := range b.devices {
+		if !d.present || d.removed {
+			continue
+		}
+		dev := Device{
+			Name:         d.spec.Name,
+			Address:      d.spec.Address,
+			MinorType:    d.spec.Type,
+			Connected:    d.connected,
+			BatteryLevel: -1,
+			Controller:   d.spec.Adapter,
+		}
+		if d.battery >= 0 {
+			dev.BatteryLevel = int(math.Round(d.battery))
+		}
+		if d.connected {
+			dev.RSSI = d.spec.RSSI
+		}
+		if d.spec.VendorID != "" {
+			dev.Details = &DeviceDetails{VendorID: d.spec.VendorID, ProductID: d.spec.ProductID}
+		}
+		snap.Devices = append(snap.Devices, dev)
+	}
+	return snap, nil
+}
+
+// CanControl always succeeds.
+func (b *simBackend) CanControl(ctx context.Context) error {
+	return nil
+}
+
+// Tools returns nil; the simulation runs nothing.
+func (b *simBackend) Tools(ctx context.Context) map[string]bool {
+	return nil
+}
+
+// find returns the present device with address, or an error.
+func (b *simBackend) find(address string) (*simDeviceState, error) {
+	for _, d := range b.devices {
+		if d.present && !d.removed && strings.EqualFold(d.spec.Address, address) {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("device not found: %s", address)
+}
+
+// Connect connects a device, failing with the scripted probability.
+func (b *simBackend) Connect(ctx context.Context, address string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	d, err := b.find(address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	if !b.powered {
+		return fmt.Errorf("failed to connect to %s: bluetooth is off", address)
+	}
+	if d.connected {
+		return nil
+	}
+	if b.rng.Float64() < d.spec.ConnectFailure {
+		b.logf("connection failed for %q (%s): page timeout", d.spec.Name, d.spec.Address)
+		return fmt.Errorf("failed to connect to %s: %w", address, ErrSimConnectFailed)
+	}
+	b.setConnected(d, true, "Connected to")
+	return nil
+}
+
+// Disconnect disconnects a device.
+func (b *simBackend) Disconnect(ctx context.Context, address string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	d, err := b.find(address)
+	if err != nil {
+		return fmt.Errorf("failed to disconnect %s: %w", address, err)
+	}
+	if d.connected {
+		b.setConnected(d, false, "Disconnected from")
+	}
+	return nil
+}
+
+// Remove unpairs a device for the rest of the simulation.
+func (b *simBackend) Remove(ctx context.Context, address string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	d, err := b.find(address)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %w", address, err)
+	}
+	if d.connected {
+		b.setConnected(d, false, "Disconnected from")
+	}
+	d.removed = true
+	return nil
+}
+
+// SetPower turns the simulated controller on or off.
+// Powering off disconnects every device.
+func (b *simBackend) SetPower(ctx context.Context, on bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	b.powered = on
+	if !on {
+		for _, d := range b.devices {
+			if d.connected {
+				b.setConnected(d, false, "Powered off, disconnected from")
+			}
+		}
+	}
+	return nil
+}
+
+// Reset disconnects every device and reconnects those scripted as connected.
+func (b *simBackend) Reset(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	for _, d := range b.devices {
+		if d.connected {
+			b.setConnected(d, false, "Disconnected from")
+		}
+	}
+	if !b.powered {
+		return nil
+	}
+	for _, d := range b.devices {
+		if d.present && !d.removed && d.spec.Connected {
+			b.setConnected(d, true, "Connected to")
+		}
+	}
+	return nil
+}
+
+// ReadLog returns the simulated log lines within the query window.
+func (b *simBackend) ReadLog(ctx context.Context, q LogQuery) ([]byte, error) {
+	if q.Archive != "" {
+		return nil, fmt.Errorf("%w: log archives (sim)", ErrUnsupported)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	since, until := q.Since, q.Until
+	if since.IsZero() {
+		window, err := parseLogDuration(q.Last)
+		if err != nil {
+			return nil, err
+		}
+		since = b.simNow().Add(-window)
+	}
+
+	var out strings.Builder
+	for _, e := range b.log {
+		if !e.at.Before(since) && (until.IsZero() || !e.at.After(until)) {
+			out.WriteString(e.line)
+			out.WriteString("\n")
+		}
+	}
+	return []byte(out.String()), nil
+}
+
+// simStreamInterval is how often StreamLog checks for new log lines.
+const simStreamInterval = 250 * time.Millisecond
+
+// StreamLog emits simulated log lines as they are written, until ctx is done.
+func (b *simBackend) StreamLog(ctx context.Context, fn func(line string)) error {
+	b.mu.Lock()
+	b.advance()
+	next := len(b.log)
+	b.mu.Unlock()
+
+	ticker := time.NewTicker(simStreamInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		b.mu.Lock()
+		b.advance()
+		var lines []string
+		for _, e := range b.log[next:] {
+			lines = append(lines, e.line)
+		}
+		next = len(b.log)
+		b.mu.Unlock()
+
+		for _, line := range lines {
+			fn(line)
+		}
+	}
+}
+
+// parseLogDuration parses a log show --last value such as "30m", "24h", or "7d".
+func parseLogDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+	return d, nil
+}
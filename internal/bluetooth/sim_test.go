@@ -0,0 +1,352 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for the simulator.
+type fakeClock struct{ t time.Time }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2024, 7, 15, 10, 0, 0, 0, time.Local)}
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) add(d time.Duration) { c.t = c.t.Add(d) }
+
+func mustScenario(t *testing.T, s string) *SimScenario {
+	t.Helper()
+	scenario, err := ParseSimScenario([]byte(s))
+	if err != nil {
+		t.Fatalf("unexpected scenario error: %v", err)
+	}
+	return scenario
+}
+
+const simYAML = `
+seed: 1
+adapters:
+  - address: BC:D0:74:22:43:D6
+    chipset: SIM
+devices:
+  - name: AirPods Pro
+    address: 74:15:F5:4E:D0:50
+    type: Headphones
+    connected: true
+    battery: 100
+    rssi: -50
+    drain_per_min: 2
+  - name: Beats Flex
+    address: A8:91:3D:DE:91:C6
+    battery: 10
+    charge_per_min: 5
+  - name: Speaker
+    address: 94:EA:32:75:76:64
+    appear_after: 5m
+    disappear_after: 10m
+`
+
+const simJSON = `{
+  "adapters": [{"address": "BC:D0:74:22:43:D6", "powered": false}],
+  "devices": [{"name": "Mouse", "address": "AA:BB:CC:DD:EE:01", "connect_failure": 1}]
+}`
+
+func TestParseSimScenario(t *testing.T) {
+	s := mustScenario(t, simYAML)
+	if len(s.Devices) != 3 {
+		t.Fatalf("expected 3 devices, got %d", len(s.Devices))
+	}
+	if s.Devices[2].AppearAfter != 5*time.Minute {
+		t.Errorf("expected appear_after 5m, got %v", s.Devices[2].AppearAfter)
+	}
+	if s.Speed != 1 {
+		t.Errorf("expected default speed 1, got %v", s.Speed)
+	}
+
+	j := mustScenario(t, simJSON)
+	if *j.Adapters[0].Powered {
+		t.Error("expected adapter powered off from JSON")
+	}
+
+	if _, err := ParseSimScenario([]byte("devices:\n  - name: x\n")); err == nil {
+		t.Error("expected error for device without address")
+	}
+	if _, err := ParseSimScenario([]byte("devices:\n  - {name: x, address: AA:BB:CC:DD:EE:01, adapter: 11:22:33:44:55:66}\n")); err == nil {
+		t.Error("expected error for device on unknown adapter")
+	}
+	if _, err := ParseSimScenario(defaultSimScenario); err != nil {
+		t.Errorf("default scenario should parse: %v", err)
+	}
+}
+
+func TestSimBackend_BatteryAndVisibility(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	b := newSimBackend(mustScenario(t, simYAML), clock.now)
+
+	snap, err := b.Snapshot(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snap.PowerState != "on" || len(snap.Devices) != 2 {
+		t.Fatalf("unexpected initial snapshot: %+v", snap)
+	}
+
+	clock.add(6 * time.Minute)
+	snap, _ = b.Snapshot(t.Context())
+	if len(snap.Devices) != 3 {
+		t.Fatalf("expected speaker to appear, got %d devices", len(snap.Devices))
+	}
+	if snap.Devices[0].BatteryLevel != 88 {
+		t.Errorf("expected AirPods Pro to drain to 88, got %d", snap.Devices[0].BatteryLevel)
+	}
+	if snap.Devices[1].BatteryLevel != 40 {
+		t.Errorf("expected Beats Flex to charge to 40, got %d", snap.Devices[1].BatteryLevel)
+	}
+
+	clock.add(5 * time.Minute)
+	snap, _ = b.Snapshot(t.Context())
+	if len(snap.Devices) != 2 {
+		t.Errorf("expected speaker to disappear, got %d devices", len(snap.Devices))
+	}
+}
+
+func TestSimBackend_LinkLoss(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	scenario := mustScenario(t, `
+seed: 7
+devices:
+  - name: Headset
+    address: AA:BB:CC:DD:EE:02
+    connected: true
+    link_loss_per_min: 1
+`)
+	b := newSimBackend(scenario, clock.now)
+
+	clock.add(time.Minute)
+	snap, _ := b.Snapshot(t.Context())
+	if snap.Devices[0].Connected {
+		t.Error("expected certain link loss after one minute")
+	}
+
+	out, err := b.ReadLog(t.Context(), LogQuery{Last: "1h"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	events := ParseHistoryEvents(string(out))
+	if len(events) != 2 || events[0].EventType != "connected" || events[1].EventType != "disconnected" {
+		t.Errorf("unexpected history: %+v", events)
+	}
+
+	out, err = b.ReadLog(t.Context(), LogQuery{Since: clock.now().Add(-30 * time.Second)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if events := ParseHistoryEvents(string(out)); len(events) != 1 || events[0].EventType != "disconnected" {
+		t.Errorf("expected only the link loss since 30s ago, got %+v", events)
+	}
+}
+
+func TestSimBackend_Control(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	b := newSimBackend(mustScenario(t, simYAML), clock.now)
+	c := NewClient(b)
+
+	if err := c.Disconnect(t.Context(), "74:15:F5:4E:D0:50"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Connect(t.Context(), "A8:91:3D:DE:91:C6"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	connected, _ := c.ListConnected(t.Context())
+	if len(connected) != 1 || connected[0].Name != "Beats Flex" {
+		t.Errorf("expected only Beats Flex connected, got %+v", connected)
+	}
+
+	if err := c.Remove(t.Context(), "A8:91:3D:DE:91:C6"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.GetDevice(t.Context(), "Beats Flex"); err == nil {
+		t.Error("expected removed device to be gone")
+	}
+
+	if err := c.PowerOff(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report, _ := c.Diagnose(t.Context())
+	if report.PowerState != "off" || len(report.ConnectedDevices) != 0 {
+		t.Errorf("unexpected report after power off: %+v", report)
+	}
+	if err := c.Connect(t.Context(), "74:15:F5:4E:D0:50"); err == nil {
+		t.Error("expected connect to fail while powered off")
+	}
+
+	history, err := c.FetchHistory(t.Context(), LogQuery{Last: "24h"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 4 {
+		t.Errorf("expected 4 history events, got %d: %+v", len(history), history)
+	}
+}
+
+func TestSimBackend_HistorySessions(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	scenario := mustScenario(t, `
+speed: 60
+devices:
+  - name: Headset
+    address: AA:BB:CC:DD:EE:02
+    connected: true
+`)
+	c := NewClient(newSimBackend(scenario, clock.now))
+	start := clock.now()
+
+	// At speed 60 each real second is a simulated minute.
+	clock.add(5 * time.Second)
+	if err := c.Disconnect(t.Context(), "AA:BB:CC:DD:EE:02"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Connect(t.Context(), "AA:BB:CC:DD:EE:02"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock.add(2 * time.Second)
+
+	if want := start.Add(7 * time.Minute); !c.Now().Equal(want) {
+		t.Errorf("expected simulated now %v, got %v", want, c.Now())
+	}
+	events, err := c.FetchHistory(t.Context(), LogQuery{Last: "1h"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sessions := BuildSessions(events, c.Now())
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %+v", sessions)
+	}
+	if sessions[0].Duration != 5*time.Minute || sessions[0].EndedBy != SessionUserDisconnect {
+		t.Errorf("expected a 5m session ended by the user, got %+v", sessions[0])
+	}
+	if sessions[1].Duration != 2*time.Minute || sessions[1].EndedBy != SessionConnected {
+		t.Errorf("expected an open 2m session, got %+v", sessions[1])
+	}
+}
+
+func TestSimBackend_StreamLog(t *testing.T) {
+	t.Parallel()
+	b := newSimBackend(mustScenario(t, simYAML), time.Now)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	lines := make(chan string, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- b.StreamLog(ctx, func(line string) { lines <- line })
+	}()
+
+	// Toggle a device until the stream, which only reports new lines, has
+	// started and picked one up.
+	tick := time.NewTicker(20 * time.Millisecond)
+	defer tick.Stop()
+	timeout := time.After(5 * time.Second)
+	connected := false
+	for {
+		select {
+		case line := <-lines:
+			if !strings.Contains(line, "Beats Flex") {
+				t.Errorf("unexpected line: %s", line)
+			}
+			cancel()
+			if err := <-done; !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+			return
+		case <-tick.C:
+			if connected {
+				b.Disconnect(ctx, "A8:91:3D:DE:91:C6")
+			} else {
+				b.Connect(ctx, "A8:91:3D:DE:91:C6")
+			}
+			connected = !connected
+		case <-timeout:
+			t.Fatal("expected a streamed log line")
+		}
+	}
+}
+
+func TestSimBackend_MultipleAdapters(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	scenario := mustScenario(t, `
+adapters:
+  - {address: BC:D0:74:22:43:D6, chipset: SIM_4387}
+  - {address: 00:1A:7D:DA:71:13, chipset: SIM_DONGLE, powered: false}
+devices:
+  - {name: AirPods Pro, address: 74:15:F5:4E:D0:50}
+  - {name: Game Controller, address: AA:BB:CC:DD:EE:02, adapter: 00:1a:7d:da:71:13}
+`)
+	c := NewClient(newSimBackend(scenario, clock.now))
+
+	controllers, err := c.Controllers(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(controllers) != 2 || controllers[0].State != "on" || controllers[1].State != "off" || controllers[1].Chipset != "SIM_DONGLE" {
+		t.Fatalf("unexpected controllers: %+v", controllers)
+	}
+
+	devices, _ := c.ListDevices(t.Context())
+	if got := DevicesOn(devices, "BC:D0:74:22:43:D6"); len(got) != 1 || got[0].Name != "AirPods Pro" {
+		t.Errorf("expected AirPods Pro on the first adapter, got %+v", got)
+	}
+	if got := DevicesOn(devices, "00:1A:7D:DA:71:13"); len(got) != 1 || got[0].Name != "Game Controller" {
+		t.Errorf("expected Game Controller on the dongle, got %+v", got)
+	}
+}
+
+func TestSimBackend_ConnectFailure(t *testing.T) {
+	t.Parallel()
+	clock := newFakeClock()
+	scenario := mustScenario(t, simJSON)
+	scenario.Adapters[0].Powered = nil
+	b := newSimBackend(scenario, clock.now)
+
+	err := b.Connect(t.Context(), "AA:BB:CC:DD:EE:01")
+	if !errors.Is(err, ErrSimConnectFailed) {
+		t.Errorf("expected ErrSimConnectFailed, got %v", err)
+	}
+}
+
+func TestParseLogDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"5m", 5 * time.Minute, false},
+		{"24h", 24 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"abc", 0, true},
+		{"-1h", 0, true},
+		{"xd", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseLogDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error state: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
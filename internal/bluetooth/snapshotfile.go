@@ -0,0 +1,94 @@
+package bluetooth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SnapshotFileVersion is the version of the snapshot file format written
+// by WriteFile. ReadSnapshotFile rejects files from a newer version.
+const SnapshotFileVersion = 1
+
+// SnapshotFile is a machine's Bluetooth state at one point in time, saved
+// to compare against later, e.g. before and after an OS update.
+type SnapshotFile struct {
+	Version           int          `json:"version"`
+	Time              time.Time    `json:"time"`
+	Backend           string       `json:"backend"`
+	PowerState        string       `json:"power_state"`
+	Controllers       []Controller `json:"controllers"`
+	Devices           []Device     `json:"devices"`
+	BlueUtilInstalled bool         `json:"blueutil_installed"`
+}
+
+// CaptureSnapshotFile captures the current state. BlueUtilInstalled is
+// only ever true for a backend that uses blueutil, such as macOS.
+func (c *Client) CaptureSnapshotFile(ctx context.Context) (*SnapshotFile, error) {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f := &SnapshotFile{
+		Version:           SnapshotFileVersion,
+		Time:              time.Now(),
+		Backend:           c.backend.Name(),
+		PowerState:        snap.PowerState,
+		Controllers:       snap.Controllers,
+		Devices:           snap.Devices,
+		BlueUtilInstalled: c.backend.Tools(ctx)["blueutil"],
+	}
+	return f, nil
+}
+
+// CaptureSnapshotFile captures the current state using the current backend.
+func CaptureSnapshotFile(ctx context.Context) (*SnapshotFile, error) {
+	return defaultClient.CaptureSnapshotFile(ctx)
+}
+
+// ReadSnapshotFile reads a snapshot file written by WriteFile.
+func ReadSnapshotFile(path string) (*SnapshotFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return ParseSnapshotFile(data)
+}
+
+// ParseSnapshotFile parses the contents of a snapshot file.
+func ParseSnapshotFile(data []byte) (*SnapshotFile, error) {
+	var f SnapshotFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	switch {
+	case f.Version == 0:
+		return nil, fmt.Errorf("failed to parse snapshot: no version; not a bltctl snapshot file")
+	case f.Version > SnapshotFileVersion:
+		return nil, fmt.Errorf("failed to parse snapshot: version %d is newer than this bltctl supports (%d)", f.Version, SnapshotFileVersion)
+	}
+	return &f, nil
+}
+
+// WriteFile writes the snapshot as indented JSON.
+func (f *SnapshotFile) WriteFile(path string) error {
+	data, err := f.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Marshal encodes the snapshot as indented JSON, as WriteFile writes it.
+func (f *SnapshotFile) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,731 @@
+package bluetooth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordKind identifies what a store record holds.
+type RecordKind string
+
+// Kinds of store records.
+const (
+	RecordSnapshot   RecordKind = "snapshot"   // every paired device
+	RecordBattery    RecordKind = "battery"    // a device's battery levels changed
+	RecordTransition RecordKind = "transition" // a device connected or disconnected
+)
+
+// Record is one entry in the store.
+type Record struct {
+	Time      time.Time        `json:"time"`
+	Kind      RecordKind       `json:"kind"`
+	Device    string           `json:"device,omitempty"`
+	Address   string           `json:"address,omitempty"`
+	EventType string           `json:"event_type,omitempty"` // transition: "connected" or "disconnected"
+	Batteries []BatteryReading `json:"batteries,omitempty"`  // battery: per component, "main" for single-battery devices
+	Devices   []Device         `json:"devices,omitempty"`    // snapshot: without details or product info
+}
+
+// StoreQuery selects records from the store. Zero fields match everything.
+type StoreQuery struct {
+	Since  time.Time    // inclusive
+	Until  time.Time    // exclusive
+	Kinds  []RecordKind // any of these kinds
+	Device string       // name or address; snapshots are narrowed to the device
+}
+
+const (
+	// DefaultStoreRetention is how long records are kept before Maintain deletes them.
+	DefaultStoreRetention = 180 * 24 * time.Hour
+
+	// DefaultStoreCompactAfter is how old a day must be before Maintain compacts it.
+	DefaultStoreCompactAfter = 48 * time.Hour
+
+	// defaultSegmentSize is the size at which a day's segment is rotated.
+	defaultSegmentSize = 8 << 20
+
+	// snapshotInterval is how often an unchanged snapshot is recorded anyway,
+	// so queries can tell when a device was last seen.
+	snapshotInterval = time.Hour
+
+	// compactBatteryInterval is the spacing of battery samples kept by Compact.
+	compactBatteryInterval = 15 * time.Minute
+
+	// storeLockFile coordinates the bltctl processes sharing a store.
+	storeLockFile = ".lock"
+)
+
+// Store is an append-only record of what bltctl has observed: device
+// snapshots, battery samples, and connect/disconnect transitions.
+//
+// Records are JSON lines in one segment per UTC day, YYYY-MM-DD.jsonl,
+// rotated to YYYY-MM-DD.1.jsonl and so on when a segment grows past 8 MiB.
+// Compact merges a day's segments into YYYY-MM-DD.compact.jsonl, thinning
+// repeated snapshots and battery samples. Several processes may share a
+// store: writers hold an exclusive lock on the .lock file and readers a
+// shared one, so compaction never removes records another process is
+// appending.
+type Store struct {
+	dir         string
+	segmentSize int64
+
+	mu           sync.Mutex
+	state        map[string]Device // last observed device, by deviceKey
+	lastSnapshot time.Time
+	tail         segmentTail // the newest segment as of the last load
+}
+
+// segmentTail is the newest segment and its size when the observed state
+// was last read, so Observe can tell whether other processes wrote since.
+type segmentTail struct {
+	path string
+	size int64
+}
+
+// segment is one file of the store.
+type segment struct {
+	path  string
+	day   time.Time // UTC midnight
+	index int       // -1 for a compacted segment
+}
+
+// OpenStore opens the store in dir, creating the directory if needed.
+func OpenStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &Store{dir: dir, segmentSize: defaultSegmentSize}, nil
+}
+
+// Dir returns the directory the store lives in.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Observe records what changed since the last observed snapshot: a
+// transition for each device whose connection state changed, a battery
+// sample for each device whose levels changed, and a snapshot when the
+// devices changed or none was recorded in the last hour. The last observed
+// snapshot is the newest in the store, whichever process wrote it.
+func (s *Store) Observe(snap *Snapshot, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	var records []Record
+	changed := len(snap.Devices) != len(s.state)
+	for _, d := range snap.Devices {
+		prev, known := s.state[deviceKey(d.Name, d.Address)]
+		switch {
+		case !known:
+			changed = true
+		case prev.Connected != d.Connected:
+			changed = true
+			records = append(records, Record{
+				Time:      at,
+				Kind:      RecordTransition,
+				Device:    d.Name,
+				Address:   d.Address,
+				EventType: connectionEvent(d.Connected),
+			})
+		}
+		if readings := batteryReadings(d); len(readings) > 0 && !slices.Equal(readings, batteryReadings(prev)) {
+			records = append(records, Record{
+				Time:      at,
+				Kind:      RecordBattery,
+				Device:    d.Name,
+				Address:   d.Address,
+				Batteries: readings,
+			})
+		}
+	}
+	if changed || at.Sub(s.lastSnapshot) >= snapshotInterval {
+		records = append(records, Record{Time: at, Kind: RecordSnapshot, Devices: storedDevices(snap.Devices)})
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+	if err := s.append(records); err != nil {
+		return err
+	}
+	return s.load()
+}
+
+// Append adds records to the store as they are.
+func (s *Store) Append(records ...Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.append(records)
+}
+
+// lock takes the store's lock file, exclusive for writers and shared for
+// readers. The returned func releases it.
+func (s *Store) lock(exclusive bool) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(s.dir, storeLockFile), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock store: %w", err)
+	}
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock store: %w", err)
+	}
+	return func() { f.Close() }, nil
+}
+
+// append writes records to the current segment of their day.
+func (s *Store) append(records []Record) error {
+	byDay := make(map[time.Time][]byte)
+	var days []time.Time
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+		day := utcDay(r.Time)
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(append(byDay[day], line...), '\n')
+	}
+
+	for _, day := range days {
+		path, err := s.currentSegment(day)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open store segment: %w", err)
+		}
+		_, werr := f.Write(byDay[day])
+		if err := errors.Join(werr, f.Close()); err != nil {
+			return fmt.Errorf("failed to write store segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// currentSegment returns the segment to append to for day, rotating to a
+// new one when the latest is full.
+func (s *Store) currentSegment(day time.Time) (string, error) {
+	segs, err := s.segments()
+	if err != nil {
+		return "", err
+	}
+	index := 0
+	for _, seg := range segs {
+		if !seg.day.Equal(day) || seg.index < 0 {
+			continue
+		}
+		index = seg.index
+		if info, err := os.Stat(seg.path); err == nil && info.Size() >= s.segmentSize {
+			index = seg.index + 1
+		}
+	}
+	return s.segmentPath(day, index), nil
+}
+
+// segmentPath returns the file name of a segment; index -1 is the
+// compacted segment.
+func (s *Store) segmentPath(day time.Time, index int) string {
+	name := day.Format(time.DateOnly)
+	switch {
+	case index < 0:
+		name += ".compact"
+	case index > 0:
+		name += "." + strconv.Itoa(index)
+	}
+	return filepath.Join(s.dir, name+".jsonl")
+}
+
+// segments lists the store's segments, oldest first.
+func (s *Store) segments() ([]segment, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list store segments: %w", err)
+	}
+	var segs []segment
+	for _, path := range paths {
+		parts := strings.Split(strings.TrimSuffix(filepath.Base(path), ".jsonl"), ".")
+		day, err := time.Parse(time.DateOnly, parts[0])
+		if err != nil || len(parts) > 2 {
+			continue
+		}
+		seg := segment{path: path, day: day}
+		if len(parts) == 2 {
+			if parts[1] == "compact" {
+				seg.index = -1
+			} else if seg.index, err = strconv.Atoi(parts[1]); err != nil || seg.index < 1 {
+				continue
+			}
+		}
+		segs = append(segs, seg)
+	}
+	sort.Slice(segs, func(i, j int) bool {
+		if !segs[i].day.Equal(segs[j].day) {
+			return segs[i].day.Before(segs[j].day)
+		}
+		return segs[i].index < segs[j].index
+	})
+	return segs, nil
+}
+
+// readSegment reads a segment's records. Lines that do not decode, such as
+// one cut short by a crash, are skipped.
+func readSegment(path string) ([]Record, error) {
+	return readSegmentFrom(path, 0)
+}
+
+// readSegmentFrom reads a segment's records from byte offset on.
+func readSegmentFrom(path string, offset int64) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store segment: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read store segment: %w", err)
+	}
+
+	var records []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for sc.Scan() {
+		var r Record
+		if json.Unmarshal(sc.Bytes(), &r) == nil && r.Kind != "" {
+			records = append(records, r)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read store segment: %w", err)
+	}
+	return records, nil
+}
+
+// load brings the last observed state up to date with the store. If only
+// the newest segment grew since the last call, the records appended to it
+// are applied; otherwise the state is rebuilt from the most recent
+// snapshot and the records after it. The caller holds the lock.
+func (s *Store) load() error {
+	segs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	var tail segmentTail
+	if len(segs) > 0 {
+		tail.path = segs[len(segs)-1].path
+		info, err := os.Stat(tail.path)
+		if err != nil {
+			return fmt.Errorf("failed to read store segment: %w", err)
+		}
+		tail.size = info.Size()
+	}
+	if s.state != nil && tail.path == s.tail.path && tail.size >= s.tail.size {
+		if tail.size == s.tail.size {
+			return nil
+		}
+		records, err := readSegmentFrom(tail.path, s.tail.size)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			s.apply(r)
+		}
+		s.tail = tail
+		return nil
+	}
+
+	s.state = make(map[string]Device)
+	s.lastSnapshot = time.Time{}
+	var records []Record
+	for i := len(segs) - 1; i >= 0; i-- {
+		recs, err := readSegment(segs[i].path)
+		if err != nil {
+			return err
+		}
+		records = append(recs, records...)
+		if slices.ContainsFunc(recs, func(r Record) bool { return r.Kind == RecordSnapshot }) {
+			break
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	for _, r := range records {
+		s.apply(r)
+	}
+	s.tail = tail
+	return nil
+}
+
+// apply updates the last observed state with a record.
+func (s *Store) apply(r Record) {
+	switch r.Kind {
+	case RecordSnapshot:
+		s.state = make(map[string]Device, len(r.Devices))
+		for _, d := range r.Devices {
+			s.state[deviceKey(d.Name, d.Address)] = d
+		}
+		s.lastSnapshot = r.Time
+	case RecordBattery:
+		key := deviceKey(r.Device, r.Address)
+		if d, ok := s.state[key]; ok {
+			d.Batteries = slices.Clone(r.Batteries)
+			s.state[key] = d
+		}
+	case RecordTransition:
+		key := deviceKey(r.Device, r.Address)
+		if d, ok := s.state[key]; ok {
+			d.Connected = r.EventType == "connected"
+			s.state[key] = d
+		}
+	}
+}
+
+// Query returns the records matching q, oldest first.
+func (s *Store) Query(q StoreQuery) ([]Record, error) {
+	unlock, err := s.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	segs, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Record
+	for _, seg := range segs {
+		if !q.Since.IsZero() && seg.day.Before(utcDay(q.Since)) {
+			continue
+		}
+		if !q.Until.IsZero() && !seg.day.Before(q.Until.UTC()) {
+			continue
+		}
+		records, err := readSegment(seg.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			if r, ok := q.match(r); ok {
+				out = append(out, r)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, nil
+}
+
+// QueryStore returns records from the store used by the package-level functions.
+func QueryStore(q StoreQuery) ([]Record, error) {
+	s := CurrentStore()
+	if s == nil {
+		return nil, fmt.Errorf("no store is open")
+	}
+	return s.Query(q)
+}
+
+// match reports whether r is selected by q, narrowing snapshots to the
+// queried device.
+func (q StoreQuery) match(r Record) (Record, bool) {
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return r, false
+	}
+	if !q.Until.IsZero() && !r.Time.Before(q.Until) {
+		return r, false
+	}
+	if len(q.Kinds) > 0 && !slices.Contains(q.Kinds, r.Kind) {
+		return r, false
+	}
+	if q.Device == "" {
+		return r, true
+	}
+	if r.Kind != RecordSnapshot {
+		return r, matchesDevice(r.Device, r.Address, q.Device)
+	}
+	var devices []Device
+	for _, d := range r.Devices {
+		if matchesDevice(d.Name, d.Address, q.Device) {
+			devices = append(devices, d)
+		}
+	}
+	r.Devices = devices
+	return r, len(devices) > 0
+}
+
+// matchesDevice reports whether query is a device's name or address.
+func matchesDevice(name, address, query string) bool {
+	if strings.EqualFold(strings.TrimSpace(query), name) {
+		return true
+	}
+	addr := normalizeAddress(query)
+	return addr != "" && addr == normalizeAddress(address)
+}
+
+// Compact merges the segments of each day before the one containing
+// before into a single compacted segment. Transitions are kept; battery
+// samples are thinned to one per device every 15 minutes, and snapshots
+// to the first of the day and those whose devices or connection states
+// differ from the previous one kept.
+func (s *Store) Compact(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	segs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	byDay := make(map[time.Time][]segment)
+	var days []time.Time
+	for _, seg := range segs {
+		if !seg.day.Before(utcDay(before)) {
+			continue
+		}
+		if _, ok := byDay[seg.day]; !ok {
+			days = append(days, seg.day)
+		}
+		byDay[seg.day] = append(byDay[seg.day], seg)
+	}
+
+	for _, day := range days {
+		daySegs := byDay[day]
+		if len(daySegs) == 1 && daySegs[0].index < 0 {
+			continue
+		}
+		if err := s.compactDay(day, daySegs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compactDay replaces a day's segments with one compacted segment.
+func (s *Store) compactDay(day time.Time, segs []segment) error {
+	var records []Record
+	for _, seg := range segs {
+		recs, err := readSegment(seg.path)
+		if err != nil {
+			return err
+		}
+		records = append(records, recs...)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+
+	var buf bytes.Buffer
+	for _, r := range thinRecords(records) {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	target := s.segmentPath(day, -1)
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to compact store: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact store: %w", err)
+	}
+	for _, seg := range segs {
+		if seg.path == target {
+			continue
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return fmt.Errorf("failed to compact store: %w", err)
+		}
+	}
+	return nil
+}
+
+// thinRecords drops redundant snapshots and battery samples from records
+// sorted by time.
+func thinRecords(records []Record) []Record {
+	var out []Record
+	var lastSnapshot string
+	seenSnapshot := false
+	lastBattery := make(map[string]time.Time)
+	for _, r := range records {
+		switch r.Kind {
+		case RecordSnapshot:
+			sig := snapshotSignature(r.Devices)
+			if seenSnapshot && sig == lastSnapshot {
+				continue
+			}
+			lastSnapshot, seenSnapshot = sig, true
+		case RecordBattery:
+			key := deviceKey(r.Device, r.Address)
+			if last, ok := lastBattery[key]; ok && r.Time.Sub(last) < compactBatteryInterval {
+				continue
+			}
+			lastBattery[key] = r.Time
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// snapshotSignature summarises which devices a snapshot holds and whether
+// each is connected.
+func snapshotSignature(devices []Device) string {
+	parts := make([]string, 0, len(devices))
+	for _, d := range devices {
+		parts = append(parts, fmt.Sprintf("%s=%t", deviceKey(d.Name, d.Address), d.Connected))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// Prune deletes the segments of every day before the one containing before.
+func (s *Store) Prune(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	segs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	for _, seg := range segs {
+		if !seg.day.Before(utcDay(before)) {
+			continue
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return fmt.Errorf("failed to prune store: %w", err)
+		}
+	}
+	return nil
+}
+
+// Maintain compacts days older than DefaultStoreCompactAfter and deletes
+// those older than DefaultStoreRetention. It does nothing if it already
+// ran in the last day, so it is cheap to call on every start.
+func (s *Store) Maintain(now time.Time) error {
+	marker := filepath.Join(s.dir, ".maintained")
+	if info, err := os.Stat(marker); err == nil && now.Sub(info.ModTime()) < 24*time.Hour {
+		return nil
+	}
+	if err := s.Prune(now.Add(-DefaultStoreRetention)); err != nil {
+		return err
+	}
+	if err := s.Compact(now.Add(-DefaultStoreCompactAfter)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		return fmt.Errorf("failed to mark store maintained: %w", err)
+	}
+	return os.Chtimes(marker, now, now)
+}
+
+// deviceKey identifies a device in the store: by address, or by name if it
+// has none.
+func deviceKey(name, address string) string {
+	if addr := normalizeAddress(address); addr != "" {
+		return addr
+	}
+	return name
+}
+
+// connectionEvent returns the event type for a connection state.
+func connectionEvent(connected bool) string {
+	if connected {
+		return "connected"
+	}
+	return "disconnected"
+}
+
+// batteryReadings returns a device's battery levels, as a single "main"
+// reading for single-battery devices, or nil if unknown.
+func batteryReadings(d Device) []BatteryReading {
+	if len(d.Batteries) > 0 {
+		return d.Batteries
+	}
+	if d.BatteryLevel >= 0 {
+		return []BatteryReading{{Component: BatteryMain, Level: d.BatteryLevel}}
+	}
+	return nil
+}
+
+// storedDevices copies devices for a snapshot record, without details or
+// product info, which are reconstructed from the backend and product table.
+func storedDevices(devices []Device) []Device {
+	out := make([]Device, len(devices))
+	for i, d := range devices {
+		d.Batteries = slices.Clone(d.Batteries)
+		d.Details = nil
+		d.Product = nil
+		out[i] = d
+	}
+	return out
+}
+
+// utcDay returns midnight UTC of the day containing t.
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// SetStore sets the store that fresh snapshots are recorded in. nil stops
+// recording.
+func (c *Client) SetStore(s *Store) {
+	c.store = s
+}
+
+// Store returns the client's store, or nil if none is set.
+func (c *Client) Store() *Store {
+	return c.store
+}
+
+// observe records a fresh snapshot in the store, if one is set. Recording
+// is best-effort: a full disk must not stop bltctl from listing devices.
+func (c *Client) observe(snap *Snapshot) {
+	if c.store != nil {
+		_ = c.store.Observe(snap, time.Now())
+	}
+}
+
+// SetStore sets the store used by the package-level functions.
+func SetStore(s *Store) {
+	defaultClient.SetStore(s)
+}
+
+// CurrentStore returns the store used by the package-level functions, or
+// nil if none is set.
+func CurrentStore() *Store {
+	return defaultClient.Store()
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package bluetooth
+
+import "os"
+
+// lockFile does nothing on platforms without flock; bltctl's backends only
+// run on macOS and Linux.
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
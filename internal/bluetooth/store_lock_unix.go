@@ -0,0 +1,18 @@
+//go:build unix
+
+package bluetooth
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an advisory lock on f, shared or exclusive, waiting until
+// it is granted. Closing f releases it.
+func lockFile(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	return syscall.Flock(int(f.Fd()), how)
+}
@@ -0,0 +1,330 @@
+package bluetooth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var storeBase = time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)
+
+func storeSnapshot(connected bool, battery int) *Snapshot {
+	return &Snapshot{Devices: []Device{
+		{Name: "AirPods Max", Address: "70:F9:4A:7A:8B:CA", Connected: connected, BatteryLevel: battery},
+		{Name: "Magic Keyboard", Address: "AA:BB:CC:DD:EE:01", BatteryLevel: -1},
+	}}
+}
+
+func countKinds(records []Record) map[RecordKind]int {
+	counts := make(map[RecordKind]int)
+	for _, r := range records {
+		counts[r.Kind]++
+	}
+	return counts
+}
+
+func TestStore_Observe(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	steps := []struct {
+		name string
+		snap *Snapshot
+		at   time.Duration
+		want map[RecordKind]int // records written by this step
+	}{
+		{"first sight", storeSnapshot(true, 80), 0, map[RecordKind]int{RecordSnapshot: 1, RecordBattery: 1}},
+		{"unchanged", storeSnapshot(true, 80), time.Minute, map[RecordKind]int{}},
+		{"battery drain", storeSnapshot(true, 75), 2 * time.Minute, map[RecordKind]int{RecordBattery: 1}},
+		{"disconnect", storeSnapshot(false, 75), 3 * time.Minute, map[RecordKind]int{RecordTransition: 1, RecordSnapshot: 1}},
+		{"hourly snapshot", storeSnapshot(false, 75), 2 * time.Hour, map[RecordKind]int{RecordSnapshot: 1}},
+	}
+
+	total := 0
+	for _, step := range steps {
+		if err := s.Observe(step.snap, storeBase.Add(step.at)); err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+		records, err := s.Query(StoreQuery{Since: storeBase.Add(step.at), Until: storeBase.Add(step.at + time.Second)})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+		got := countKinds(records)
+		for kind, n := range step.want {
+			if got[kind] != n {
+				t.Errorf("%s: expected %d %s records, got %d", step.name, n, kind, got[kind])
+			}
+		}
+		if len(records) != sum(step.want) {
+			t.Errorf("%s: expected %d records, got %+v", step.name, sum(step.want), records)
+		}
+		total += len(records)
+	}
+
+	// A new process picks up where the last left off.
+	reopened, err := OpenStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := reopened.Observe(storeSnapshot(false, 75), storeBase.Add(2*time.Hour+time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := reopened.Query(StoreQuery{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != total {
+		t.Errorf("expected reopened store to write nothing, got %d records, want %d", len(records), total)
+	}
+
+	transitions, _ := reopened.Query(StoreQuery{Kinds: []RecordKind{RecordTransition}})
+	if len(transitions) != 1 || transitions[0].EventType != "disconnected" || transitions[0].Device != "AirPods Max" {
+		t.Errorf("unexpected transitions: %+v", transitions)
+	}
+}
+
+func sum(counts map[RecordKind]int) int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
+
+func TestStore_QueryDevice(t *testing.T) {
+	s, _ := OpenStore(t.TempDir())
+	if err := s.Observe(storeSnapshot(true, 80), storeBase); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, query := range []string{"magic keyboard", "aa-bb-cc-dd-ee-01"} {
+		records, err := s.Query(StoreQuery{Device: query})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(records) != 1 || records[0].Kind != RecordSnapshot || len(records[0].Devices) != 1 || records[0].Devices[0].Name != "Magic Keyboard" {
+			t.Errorf("%q: expected the snapshot narrowed to Magic Keyboard, got %+v", query, records)
+		}
+	}
+
+	records, _ := s.Query(StoreQuery{Device: "AirPods Max", Kinds: []RecordKind{RecordBattery}})
+	if len(records) != 1 || len(records[0].Batteries) != 1 || records[0].Batteries[0] != (BatteryReading{BatteryMain, 80}) {
+		t.Errorf("expected one main battery sample, got %+v", records)
+	}
+}
+
+func TestStore_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := OpenStore(dir)
+	s.segmentSize = 200
+
+	for i := range 10 {
+		r := Record{Time: storeBase.Add(time.Duration(i) * time.Minute), Kind: RecordTransition, Device: "AirPods Max", EventType: "connected"}
+		if err := s.Append(r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	segs, _ := s.segments()
+	if len(segs) < 2 {
+		t.Fatalf("expected the day to rotate into several segments, got %d", len(segs))
+	}
+	records, _ := s.Query(StoreQuery{})
+	if len(records) != 10 {
+		t.Fatalf("expected 10 records across segments, got %d", len(records))
+	}
+	for i := 1; i < len(records); i++ {
+		if records[i].Time.Before(records[i-1].Time) {
+			t.Errorf("expected records in time order, got %v before %v", records[i-1].Time, records[i].Time)
+		}
+	}
+}
+
+func TestStore_CompactAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := OpenStore(dir)
+	s.segmentSize = 1 << 10
+
+	// An hour of one-minute battery samples with an unchanged snapshot each time.
+	for i := range 60 {
+		at := storeBase.Add(time.Duration(i) * time.Minute)
+		if err := s.Append(
+			Record{Time: at, Kind: RecordBattery, Device: "AirPods Max", Address: "70:F9:4A:7A:8B:CA", Batteries: []BatteryReading{{BatteryMain, 100 - i}}},
+			Record{Time: at, Kind: RecordSnapshot, Devices: storeSnapshot(true, 100-i).Devices},
+		); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	s.Append(Record{Time: storeBase.Add(time.Hour), Kind: RecordTransition, Device: "AirPods Max", EventType: "disconnected"})
+	s.Append(Record{Time: storeBase.Add(24 * time.Hour), Kind: RecordTransition, Device: "AirPods Max", EventType: "connected"})
+
+	if err := s.Compact(storeBase.Add(24 * time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	segs, _ := s.segments()
+	if len(segs) != 2 || segs[0].index != -1 || segs[1].index != 0 {
+		t.Fatalf("expected a compacted day and an untouched day, got %+v", segs)
+	}
+
+	counts := countKinds(mustQuery(t, s, StoreQuery{Until: storeBase.Add(24 * time.Hour)}))
+	if counts[RecordBattery] != 4 || counts[RecordSnapshot] != 1 || counts[RecordTransition] != 1 {
+		t.Errorf("expected 4 battery samples, 1 snapshot, and 1 transition, got %v", counts)
+	}
+
+	// Compacting again leaves the compacted day alone.
+	before, _ := os.Stat(segs[0].path)
+	if err := s.Compact(storeBase.Add(24 * time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if after, _ := os.Stat(segs[0].path); !after.ModTime().Equal(before.ModTime()) {
+		t.Error("expected compacted day to be left alone")
+	}
+
+	if err := s.Prune(storeBase.Add(24 * time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if records := mustQuery(t, s, StoreQuery{}); len(records) != 1 || records[0].EventType != "connected" {
+		t.Errorf("expected only the second day left, got %+v", records)
+	}
+}
+
+func TestStore_AppendWhileCompacting(t *testing.T) {
+	dir := t.TempDir()
+	// Two stores on one directory stand in for two bltctl processes.
+	writer, _ := OpenStore(dir)
+	compactor, _ := OpenStore(dir)
+	writer.segmentSize = 1 << 10
+
+	const n = 200
+	done := make(chan error, 1)
+	go func() {
+		for i := range n {
+			r := Record{Time: storeBase.Add(time.Duration(i) * time.Second), Kind: RecordTransition, Device: "AirPods Max", EventType: connectionEvent(i%2 == 0)}
+			if err := writer.Append(r); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for {
+		if err := compactor.Compact(storeBase.Add(24 * time.Hour)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if records := mustQuery(t, compactor, StoreQuery{}); len(records) != n {
+				t.Errorf("expected all %d transitions to survive compaction, got %d", n, len(records))
+			}
+			return
+		default:
+		}
+	}
+}
+
+func TestStore_ObserveAcrossProcesses(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := OpenStore(dir)
+	b, _ := OpenStore(dir)
+
+	steps := []struct {
+		store     *Store
+		connected bool
+	}{
+		{a, true},
+		{b, true},
+		{a, false},
+		{b, false}, // b last saw "connected" itself, but a already recorded the disconnect
+	}
+	for i, step := range steps {
+		if err := step.store.Observe(storeSnapshot(step.connected, 80), storeBase.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	transitions := mustQuery(t, a, StoreQuery{Kinds: []RecordKind{RecordTransition}})
+	if len(transitions) != 1 || transitions[0].EventType != "disconnected" {
+		t.Errorf("expected one disconnect across both processes, got %+v", transitions)
+	}
+}
+
+func TestThinRecords_EmptySnapshots(t *testing.T) {
+	records := thinRecords([]Record{
+		{Time: storeBase, Kind: RecordSnapshot},
+		{Time: storeBase.Add(time.Hour), Kind: RecordSnapshot},
+		{Time: storeBase.Add(2 * time.Hour), Kind: RecordSnapshot},
+	})
+	if len(records) != 1 {
+		t.Errorf("expected repeated empty snapshots thinned to one, got %d", len(records))
+	}
+}
+
+func TestStore_Maintain(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := OpenStore(dir)
+	now := storeBase.Add(DefaultStoreRetention + 24*time.Hour)
+	s.Append(
+		Record{Time: storeBase, Kind: RecordTransition, Device: "AirPods Max", EventType: "connected"},
+		Record{Time: now.Add(-72 * time.Hour), Kind: RecordTransition, Device: "AirPods Max", EventType: "disconnected"},
+	)
+
+	if err := s.Maintain(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	segs, _ := s.segments()
+	if len(segs) != 1 || segs[0].index != -1 {
+		t.Fatalf("expected the old day pruned and the recent one compacted, got %+v", segs)
+	}
+
+	// Within a day of the last run, Maintain does nothing.
+	s.Append(Record{Time: storeBase, Kind: RecordTransition, Device: "AirPods Max", EventType: "connected"})
+	if err := s.Maintain(now.Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, storeBase.Format(time.DateOnly)+".jsonl")); err != nil {
+		t.Errorf("expected recent Maintain run to skip pruning: %v", err)
+	}
+}
+
+func TestStore_SkipsTornLines(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := OpenStore(dir)
+	s.Append(Record{Time: storeBase, Kind: RecordTransition, Device: "AirPods Max", EventType: "connected"})
+
+	path := filepath.Join(dir, storeBase.Format(time.DateOnly)+".jsonl")
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"time":"2024-07-15T10:01:00Z","kind":"trans`)
+	f.Close()
+
+	if records := mustQuery(t, s, StoreQuery{}); len(records) != 1 {
+		t.Errorf("expected the torn line skipped, got %+v", records)
+	}
+}
+
+func TestClient_RecordsSnapshots(t *testing.T) {
+	s, _ := OpenStore(t.TempDir())
+	c := NewClient(newFakeBackend())
+	c.SetStore(s)
+
+	if _, err := c.ListDevices(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	counts := countKinds(mustQuery(t, s, StoreQuery{}))
+	if counts[RecordSnapshot] != 1 || counts[RecordBattery] != 1 {
+		t.Errorf("expected a snapshot and a battery sample, got %v", counts)
+	}
+}
+
+func mustQuery(t *testing.T, s *Store, q StoreQuery) []Record {
+	t.Helper()
+	records, err := s.Query(q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return records
+}
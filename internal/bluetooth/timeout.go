@@ -0,0 +1,79 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Default per-operation timeouts, used unless the client has an override.
+const (
+	// DefaultSnapshotTimeout bounds device enumeration. system_profiler
+	// normally answers in 1-3s but hangs when bluetoothd is wedged.
+	DefaultSnapshotTimeout = 15 * time.Second
+	// DefaultControlTimeout bounds connect, disconnect, and remove, which
+	// wait on the remote device.
+	DefaultControlTimeout = 30 * time.Second
+	// DefaultPowerTimeout bounds power changes and resets.
+	DefaultPowerTimeout = 30 * time.Second
+	// DefaultLogTimeout bounds log retrieval; long windows are slow to scan.
+	DefaultLogTimeout = 60 * time.Second
+)
+
+// TimeoutError is returned when an external tool does not finish before
+// the operation's deadline.
+type TimeoutError struct {
+	Tool    string        // the command or service that stalled, e.g. "system_profiler"
+	Timeout time.Duration // how long bltctl waited
+}
+
+// Error names the tool that stalled and how long bltctl waited.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Tool, e.Timeout)
+}
+
+// Unwrap lets errors.Is(err, context.DeadlineExceeded) match.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// contextError converts the error of a call to tool that started at start
+// and was cut short by ctx. It returns a TimeoutError for an expired
+// deadline and ctx.Err() for cancellation.
+func contextError(ctx context.Context, tool string, start time.Time) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ctx.Err()
+	}
+	waited := time.Since(start)
+	if deadline, ok := ctx.Deadline(); ok {
+		waited = deadline.Sub(start)
+	}
+	if waited >= time.Second {
+		waited = waited.Round(time.Second)
+	} else {
+		waited = waited.Round(time.Millisecond)
+	}
+	return &TimeoutError{Tool: tool, Timeout: waited}
+}
+
+// withTimeout derives a context for one backend call. The client's override
+// takes precedence over def; a shorter deadline already on ctx is kept.
+func (c *Client) withTimeout(ctx context.Context, def time.Duration) (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		def = c.timeout
+	}
+	return context.WithTimeout(ctx, def)
+}
+
+// SetTimeout overrides the per-operation default timeouts with d.
+// Zero restores the defaults.
+func (c *Client) SetTimeout(d time.Duration) {
+	c.timeout = d
+}
+
+// SetTimeout overrides the per-operation default timeouts of the
+// package-level functions. Zero restores the defaults.
+func SetTimeout(d time.Duration) {
+	defaultClient.SetTimeout(d)
+}
@@ -0,0 +1,118 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stallingBackend is a fakeBackend whose Snapshot hangs until its context ends,
+// like system_profiler against a wedged bluetoothd.
+type stallingBackend struct {
+	*fakeBackend
+}
+
+func (s stallingBackend) Snapshot(ctx context.Context) (*Snapshot, error) {
+	start := time.Now()
+	<-ctx.Done()
+	return nil, contextError(ctx, "system_profiler", start)
+}
+
+func TestExecRunner_Timeout(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := ExecRunner(ctx, "sleep", "5")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected TimeoutError, got %v", err)
+	}
+	if timeoutErr.Tool != "sleep" {
+		t.Errorf("expected tool sleep, got %q", timeoutErr.Tool)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected error to match context.DeadlineExceeded")
+	}
+}
+
+func TestExecRunner_Canceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := ExecRunner(ctx, "sleep", "5")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		t.Error("cancellation should not be reported as a timeout")
+	}
+}
+
+func TestExecStreamer(t *testing.T) {
+	t.Parallel()
+	var lines []string
+	err := ExecStreamer(t.Context(), func(line string) { lines = append(lines, line) }, "printf", `a\nb\n`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 2 || lines[0] != "a" || lines[1] != "b" {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+}
+
+func TestExecStreamer_Canceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	err := ExecStreamer(ctx, func(string) {}, "sleep", "5")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+}
+
+func TestRunnerStreamer(t *testing.T) {
+	t.Parallel()
+	run := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("a\n\nb\n"), errors.New("exit status 1")
+	}
+	var lines []string
+	err := runnerStreamer(run)(t.Context(), func(line string) { lines = append(lines, line) }, "log", "stream")
+	if err == nil || len(lines) != 2 {
+		t.Errorf("expected both lines and the error, got %q, %v", lines, err)
+	}
+}
+
+func TestClient_TimeoutOverride(t *testing.T) {
+	t.Parallel()
+	c := NewClient(stallingBackend{newFakeBackend()})
+	c.SetTimeout(20 * time.Millisecond)
+
+	_, err := c.ListDevices(t.Context())
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected TimeoutError, got %v", err)
+	}
+	if got := err.Error(); got != "system_profiler timed out after 20ms" {
+		t.Errorf("unexpected message: %q", got)
+	}
+}
+
+func TestClient_ParentDeadlineWins(t *testing.T) {
+	t.Parallel()
+	c := NewClient(stallingBackend{newFakeBackend()})
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.Diagnose(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > DefaultSnapshotTimeout/2 {
+		t.Errorf("expected parent deadline to cut the call short, took %s", elapsed)
+	}
+}
@@ -1,10 +1,9 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 	"text/tabwriter"
 	"time"
 
@@ -15,6 +14,7 @@ import (
 // BatteryAlert represents a low-battery alert for JSON output.
 type BatteryAlert struct {
 	Device       string `json:"device"`
+	Component    string `json:"component,omitempty"` // "left", "right", "case", or "main"; empty for single-battery devices
 	BatteryLevel int    `json:"battery_level"`
 	Threshold    int    `json:"threshold"`
 	Alert        string `json:"alert"`
@@ -37,17 +37,17 @@ var batteryCmd = &cobra.Command{
 func runBattery(cmd *cobra.Command, args []string) error {
 	watch, _ := cmd.Flags().GetBool("watch")
 	if !watch {
-		return showBattery()
+		return showBattery(cmd.Context())
 	}
 
 	interval, _ := cmd.Flags().GetInt("interval")
 	low, _ := cmd.Flags().GetInt("low")
-	return watchBattery(interval, low)
+	return watchBattery(cmd.Context(), interval, low)
 }
 
 // showBattery displays battery levels once and returns.
-func showBattery() error {
-	devices, err := bluetooth.ListConnected()
+func showBattery(ctx context.Context) error {
+	devices, err := bluetooth.ListConnected(ctx)
 	if err != nil {
 		return err
 	}
@@ -71,6 +71,9 @@ func showBattery() error {
 			level = fmt.Sprintf("%d%%", d.BatteryLevel)
 			bar = batteryBar(d.BatteryLevel)
 		}
+		if d.HasComponentBatteries() {
+			level = bluetooth.FormatBatteries(d.Batteries)
+		}
 		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Name, bar, level)
 	}
 
@@ -79,25 +82,28 @@ func showBattery() error {
 
 // watchBattery polls battery levels at the given interval and alerts on low battery.
 // Returns a non-nil error (with exit code 1) if any device drops below the threshold.
-func watchBattery(intervalSec, threshold int) error {
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-	defer signal.Stop(sig)
-
+// It stops cleanly when ctx is cancelled (SIGINT/SIGTERM).
+func watchBattery(ctx context.Context, intervalSec, threshold int) error {
 	ticker := time.NewTicker(time.Duration(intervalSec) * time.Second)
 	defer ticker.Stop()
 
 	// Run immediately on first tick
-	if err := pollBattery(threshold); err != nil {
+	if err := pollBattery(ctx, threshold); err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil
+		}
 		return err
 	}
 
 	for {
 		select {
-		case <-sig:
+		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			if err := pollBattery(threshold); err != nil {
+			if err := pollBattery(ctx, threshold); err != nil {
+				if ctx.Err() == context.Canceled {
+					return nil
+				}
 				return err
 			}
 		}
@@ -105,21 +111,18 @@ func watchBattery(intervalSec, threshold int) error {
 }
 
 // pollBattery checks battery levels once and returns an error if any device is below threshold.
-func pollBattery(threshold int) error {
-	devices, err := bluetooth.ListConnected()
+func pollBattery(ctx context.Context, threshold int) error {
+	devices, err := bluetooth.ListConnected(ctx)
 	if err != nil {
 		return err
 	}
 
 	var alerts []BatteryAlert
+	lowDevices := 0
 	for _, d := range devices {
-		if d.BatteryLevel >= 0 && d.BatteryLevel < threshold {
-			alerts = append(alerts, BatteryAlert{
-				Device:       d.Name,
-				BatteryLevel: d.BatteryLevel,
-				Threshold:    threshold,
-				Alert:        "low battery",
-			})
+		if a := lowBatteryAlerts(d, threshold); len(a) > 0 {
+			alerts = append(alerts, a...)
+			lowDevices++
 		}
 	}
 
@@ -140,30 +143,60 @@ func pollBattery(threshold int) error {
 			fmt.Println("  No connected devices.")
 		} else {
 			for _, d := range devices {
-				level := "unknown"
-				if d.BatteryLevel >= 0 {
-					level = fmt.Sprintf("%d%%", d.BatteryLevel)
-				}
-				fmt.Printf("  %s: %s %s\n", d.Name, batteryBar(d.BatteryLevel), level)
+				fmt.Printf("  %s: %s\n", d.Name, batteryText(d))
 			}
 		}
 
 		for _, a := range alerts {
+			name := a.Device
+			if a.Component != "" {
+				name += " " + a.Component
+			}
 			fmt.Printf("  WARNING: %s battery at %d%% (below %d%% threshold)\n",
-				a.Device, a.BatteryLevel, a.Threshold)
+				name, a.BatteryLevel, a.Threshold)
 		}
 	}
 
 	if len(alerts) > 0 {
-		return fmt.Errorf("low battery detected on %d device(s)", len(alerts))
+		return fmt.Errorf("low battery detected on %d device(s)", lowDevices)
 	}
 
 	return nil
 }
 
+// lowBatteryAlerts returns an alert for each battery of d below threshold.
+// Devices with components (buds, case) are checked per component, so one
+// nearly empty bud is caught even when the summary level looks fine.
+func lowBatteryAlerts(d bluetooth.Device, threshold int) []BatteryAlert {
+	var alerts []BatteryAlert
+	if d.HasComponentBatteries() {
+		for _, r := range d.Batteries {
+			if r.Level < threshold {
+				alerts = append(alerts, BatteryAlert{
+					Device:       d.Name,
+					Component:    string(r.Component),
+					BatteryLevel: r.Level,
+					Threshold:    threshold,
+					Alert:        "low battery",
+				})
+			}
+		}
+		return alerts
+	}
+	if d.BatteryLevel >= 0 && d.BatteryLevel < threshold {
+		alerts = append(alerts, BatteryAlert{
+			Device:       d.Name,
+			BatteryLevel: d.BatteryLevel,
+			Threshold:    threshold,
+			Alert:        "low battery",
+		})
+	}
+	return alerts
+}
+
 func init() {
 	batteryCmd.Flags().Bool("watch", false, "Continuously monitor battery levels")
 	batteryCmd.Flags().Int("interval", 30, "Polling interval in seconds (used with --watch)")
-	batteryCmd.Flags().Int("low", 20, "Low battery threshold percentage (used with --watch)")
+	batteryCmd.Flags().Int("low", 20, "Low battery threshold percentage, checked per bud and case (used with --watch)")
 	rootCmd.AddCommand(batteryCmd)
 }
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -8,22 +9,31 @@ import (
 )
 
 var connectCmd = &cobra.Command{
-	Use:   "connect <device>",
+	Use:   "connect <device|group>",
 	Short: "Connect to a paired device",
-	Long:  "Connect to a paired Bluetooth device by name or address. Requires blueutil (brew install blueutil).",
+	Long:  "Connect to a paired Bluetooth device by name, address, or alias, or every device in a group. Needs a backend that supports device control (on macOS, blueutil: brew install blueutil).",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		device, err := bluetooth.GetDevice(args[0])
+		devices, err := resolveGroup(cmd.Context(), args[0])
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Connecting to %s (%s)...\n", device.Name, device.Address)
-		if err := bluetooth.Connect(device.Address); err != nil {
-			return err
+		// Keep going through a group so one unreachable device doesn't
+		// leave the rest untouched.
+		var errs []error
+		for _, device := range devices {
+			fmt.Printf("Connecting to %s (%s)...\n", device.Name, device.Address)
+			if err := bluetooth.Connect(cmd.Context(), device.Address); err != nil {
+				if len(devices) == 1 {
+					return err
+				}
+				errs = append(errs, fmt.Errorf("%s: %w", device.Name, err))
+				continue
+			}
+			fmt.Printf("Connected to %s.\n", device.Name)
 		}
-		fmt.Printf("Connected to %s.\n", device.Name)
-		return nil
+		return errors.Join(errs...)
 	},
 }
 
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/lu-zhengda/bltctl/internal/bluetooth"
+)
+
+var controllerCmd = &cobra.Command{
+	Use:   "controller",
+	Short: "Show Bluetooth controller info",
+	Long:  "Show the Bluetooth controllers with address, chipset, firmware, vendor and product IDs, transport, and supported services.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		controllers, err := bluetooth.Controllers(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if sel, _ := cmd.Flags().GetString("controller"); sel != "" {
+			c, err := bluetooth.SelectController(controllers, sel)
+			if err != nil {
+				return err
+			}
+			controllers = []bluetooth.Controller{*c}
+		}
+
+		if jsonFlag {
+			return printJSON(controllers)
+		}
+
+		if len(controllers) == 0 {
+			fmt.Println("No Bluetooth controller found.")
+			return nil
+		}
+		for i, c := range controllers {
+			if i > 0 {
+				fmt.Println()
+			}
+			printController(c, "")
+		}
+		return nil
+	},
+}
+
+// printController prints a controller's typed fields, each line prefixed
+// with indent.
+func printController(c bluetooth.Controller, indent string) {
+	discoverable := "no"
+	if c.Discoverable {
+		discoverable = "yes"
+	}
+	vendor := c.VendorID
+	if c.Vendor != "" && c.VendorID != "" {
+		vendor = fmt.Sprintf("%s (%s)", c.Vendor, c.VendorID)
+	}
+
+	for _, f := range []struct{ label, value string }{
+		{"Address:", c.Address},
+		{"State:", c.State},
+		{"Chipset:", valueOrDash(c.Chipset)},
+		{"Firmware:", valueOrDash(c.Firmware)},
+		{"Vendor:", valueOrDash(vendor)},
+		{"Product ID:", valueOrDash(c.ProductID)},
+		{"Transport:", valueOrDash(c.Transport)},
+		{"Discoverable:", discoverable},
+		{"Services:", valueOrDash(strings.Join(c.Services, ", "))},
+	} {
+		fmt.Printf("%s%-14s%s\n", indent, f.label, f.value)
+	}
+}
+
+func init() {
+	controllerCmd.Flags().String("controller", "", "Show only this controller (index, address, or chipset)")
+	rootCmd.AddCommand(controllerCmd)
+}
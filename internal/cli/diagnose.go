@@ -2,8 +2,9 @@ package cli
 
 import (
 	"fmt"
+	"maps"
 	"os"
-	"sort"
+	"slices"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
@@ -12,48 +13,68 @@ import (
 
 type diagResult struct {
 	bluetooth.DiagReport
-	BlueUtilInstalled bool `json:"blueutil_installed"`
+	Tools             map[string]bool `json:"tools,omitempty"`
+	BlueUtilInstalled bool            `json:"blueutil_installed"`
+}
+
+// toolHints tells the user how to install a missing tool and what it is for.
+var toolHints = map[string]string{
+	"blueutil":     "brew install blueutil for connect/disconnect",
+	"bluetoothctl": "install bluez for connect/disconnect",
 }
 
 var diagnoseCmd = &cobra.Command{
 	Use:   "diagnose",
 	Short: "Run Bluetooth diagnostics",
-	Long:  "Run a comprehensive Bluetooth diagnostic check including power state, controller info, devices, and recent errors.",
+	Long: `Run a comprehensive Bluetooth diagnostic check including power state, controller info, devices, and recent errors.
+
+--archive or --input reads the errors and disconnects from a .logarchive or
+captured log show output instead of the live log, taking the last 5
+minutes before its end.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		report, err := bluetooth.Diagnose()
+		if _, err := applyLogSource(cmd); err != nil {
+			return err
+		}
+		report, err := bluetooth.Diagnose(cmd.Context())
 		if err != nil {
 			return err
 		}
+		if controller, _ := cmd.Flags().GetString("controller"); controller != "" {
+			if report, err = report.ForController(controller); err != nil {
+				return err
+			}
+		}
 
+		tools := bluetooth.CurrentBackend().Tools(cmd.Context())
 		if jsonFlag {
 			return printJSON(diagResult{
 				DiagReport:        *report,
-				BlueUtilInstalled: bluetooth.IsBlueUtilInstalled(),
+				Tools:             tools,
+				BlueUtilInstalled: tools["blueutil"],
 			})
 		}
 
 		// Power state
 		fmt.Printf("Power State: %s\n\n", report.PowerState)
 
-		// Controller info
-		if len(report.ControllerInfo) > 0 {
-			fmt.Println("Controller Info:")
+		// Controllers, when there is more than one
+		if len(report.Controllers) > 1 {
+			fmt.Println("Controllers:")
 			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-
-			// Sort keys for stable output
-			keys := make([]string, 0, len(report.ControllerInfo))
-			for k := range report.ControllerInfo {
-				keys = append(keys, k)
-			}
-			sort.Strings(keys)
-
-			for _, k := range keys {
-				fmt.Fprintf(w, "  %s\t%s\n", k, report.ControllerInfo[k])
+			for i, c := range report.Controllers {
+				fmt.Fprintf(w, "  %d\t%s\t%s\t%s\n", i, c.Address, valueOrDash(c.Chipset), c.State)
 			}
 			w.Flush()
 			fmt.Println()
 		}
 
+		// Default (or selected) controller
+		if len(report.Controllers) > 0 {
+			fmt.Println("Controller:")
+			printController(report.Controllers[0], "  ")
+			fmt.Println()
+		}
+
 		// Connected devices
 		if len(report.ConnectedDevices) > 0 {
 			fmt.Println("Connected Devices:")
@@ -71,20 +92,41 @@ var diagnoseCmd = &cobra.Command{
 		}
 
 		// Recent errors
-		if len(report.RecentErrors) > 0 {
+		switch {
+		case report.LogUnavailable != "":
+			fmt.Printf("Recent Errors: unknown (%s)\n", report.LogUnavailable)
+		case len(report.RecentErrors) > 0:
 			fmt.Println("Recent Errors (last 5 min):")
 			for _, e := range report.RecentErrors {
 				fmt.Printf("  %s\n", e)
 			}
-		} else {
+		default:
 			fmt.Println("Recent Errors: none")
 		}
 
-		// BlueUtil availability
-		if bluetooth.IsBlueUtilInstalled() {
-			fmt.Println("\nblueutil: installed")
-		} else {
-			fmt.Println("\nblueutil: not installed (brew install blueutil for connect/disconnect)")
+		// Recent disconnects, with why they happened
+		if len(report.RecentDisconnects) > 0 {
+			fmt.Println("\nRecent Disconnects (last 5 min):")
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, ev := range report.RecentDisconnects {
+				fmt.Fprintf(w, "  %s\t%s\t%s\n", formatTime(ev.Timestamp), ev.Device, formatReason(ev))
+			}
+			w.Flush()
+		}
+
+		// Tools the backend controls devices with
+		if len(tools) > 0 {
+			fmt.Println()
+		}
+		for _, name := range slices.Sorted(maps.Keys(tools)) {
+			switch {
+			case tools[name]:
+				fmt.Printf("%s: installed\n", name)
+			case toolHints[name] != "":
+				fmt.Printf("%s: not installed (%s)\n", name, toolHints[name])
+			default:
+				fmt.Printf("%s: not installed\n", name)
+			}
 		}
 
 		return nil
@@ -92,5 +134,7 @@ var diagnoseCmd = &cobra.Command{
 }
 
 func init() {
+	diagnoseCmd.Flags().String("controller", "", "Diagnose this controller (index, address, or chipset)")
+	addLogSourceFlags(diagnoseCmd)
 	rootCmd.AddCommand(diagnoseCmd)
 }
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/lu-zhengda/bltctl/internal/bluetooth"
+)
+
+// diffResult is the JSON output of diff.
+type diffResult struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Changes []bluetooth.Change `json:"changes"`
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <a> [<b>|live]",
+	Short: "Compare two saved snapshots, or one with the live state",
+	Long: `Compare a snapshot saved with "snapshot save" against another, or against
+the live state if the second is omitted or "live". Reports devices paired
+and unpaired, connection changes, battery deltas, firmware changes, and
+controller changes.
+
+Like diff(1), exits with status 0 when the snapshots are the same, 1 when
+there are differences, and 2 on errors.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.RangeArgs(1, 2)(cmd, args); err != nil {
+			return &ExitError{Code: 2, Err: err}
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The arguments are valid; what fails from here on is not usage.
+		cmd.SilenceUsage = true
+		a, err := bluetooth.ReadSnapshotFile(args[0])
+		if err != nil {
+			return &ExitError{Code: 2, Err: err}
+		}
+		var b *bluetooth.SnapshotFile
+		if len(args) == 1 || args[1] == "live" {
+			b, err = bluetooth.CaptureSnapshotFile(cmd.Context())
+		} else {
+			b, err = bluetooth.ReadSnapshotFile(args[1])
+		}
+		if err != nil {
+			return &ExitError{Code: 2, Err: err}
+		}
+
+		changes := bluetooth.DiffSnapshots(a, b)
+		if jsonFlag {
+			if changes == nil {
+				changes = []bluetooth.Change{}
+			}
+			if err := printJSON(diffResult{From: a.Time, To: b.Time, Changes: changes}); err != nil {
+				return &ExitError{Code: 2, Err: err}
+			}
+		} else {
+			printChanges(changes)
+		}
+
+		if len(changes) > 0 {
+			cmd.SilenceErrors = true
+			return &ExitError{Code: 1}
+		}
+		return nil
+	},
+}
+
+// printChanges prints one line per change: + for something new, - for
+// something gone, and ~ for something that changed.
+func printChanges(changes []bluetooth.Change) {
+	if len(changes) == 0 {
+		fmt.Println("No differences.")
+		return
+	}
+	for _, c := range changes {
+		switch c.Kind {
+		case bluetooth.ChangePaired:
+			fmt.Printf("+ %s (%s) paired\n", c.Device, c.Address)
+		case bluetooth.ChangeUnpaired:
+			fmt.Printf("- %s (%s) unpaired\n", c.Device, c.Address)
+		case bluetooth.ChangeConnection:
+			fmt.Printf("~ %s: %s → %s\n", c.Device, c.From, c.To)
+		case bluetooth.ChangeBattery:
+			fmt.Printf("~ %s: %s battery %s → %s (%+d)\n", c.Device, c.Field, c.From, c.To, c.Delta)
+		case bluetooth.ChangeFirmware:
+			fmt.Printf("~ %s: %s %s → %s\n", c.Device, c.Field, valueOrDash(c.From), valueOrDash(c.To))
+		case bluetooth.ChangeController:
+			name := "controller"
+			if c.Address != "" {
+				name += " " + c.Address
+			}
+			switch c.Field {
+			case "added":
+				fmt.Printf("+ %s added\n", name)
+			case "removed":
+				fmt.Printf("- %s removed\n", name)
+			default:
+				fmt.Printf("~ %s: %s %s → %s\n", name, c.Field, valueOrDash(c.From), valueOrDash(c.To))
+			}
+		case bluetooth.ChangeBlueUtil:
+			fmt.Printf("~ blueutil: %s → %s\n", c.From, c.To)
+		}
+	}
+}
+
+func init() {
+	// Every failure exits 2, including bad flags and backend setup.
+	diffCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &ExitError{Code: 2, Err: err}
+	})
+	diffCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := rootCmd.PersistentPreRunE(cmd, args); err != nil {
+			return &ExitError{Code: 2, Err: err}
+		}
+		return nil
+	}
+	rootCmd.AddCommand(diffCmd)
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -8,22 +9,31 @@ import (
 )
 
 var disconnectCmd = &cobra.Command{
-	Use:   "disconnect <device>",
+	Use:   "disconnect <device|group>",
 	Short: "Disconnect a device",
-	Long:  "Disconnect a Bluetooth device by name or address. Requires blueutil (brew install blueutil).",
+	Long:  "Disconnect a Bluetooth device by name, address, or alias, or every device in a group. Needs a backend that supports device control (on macOS, blueutil: brew install blueutil).",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		device, err := bluetooth.GetDevice(args[0])
+		devices, err := resolveGroup(cmd.Context(), args[0])
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Disconnecting %s (%s)...\n", device.Name, device.Address)
-		if err := bluetooth.Disconnect(device.Address); err != nil {
-			return err
+		// Keep going through a group so one unreachable device doesn't
+		// leave the rest untouched.
+		var errs []error
+		for _, device := range devices {
+			fmt.Printf("Disconnecting %s (%s)...\n", device.Name, device.Address)
+			if err := bluetooth.Disconnect(cmd.Context(), device.Address); err != nil {
+				if len(devices) == 1 {
+					return err
+				}
+				errs = append(errs, fmt.Errorf("%s: %w", device.Name, err))
+				continue
+			}
+			fmt.Printf("Disconnected %s.\n", device.Name)
 		}
-		fmt.Printf("Disconnected %s.\n", device.Name)
-		return nil
+		return errors.Join(errs...)
 	},
 }
 
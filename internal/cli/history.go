@@ -1,9 +1,14 @@
 package cli
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/lu-zhengda/bltctl/internal/bluetooth"
@@ -12,40 +17,459 @@ import (
 var historyCmd = &cobra.Command{
 	Use:   "history",
 	Short: "Show Bluetooth connect/disconnect events",
-	Long:  "Show recent Bluetooth connect/disconnect events from the system log.",
+	Long: `Show recent Bluetooth connect/disconnect events from the system log.
+
+The window is the last --last of the log, or the range from --since to
+--until (e.g. "2024-07-15", "2024-07-15 09:30", or RFC 3339). --format
+csv and ndjson export the events for spreadsheets and scripts.
+
+--sessions groups the events into per-device connection sessions, and
+--summary totals them per device (connected time, sessions, median session
+length, and link-loss drops). --follow prints events live as they are
+logged until interrupted; with --json, one JSON object per line.
+
+--anomalies scores each device's stability (the percentage of sessions
+that were not short, dropped, or flapping) and lists the offending
+windows: more than --max-cycles reconnects within --cycle-window,
+sessions shorter than --min-session, and more than --max-link-losses
+link losses within --link-loss-window.
+
+--archive reads a .logarchive, such as one from a sysdiagnose bundle, and
+--input reads captured log show output (compact or ndjson) from a file or
+stdin, so another machine's events can be analyzed on any system. --last
+then counts back from the end of that log, and sessions still open there
+end with its last event.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		duration, _ := cmd.Flags().GetString("last")
+		sessions, _ := cmd.Flags().GetBool("sessions")
+		summary, _ := cmd.Flags().GetBool("summary")
+		follow, _ := cmd.Flags().GetBool("follow")
+		anomalies, _ := cmd.Flags().GetBool("anomalies")
+		if (sessions && summary) || (anomalies && (sessions || summary)) {
+			return fmt.Errorf("--sessions, --summary, and --anomalies cannot be used together")
+		}
+		if follow && (sessions || summary || anomalies) {
+			return fmt.Errorf("--follow cannot be combined with --sessions, --summary, or --anomalies")
+		}
 
-		events, err := bluetooth.FetchHistory(duration)
+		format, err := historyFormat(cmd)
 		if err != nil {
 			return err
 		}
+		if (sessions || summary || anomalies) && (format == "csv" || format == "ndjson") {
+			return fmt.Errorf("--format %s applies to events, not --sessions, --summary, or --anomalies", format)
+		}
+		thresholds, err := anomalyThresholds(cmd)
+		if err != nil {
+			return err
+		}
+		live, err := applyLogSource(cmd)
+		if err != nil {
+			return err
+		}
+		if follow && !live {
+			return fmt.Errorf("--follow cannot be combined with --archive or --input")
+		}
+		query, err := historyQuery(cmd, follow)
+		if err != nil {
+			return err
+		}
+		filter, err := historyFilter(cmd, live)
+		if err != nil {
+			return err
+		}
+		if follow {
+			return followHistory(cmd.Context(), filter, format)
+		}
 
-		if jsonFlag {
-			return printJSON(events)
+		events, err := bluetooth.FetchHistory(cmd.Context(), query)
+		if err != nil {
+			return err
 		}
+		// Sessions still open in another machine's log end with the log.
+		now := bluetooth.Now()
+		if !live {
+			now = bluetooth.LastEventTime(events)
+		}
+		events = filter.Filter(events)
 
-		if len(events) == 0 {
-			fmt.Println("No Bluetooth events found.")
-			return nil
+		switch {
+		case sessions:
+			return printSessions(bluetooth.BuildSessions(events, now))
+		case summary:
+			return printSummary(bluetooth.SummarizeSessions(bluetooth.BuildSessions(events, now)))
+		case anomalies:
+			return printAnomalies(bluetooth.DetectAnomalies(bluetooth.BuildSessions(events, now), thresholds))
 		}
+		return printEvents(events, format)
+	},
+}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "TIMESTAMP\tEVENT\tDEVICE")
+// historyFormat returns the --format value, with --json meaning "json".
+func historyFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("format")
+	switch format {
+	case "table", "json", "csv", "ndjson":
+	default:
+		return "", fmt.Errorf("invalid --format %q (use table, json, csv, or ndjson)", format)
+	}
+	if jsonFlag {
+		if cmd.Flags().Changed("format") && format != "json" {
+			return "", fmt.Errorf("--json cannot be combined with --format %s", format)
+		}
+		format = "json"
+	}
+	return format, nil
+}
+
+// anomalyThresholds builds the --anomalies thresholds from their flags.
+func anomalyThresholds(cmd *cobra.Command) (bluetooth.AnomalyThresholds, error) {
+	var t bluetooth.AnomalyThresholds
+	t.MaxCycles, _ = cmd.Flags().GetInt("max-cycles")
+	t.CycleWindow, _ = cmd.Flags().GetDuration("cycle-window")
+	t.MinSession, _ = cmd.Flags().GetDuration("min-session")
+	t.MaxLinkLosses, _ = cmd.Flags().GetInt("max-link-losses")
+	t.LinkLossWindow, _ = cmd.Flags().GetDuration("link-loss-window")
+	return t, t.Validate()
+}
+
+// historyQuery builds the log window from --last, --since, and --until.
+// Following has no window.
+func historyQuery(cmd *cobra.Command, follow bool) (bluetooth.LogQuery, error) {
+	last, _ := cmd.Flags().GetString("last")
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+
+	var q bluetooth.LogQuery
+	switch {
+	case follow && (since != "" || until != "" || cmd.Flags().Changed("last")):
+		return q, fmt.Errorf("--follow cannot be combined with --last, --since, or --until")
+	case follow:
+		return q, nil
+	case since != "" && cmd.Flags().Changed("last"):
+		return q, fmt.Errorf("--last cannot be combined with --since")
+	case since == "" && until != "":
+		return q, fmt.Errorf("--until requires --since")
+	case since == "":
+		q.Last = last
+		return q, q.Validate()
+	}
+
+	var err error
+	if q.Since, err = parseTimeFlag("--since", since); err != nil {
+		return q, err
+	}
+	if until != "" {
+		if q.Until, err = parseTimeFlag("--until", until); err != nil {
+			return q, err
+		}
+	}
+	return q, q.Validate()
+}
+
+// timeFlagLayouts are the layouts --since and --until accept, in local
+// time unless the value carries an offset.
+var timeFlagLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	time.DateTime,
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+// parseTimeFlag parses an absolute timestamp given to flag.
+func parseTimeFlag(flag, value string) (time.Time, error) {
+	for _, layout := range timeFlagLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q (use e.g. 2024-07-15, \"2024-07-15 09:30\", or 2024-07-15T09:30:00Z)", flag, value)
+}
+
+// historyFilter builds the event filter from --device and --type. For the
+// live log the device is resolved through the paired devices; one that is
+// not paired any more, or that cannot be looked up, is matched by the name
+// or address given. Another machine's log is always matched literally, as
+// this machine's paired devices say nothing about it.
+func historyFilter(cmd *cobra.Command, live bool) (bluetooth.HistoryFilter, error) {
+	device, _ := cmd.Flags().GetString("device")
+	eventType, _ := cmd.Flags().GetString("type")
+
+	filter := bluetooth.HistoryFilter{EventType: eventType}
+	switch eventType {
+	case "", "connected", "disconnected", "link-loss":
+	default:
+		return filter, fmt.Errorf("invalid --type %q (use connected, disconnected, or link-loss)", eventType)
+	}
+	if device == "" {
+		return filter, nil
+	}
+
+	literal := bluetooth.CurrentConfig().Expand(device)
+	filter.Name, filter.Address = literal, literal
+	if !live {
+		return filter, nil
+	}
+	d, err := bluetooth.GetDevice(cmd.Context(), device)
+	var ambiguous *bluetooth.AmbiguousDeviceError
+	switch {
+	case err == nil:
+		filter.Name, filter.Address = d.Name, d.Address
+	case errors.As(err, &ambiguous):
+		return filter, err
+	}
+	return filter, nil
+}
 
+// printEvents prints events as a table, a JSON array, CSV, or NDJSON.
+func printEvents(events []bluetooth.HistoryEvent, format string) error {
+	switch format {
+	case "json":
+		return printJSON(events)
+	case "csv", "ndjson":
+		p := newEventPrinter(format)
 		for _, ev := range events {
-			ts := ""
-			if !ev.Timestamp.IsZero() {
-				ts = ev.Timestamp.Format("2006-01-02 15:04:05")
+			if err := p.print(ev); err != nil {
+				return err
 			}
-			fmt.Fprintf(w, "%s\t%s\t%s\n", ts, ev.EventType, ev.Device)
 		}
+		return nil
+	}
 
-		return w.Flush()
-	},
+	if len(events) == 0 {
+		fmt.Println("No Bluetooth events found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIMESTAMP\tEVENT\tDEVICE\tADDRESS\tREASON")
+
+	for _, ev := range events {
+		ts := ""
+		if !ev.Timestamp.IsZero() {
+			ts = ev.Timestamp.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ts, ev.EventType, ev.Device, eventAddress(ev), formatReason(ev))
+	}
+
+	return w.Flush()
+}
+
+// eventPrinter writes events one at a time, so output keeps up with
+// --follow: fixed-width table rows, CSV records, or JSON lines.
+type eventPrinter struct {
+	format string
+	csv    *csv.Writer
+	json   *json.Encoder
+}
+
+// eventCSVHeader names the columns of CSV output.
+var eventCSVHeader = []string{"timestamp", "event_type", "device", "address", "reason", "reason_code", "process", "category", "message"}
+
+// newEventPrinter returns a printer for format and writes its header.
+// "json" prints JSON lines, as an array cannot be streamed.
+func newEventPrinter(format string) *eventPrinter {
+	p := &eventPrinter{format: format}
+	switch format {
+	case "csv":
+		p.csv = csv.NewWriter(os.Stdout)
+		p.csv.Write(eventCSVHeader)
+		p.csv.Flush()
+	case "json", "ndjson":
+		p.json = json.NewEncoder(os.Stdout)
+	default:
+		fmt.Printf("%-19s  %-12s  %-24s  %-17s  %s\n", "TIMESTAMP", "EVENT", "DEVICE", "ADDRESS", "REASON")
+	}
+	return p
+}
+
+// print writes one event.
+func (p *eventPrinter) print(ev bluetooth.HistoryEvent) error {
+	switch {
+	case p.csv != nil:
+		ts := ""
+		if !ev.Timestamp.IsZero() {
+			ts = ev.Timestamp.Format(time.RFC3339)
+		}
+		code := ""
+		if ev.ReasonCode != 0 {
+			code = fmt.Sprintf("0x%02x", ev.ReasonCode)
+		}
+		p.csv.Write([]string{ts, ev.EventType, ev.Device, ev.Address, string(ev.Reason), code, ev.Process, ev.Category, ev.RawLine})
+		p.csv.Flush()
+		if err := p.csv.Error(); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	case p.json != nil:
+		if err := p.json.Encode(ev); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+	default:
+		fmt.Printf("%-19s  %-12s  %-24s  %-17s  %s\n", formatTime(ev.Timestamp), ev.EventType, ev.Device, eventAddress(ev), formatReason(ev))
+	}
+	return nil
+}
+
+// followHistory prints events matching filter as they are logged. It stops
+// cleanly when ctx is cancelled (SIGINT/SIGTERM).
+func followHistory(ctx context.Context, filter bluetooth.HistoryFilter, format string) error {
+	p := newEventPrinter(format)
+	var printErr error
+	err := bluetooth.FollowHistory(ctx, func(ev bluetooth.HistoryEvent) {
+		if printErr == nil && filter.Match(ev) {
+			printErr = p.print(ev)
+		}
+	})
+	if ctx.Err() == context.Canceled {
+		return printErr
+	}
+	return errors.Join(err, printErr)
+}
+
+// eventAddress returns the address column for an event, or "-" if the
+// event has none beyond its device column.
+func eventAddress(ev bluetooth.HistoryEvent) string {
+	if ev.Address == "" || ev.Address == ev.Device {
+		return "-"
+	}
+	return ev.Address
+}
+
+// formatReason formats why a disconnect happened, with its HCI code if
+// logged, e.g. "connection-timeout (0x08)", or "-" if unknown.
+func formatReason(ev bluetooth.HistoryEvent) string {
+	switch {
+	case ev.Reason == "":
+		return "-"
+	case ev.ReasonCode != 0:
+		return fmt.Sprintf("%s (0x%02x)", ev.Reason, ev.ReasonCode)
+	default:
+		return string(ev.Reason)
+	}
+}
+
+// printSessions prints one line per connection session.
+func printSessions(sessions []bluetooth.Session) error {
+	if jsonFlag {
+		return printJSON(sessions)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No Bluetooth sessions found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tSTART\tEND\tDURATION\tENDED BY")
+	for _, s := range sessions {
+		duration := "-"
+		if !s.Start.IsZero() {
+			duration = formatDuration(s.Duration)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			s.Device, formatTime(s.Start), formatTime(s.End), duration, s.EndedBy)
+	}
+	return w.Flush()
+}
+
+// printSummary prints one line per device with its session totals.
+func printSummary(summaries []bluetooth.DeviceSummary) error {
+	if jsonFlag {
+		return printJSON(summaries)
+	}
+	if len(summaries) == 0 {
+		fmt.Println("No Bluetooth sessions found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tCONNECTED\tSESSIONS\tMEDIAN\tDROPS")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\n",
+			s.Device, formatDuration(s.Connected), s.Sessions, formatDuration(s.Median), s.Drops)
+	}
+	return w.Flush()
+}
+
+// printAnomalies prints each device's stability score followed by its
+// offending windows, least stable first.
+func printAnomalies(devices []bluetooth.DeviceStability) error {
+	if jsonFlag {
+		return printJSON(devices)
+	}
+	if len(devices) == 0 {
+		fmt.Println("No Bluetooth sessions found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tSCORE\tSESSIONS\tANOMALIES")
+	for _, d := range devices {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", d.Device, d.Score, d.Sessions, len(d.Anomalies))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, d := range devices {
+		if len(d.Anomalies) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s:\n", d.Device)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, a := range d.Anomalies {
+			fmt.Fprintf(w, "  %s\t%s – %s\t%s\n", a.Kind, formatTime(a.Start), formatTime(a.End), anomalyCount(a))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// anomalyCount describes what an anomaly counted, e.g. "5 cycles".
+func anomalyCount(a bluetooth.Anomaly) string {
+	one, many := "cycle", "cycles"
+	switch a.Kind {
+	case bluetooth.AnomalyShortSessions:
+		one, many = "session", "sessions"
+	case bluetooth.AnomalyLinkLossBurst:
+		one, many = "link loss", "link losses"
+	}
+	if a.Count == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", a.Count, many)
+}
+
+// formatTime formats a log timestamp, or "-" if it is unknown.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// formatDuration formats a duration to the second, e.g. "1h2m5s".
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
 }
 
 func init() {
 	historyCmd.Flags().String("last", "24h", "Time range to search (e.g. 1h, 30m, 7d)")
+	historyCmd.Flags().Bool("sessions", false, "Group events into per-device connection sessions")
+	historyCmd.Flags().Bool("summary", false, "Summarize sessions per device")
+	historyCmd.Flags().Bool("follow", false, "Print events live as they are logged")
+	historyCmd.Flags().String("device", "", "Only show events for this device (name, address, or alias)")
+	historyCmd.Flags().String("type", "", "Only show events of this type (connected, disconnected, or link-loss)")
+	historyCmd.Flags().String("since", "", "Start of an absolute time range (e.g. 2024-07-15 or \"2024-07-15 09:30\")")
+	historyCmd.Flags().String("until", "", "End of an absolute time range (default now)")
+	historyCmd.Flags().String("format", "table", "Output format: table, json, csv, or ndjson")
+	historyCmd.Flags().Bool("anomalies", false, "Score each device's stability and list flapping, short sessions, and link-loss bursts")
+	historyCmd.Flags().Int("max-cycles", bluetooth.DefaultAnomalyThresholds.MaxCycles, "Flag more reconnects than this within --cycle-window")
+	historyCmd.Flags().Duration("cycle-window", bluetooth.DefaultAnomalyThresholds.CycleWindow, "Window for --max-cycles")
+	historyCmd.Flags().Duration("min-session", bluetooth.DefaultAnomalyThresholds.MinSession, "Flag sessions shorter than this")
+	historyCmd.Flags().Int("max-link-losses", bluetooth.DefaultAnomalyThresholds.MaxLinkLosses, "Flag more link losses than this within --link-loss-window")
+	historyCmd.Flags().Duration("link-loss-window", bluetooth.DefaultAnomalyThresholds.LinkLossWindow, "Window for --max-link-losses")
+	addLogSourceFlags(historyCmd)
 	rootCmd.AddCommand(historyCmd)
 }
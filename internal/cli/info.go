@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/lu-zhengda/bltctl/internal/bluetooth"
@@ -13,7 +15,12 @@ var infoCmd = &cobra.Command{
 	Long:  "Show detailed information for a specific Bluetooth device by name or address.",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		device, err := bluetooth.GetDevice(args[0])
+		controller, _ := cmd.Flags().GetString("controller")
+		devices, err := controllerDevices(cmd.Context(), controller)
+		if err != nil {
+			return err
+		}
+		device, err := resolveAmong(devices, args[0])
 		if err != nil {
 			return err
 		}
@@ -30,24 +37,75 @@ var infoCmd = &cobra.Command{
 		fmt.Printf("Name:       %s\n", device.Name)
 		fmt.Printf("Address:    %s\n", device.Address)
 		fmt.Printf("Type:       %s\n", valueOrDash(device.MinorType))
+		if device.Product != nil {
+			fmt.Printf("Model:      %s\n", valueOrDash(productModel(device.Product)))
+			if caps := device.Product.Capabilities(); len(caps) > 0 {
+				fmt.Printf("Features:   %s\n", strings.Join(caps, ", "))
+			}
+		}
 		fmt.Printf("Status:     %s\n", status)
-
-		if device.BatteryLevel >= 0 {
-			fmt.Printf("Battery:    %s %d%%\n", batteryBar(device.BatteryLevel), device.BatteryLevel)
-		} else {
-			fmt.Printf("Battery:    -\n")
+		if device.Controller != "" {
+			fmt.Printf("Controller: %s\n", device.Controller)
 		}
 
+		fmt.Printf("Battery:    %s\n", batteryText(*device))
+
 		if device.RSSI != 0 {
 			fmt.Printf("RSSI:       %d dBm\n", device.RSSI)
 		} else {
 			fmt.Printf("RSSI:       -\n")
 		}
 
+		printDetails(device.Details)
 		return nil
 	},
 }
 
+// printDetails prints the extended properties reported for a device, if any.
+func printDetails(d *bluetooth.DeviceDetails) {
+	if d == nil {
+		return
+	}
+	for _, f := range []struct{ label, value string }{
+		{"Firmware:", d.FirmwareVersion},
+		{"Case FW:", d.CaseFirmwareVersion},
+		{"Vendor ID:", d.VendorID},
+		{"Product ID:", d.ProductID},
+		{"Serial:", d.SerialNumber},
+		{"Serial L:", d.SerialNumberLeft},
+		{"Serial R:", d.SerialNumberRight},
+		{"Services:", strings.Join(d.Services, ", ")},
+	} {
+		if f.value != "" {
+			fmt.Printf("%-12s%s\n", f.label, f.value)
+		}
+	}
+
+	if len(d.Extra) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(d.Extra))
+	for k := range d.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Println("Other:")
+	for _, k := range keys {
+		fmt.Printf("  %s: %s\n", k, d.Extra[k])
+	}
+}
+
+// productModel returns the model name, falling back to the vendor name.
+func productModel(p *bluetooth.ProductInfo) string {
+	if p == nil {
+		return ""
+	}
+	if p.Model == "" {
+		return p.Vendor
+	}
+	return p.Model
+}
+
 func valueOrDash(s string) string {
 	if s == "" {
 		return "-"
@@ -56,5 +114,6 @@ func valueOrDash(s string) string {
 }
 
 func init() {
+	infoCmd.Flags().String("controller", "", "Only match devices on this controller (index, address, or chipset)")
 	rootCmd.AddCommand(infoCmd)
 }
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"text/tabwriter"
@@ -14,7 +15,8 @@ var listCmd = &cobra.Command{
 	Short: "List paired Bluetooth devices",
 	Long:  "List all paired Bluetooth devices with name, type, connection status, and battery level.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		devices, err := bluetooth.ListDevices()
+		controller, _ := cmd.Flags().GetString("controller")
+		devices, err := controllerDevices(cmd.Context(), controller)
 		if err != nil {
 			return err
 		}
@@ -28,8 +30,14 @@ var listCmd = &cobra.Command{
 			return nil
 		}
 
+		wide, _ := cmd.Flags().GetBool("wide")
+
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "STATUS\tNAME\tTYPE\tADDRESS\tBATTERY")
+		if wide {
+			fmt.Fprintln(w, "STATUS\tNAME\tTYPE\tMODEL\tADDRESS\tFIRMWARE\tBATTERY")
+		} else {
+			fmt.Fprintln(w, "STATUS\tNAME\tTYPE\tADDRESS\tBATTERY")
+		}
 
 		for _, d := range devices {
 			status := "\u25cb"
@@ -42,19 +50,54 @@ var listCmd = &cobra.Command{
 				deviceType = "-"
 			}
 
-			battery := "-"
-			if d.BatteryLevel >= 0 {
-				battery = fmt.Sprintf("%s %d%%", batteryBar(d.BatteryLevel), d.BatteryLevel)
+			if wide {
+				firmware := ""
+				if d.Details != nil {
+					firmware = d.Details.FirmwareVersion
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					status, d.Name, deviceType, valueOrDash(productModel(d.Product)),
+					d.Address, valueOrDash(firmware), batteryText(d))
+				continue
 			}
-
 			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-				status, d.Name, deviceType, d.Address, battery)
+				status, d.Name, deviceType, d.Address, batteryText(d))
 		}
 
 		return w.Flush()
 	},
 }
 
+// controllerDevices lists the paired devices, limited to the controller
+// matching sel when it is non-empty.
+func controllerDevices(ctx context.Context, sel string) ([]bluetooth.Device, error) {
+	devices, err := bluetooth.ListDevices(ctx)
+	if err != nil || sel == "" {
+		return devices, err
+	}
+	controllers, err := bluetooth.Controllers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := bluetooth.SelectController(controllers, sel)
+	if err != nil {
+		return nil, err
+	}
+	return bluetooth.DevicesOn(devices, c.Address), nil
+}
+
+// batteryText renders a device's battery as "L 100% R 40% C 77%" when it
+// reports components, as a bar and percentage otherwise, or "-" if unknown.
+func batteryText(d bluetooth.Device) string {
+	if d.HasComponentBatteries() {
+		return bluetooth.FormatBatteries(d.Batteries)
+	}
+	if d.BatteryLevel >= 0 {
+		return fmt.Sprintf("%s %d%%", batteryBar(d.BatteryLevel), d.BatteryLevel)
+	}
+	return "-"
+}
+
 // batteryBar returns a visual bar representation of battery level.
 func batteryBar(level int) string {
 	const barLen = 10
@@ -71,5 +114,7 @@ func batteryBar(level int) string {
 }
 
 func init() {
+	listCmd.Flags().Bool("wide", false, "Show model and firmware columns")
+	listCmd.Flags().String("controller", "", "Only show devices on this controller (index, address, or chipset)")
 	rootCmd.AddCommand(listCmd)
 }
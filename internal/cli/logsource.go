@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/lu-zhengda/bltctl/internal/bluetooth"
+)
+
+// addLogSourceFlags adds --archive and --input to a command that reads
+// the system log.
+func addLogSourceFlags(cmd *cobra.Command) {
+	cmd.Flags().String("archive", "", "Read a .logarchive (e.g. from a sysdiagnose) instead of the live log")
+	cmd.Flags().String("input", "", "Read captured log show output (compact or ndjson) from a file, or - for stdin")
+}
+
+// applyLogSource points the log reads at --archive or --input, if given,
+// and reports whether the live system log is still used.
+func applyLogSource(cmd *cobra.Command) (bool, error) {
+	archive, _ := cmd.Flags().GetString("archive")
+	input, _ := cmd.Flags().GetString("input")
+
+	var src bluetooth.LogSource
+	switch {
+	case archive != "" && input != "":
+		return false, fmt.Errorf("--archive and --input cannot be combined")
+	case input == "-" && fromFileFlag == "-":
+		return false, fmt.Errorf("--from-file - and --input - cannot both read stdin")
+	case archive != "":
+		if _, err := os.Stat(archive); err != nil {
+			return false, fmt.Errorf("failed to open archive: %w", err)
+		}
+		src.Archive = archive
+	case input == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return false, fmt.Errorf("failed to read log from stdin: %w", err)
+		}
+		src.Input = data
+	case input != "":
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return false, fmt.Errorf("failed to read log: %w", err)
+		}
+		src.Input = data
+	}
+	bluetooth.SetLogSource(src)
+	return src.IsLive(), nil
+}
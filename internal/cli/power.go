@@ -16,13 +16,13 @@ var powerCmd = &cobra.Command{
 		switch args[0] {
 		case "on":
 			fmt.Println("Turning Bluetooth on...")
-			if err := bluetooth.PowerOn(); err != nil {
+			if err := bluetooth.PowerOn(cmd.Context()); err != nil {
 				return err
 			}
 			fmt.Println("Bluetooth is now on.")
 		case "off":
 			fmt.Println("Turning Bluetooth off...")
-			if err := bluetooth.PowerOff(); err != nil {
+			if err := bluetooth.PowerOff(cmd.Context()); err != nil {
 				return err
 			}
 			fmt.Println("Bluetooth is now off.")
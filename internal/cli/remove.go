@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/lu-zhengda/bltctl/internal/bluetooth"
@@ -10,16 +11,34 @@ import (
 var removeCmd = &cobra.Command{
 	Use:   "remove <device>",
 	Short: "Unpair a device",
-	Long:  "Unpair a Bluetooth device by name or address. Requires blueutil (brew install blueutil).",
-	Args:  cobra.ExactArgs(1),
+	Long: `Unpair a Bluetooth device by name or address. Needs a backend that
+supports device control (on macOS, blueutil: brew install blueutil).
+
+Unless the device is given by its exact name or full address, or by an
+alias for either, bltctl shows the device it matched and asks before
+unpairing it.
+--yes skips the question for scripts.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		device, err := bluetooth.GetDevice(args[0])
+		device, err := resolveDevice(cmd.Context(), args[0])
 		if err != nil {
 			return err
 		}
 
+		yes, _ := cmd.Flags().GetBool("yes")
+		// An alias is only as exact as its target.
+		query := bluetooth.CurrentConfig().Expand(args[0])
+		if !yes && !bluetooth.ExactMatch(*device, query) {
+			if !stdinIsTerminal() {
+				return fmt.Errorf("%q matched %s (%s) only loosely; give its exact name or address, or pass --yes", args[0], device.Name, device.Address)
+			}
+			if !confirmDevice(os.Stdin, os.Stderr, "Remove", device) {
+				return fmt.Errorf("remove cancelled")
+			}
+		}
+
 		fmt.Printf("Removing %s (%s)...\n", device.Name, device.Address)
-		if err := bluetooth.Remove(device.Address); err != nil {
+		if err := bluetooth.Remove(cmd.Context(), device.Address); err != nil {
 			return err
 		}
 		fmt.Printf("Removed %s.\n", device.Name)
@@ -28,5 +47,6 @@ var removeCmd = &cobra.Command{
 }
 
 func init() {
+	removeCmd.Flags().BoolP("yes", "y", false, "Remove without asking when the device matched only loosely")
 	rootCmd.AddCommand(removeCmd)
 }
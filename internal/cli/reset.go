@@ -13,7 +13,7 @@ var resetCmd = &cobra.Command{
 	Long:  "Reset the Bluetooth module by killing bluetoothd (auto-restarts). Requires sudo.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("Resetting Bluetooth module...")
-		if err := bluetooth.Reset(); err != nil {
+		if err := bluetooth.Reset(cmd.Context()); err != nil {
 			return err
 		}
 		fmt.Println("Bluetooth module reset. It may take a moment to reconnect devices.")
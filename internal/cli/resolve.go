@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lu-zhengda/bltctl/internal/bluetooth"
+)
+
+// resolveDevice resolves an alias, name, or address among all paired devices.
+func resolveDevice(ctx context.Context, query string) (*bluetooth.Device, error) {
+	devices, err := bluetooth.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resolveAmong(devices, query)
+}
+
+// resolveGroup resolves a group from the config file to its members, or
+// anything else to the single device it names.
+func resolveGroup(ctx context.Context, query string) ([]bluetooth.Device, error) {
+	if _, ok := bluetooth.CurrentConfig().Group(query); ok {
+		return bluetooth.GetDevices(ctx, query)
+	}
+	device, err := resolveDevice(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return []bluetooth.Device{*device}, nil
+}
+
+// resolveAmong resolves an alias, name, or address among devices. When
+// several devices match and stdin is a terminal, the user picks one;
+// otherwise the *bluetooth.AmbiguousDeviceError is returned.
+func resolveAmong(devices []bluetooth.Device, query string) (*bluetooth.Device, error) {
+	device, err := bluetooth.FindDevice(devices, bluetooth.CurrentConfig().Expand(query))
+	var ambiguous *bluetooth.AmbiguousDeviceError
+	if !errors.As(err, &ambiguous) || !stdinIsTerminal() {
+		return device, err
+	}
+	return pickDevice(os.Stdin, os.Stderr, ambiguous)
+}
+
+// pickDevice asks the user to choose one of the ambiguous candidates.
+func pickDevice(in io.Reader, out io.Writer, ambiguous *bluetooth.AmbiguousDeviceError) (*bluetooth.Device, error) {
+	fmt.Fprintf(out, "%q matches %d devices:\n", ambiguous.Query, len(ambiguous.Candidates))
+	for i, d := range ambiguous.Candidates {
+		status := "disconnected"
+		if d.Connected {
+			status = "connected"
+		}
+		fmt.Fprintf(out, "  %d) %s (%s, %s)\n", i+1, d.Name, d.Address, status)
+	}
+	fmt.Fprintf(out, "Select a device [1-%d]: ", len(ambiguous.Candidates))
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, ambiguous
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(ambiguous.Candidates) {
+		return nil, fmt.Errorf("invalid selection %q: %w", strings.TrimSpace(line), ambiguous)
+	}
+	return &ambiguous.Candidates[n-1], nil
+}
+
+// confirmDevice asks the user whether to go ahead with action on device.
+// Anything but "y" or "yes" declines.
+func confirmDevice(in io.Reader, out io.Writer, action string, device *bluetooth.Device) bool {
+	fmt.Fprintf(out, "%s %s (%s)? [y/N] ", action, device.Name, device.Address)
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
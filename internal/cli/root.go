@@ -1,26 +1,56 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
+	"github.com/lu-zhengda/bltctl/internal/bluetooth"
 	"github.com/lu-zhengda/bltctl/internal/tui"
 )
 
 var (
 	// version is set via ldflags at build time.
 	version = "dev"
+
+	backendFlag  string
+	scenarioFlag string
+	recordFlag   string
+	replayFlag   string
+	timeoutFlag  time.Duration
+	cacheTTLFlag time.Duration
+	noStoreFlag  bool
+	fromFileFlag string
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "bltctl",
-	Short: "macOS Bluetooth manager",
-	Long: `bltctl is a macOS Bluetooth manager — browse, connect, and manage
+	Short: "Bluetooth manager for macOS and Linux",
+	Long: `bltctl is a Bluetooth manager for macOS and Linux — browse, connect, and manage
 Bluetooth devices with a live-updating TUI or handy CLI subcommands.
 Launch without subcommands for interactive TUI mode.`,
 	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadProductOverrides(); err != nil {
+			return err
+		}
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		if err := selectBackend(); err != nil {
+			return err
+		}
+		openStore()
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if shell, _ := cmd.Flags().GetString("generate-completion"); shell != "" {
 			switch shell {
@@ -34,15 +64,191 @@ Launch without subcommands for interactive TUI mode.`,
 				return fmt.Errorf("unsupported shell: %s (use bash, zsh, or fish)", shell)
 			}
 		}
-		p := tea.NewProgram(tui.New(version), tea.WithAltScreen())
+		opts := []tea.ProgramOption{tea.WithAltScreen()}
+		if fromFileFlag == "-" {
+			// stdin held the document; read keys from the terminal.
+			opts = append(opts, tea.WithInputTTY())
+		}
+		p := tea.NewProgram(tui.New(version), opts...)
 		_, err := p.Run()
 		return err
 	},
 }
 
-// Execute runs the root command.
+// selectBackend applies --from-file or --backend, falling back to
+// $BLTCTL_BACKEND and then the config file's backend.
+func selectBackend() error {
+	b, err := newBackend()
+	if err != nil {
+		return err
+	}
+	bluetooth.SetBackend(b)
+
+	if timeoutFlag < 0 {
+		return fmt.Errorf("--timeout must not be negative")
+	}
+	bluetooth.SetTimeout(timeoutFlag)
+
+	if cacheTTLFlag < 0 {
+		return fmt.Errorf("--cache-ttl must not be negative")
+	}
+	bluetooth.SetSnapshotTTL(cacheTTLFlag)
+	return nil
+}
+
+// newBackend creates the backend the flags, environment, and config file
+// select.
+func newBackend() (bluetooth.Backend, error) {
+	if fromFileFlag != "" {
+		return newFileBackend()
+	}
+	name := bluetooth.BackendName(backendFlag, os.Getenv("BLTCTL_BACKEND"), bluetooth.CurrentConfig())
+	opts := bluetooth.BackendOptions{Scenario: scenarioFlag}
+	if scenarioFlag != "" && name != "sim" {
+		return nil, fmt.Errorf("--scenario requires --backend=sim")
+	}
+	switch {
+	case recordFlag != "" && replayFlag != "":
+		return nil, fmt.Errorf("--record and --replay cannot be combined")
+	case recordFlag != "":
+		rec, err := bluetooth.NewRecorder(recordFlag)
+		if err != nil {
+			return nil, err
+		}
+		opts.Runner = rec.Runner(bluetooth.ExecRunner)
+		opts.Streamer = rec.Streamer(bluetooth.ExecStreamer)
+		opts.LookPath = rec.LookPath(nil)
+	case replayFlag != "":
+		interactions, err := bluetooth.LoadInteractions(replayFlag)
+		if err != nil {
+			return nil, err
+		}
+		opts.Runner = bluetooth.ReplayInteractions(interactions)
+		opts.LookPath = bluetooth.ReplayLookPath(interactions)
+	}
+	return bluetooth.NewBackend(name, opts)
+}
+
+// newFileBackend serves devices from the system_profiler document given
+// to --from-file, or stdin for "-".
+func newFileBackend() (bluetooth.Backend, error) {
+	if backendFlag != "" || scenarioFlag != "" || recordFlag != "" || replayFlag != "" {
+		return nil, fmt.Errorf("--from-file cannot be combined with --backend, --scenario, --record, or --replay")
+	}
+	var data []byte
+	var err error
+	if fromFileFlag == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fromFileFlag)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --from-file: %w", err)
+	}
+	return bluetooth.NewFileBackend(data)
+}
+
+// configDir returns bltctl's configuration directory,
+// $XDG_CONFIG_HOME/bltctl or ~/.config/bltctl.
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "bltctl")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "bltctl")
+}
+
+// dataDir returns bltctl's data directory,
+// $XDG_DATA_HOME/bltctl or ~/.local/share/bltctl.
+func dataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "bltctl")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "share", "bltctl")
+}
+
+// openStore records what this run observes in the store in the data
+// directory. Simulated, replayed, and --from-file devices are not recorded. The store is
+// best-effort: if it cannot be opened, bltctl runs without it.
+func openStore() {
+	dir := dataDir()
+	if noStoreFlag || dir == "" || replayFlag != "" || fromFileFlag != "" || bluetooth.CurrentBackend().Name() == "sim" {
+		return
+	}
+	store, err := bluetooth.OpenStore(filepath.Join(dir, "store"))
+	if err != nil {
+		return
+	}
+	_ = store.Maintain(time.Now())
+	bluetooth.SetStore(store)
+}
+
+// loadProductOverrides merges products.yaml from the config directory, if
+// present, over the built-in product database.
+func loadProductOverrides() error {
+	dir := configDir()
+	if dir == "" {
+		return nil
+	}
+	path := filepath.Join(dir, "products.yaml")
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	return bluetooth.LoadProductOverrides(path)
+}
+
+// loadConfig reads aliases, groups, and the backend from config.yaml in
+// the config directory, if present.
+func loadConfig() error {
+	dir := configDir()
+	if dir == "" {
+		return nil
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	cfg, err := bluetooth.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	bluetooth.SetConfig(cfg)
+	return nil
+}
+
+// ExitError asks the caller of Execute to exit with Code, for commands
+// whose exit status is a result, such as diff. Err, if set, is the error
+// behind the status; cobra has already printed it unless errors are
+// silenced.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Execute runs the root command. SIGINT and SIGTERM cancel the running
+// operation, killing any external tool it is waiting on.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
@@ -51,4 +257,22 @@ func init() {
 	rootCmd.Flags().String("generate-completion", "", "Generate shell completion (bash, zsh, fish)")
 	rootCmd.Flags().MarkHidden("generate-completion")
 	rootCmd.PersistentFlags().BoolVar(&jsonFlag, "json", false, "Output in JSON format")
+	rootCmd.PersistentFlags().StringVar(&backendFlag, "backend", "",
+		fmt.Sprintf("Bluetooth backend (%s; default $BLTCTL_BACKEND, then config.yaml, then %s)",
+			strings.Join(bluetooth.Backends(), ", "), bluetooth.DefaultBackend))
+	rootCmd.PersistentFlags().StringVar(&scenarioFlag, "scenario", "",
+		"YAML or JSON scenario for --backend=sim (default $BLTCTL_SIM_SCENARIO or built-in); each run starts it afresh")
+	rootCmd.PersistentFlags().StringVar(&recordFlag, "record", "",
+		"Record every external command (argv, output, exit code, latency) to a directory")
+	rootCmd.PersistentFlags().StringVar(&replayFlag, "replay", "",
+		"Serve external commands from a directory written by --record")
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 0,
+		fmt.Sprintf("Timeout for each Bluetooth operation (default %s to list, %s to connect, %s for logs)",
+			bluetooth.DefaultSnapshotTimeout, bluetooth.DefaultControlTimeout, bluetooth.DefaultLogTimeout))
+	rootCmd.PersistentFlags().DurationVar(&cacheTTLFlag, "cache-ttl", bluetooth.DefaultSnapshotTTL,
+		"How long to reuse a device snapshot before querying again (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&noStoreFlag, "no-store", false,
+		"Do not record devices, battery levels, and connections in the local store")
+	rootCmd.PersistentFlags().StringVar(&fromFileFlag, "from-file", "",
+		"Read devices from a captured system_profiler SPBluetoothDataType -json document (- for stdin)")
 }
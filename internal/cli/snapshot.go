@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/lu-zhengda/bltctl/internal/bluetooth"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save the Bluetooth state to compare later",
+	Long: `Save the Bluetooth state (devices, controllers, and whether blueutil is
+installed) to a versioned JSON file, to compare later with diff.`,
+}
+
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save <file>",
+	Short: "Save the current Bluetooth state to a file (- for stdout)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := bluetooth.CaptureSnapshotFile(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if args[0] == "-" {
+			data, err := f.Marshal()
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := f.WriteFile(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Saved snapshot to %s.\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -1,21 +1,26 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/lu-zhengda/bltctl/internal/bluetooth"
 )
 
 type tickMsg time.Time
 
 type deviceMsg struct {
-	devices []bluetooth.Device
-	err     error
+	devices     []bluetooth.Device
+	controllers []bluetooth.Controller
+	err         error
 }
 
 type actionMsg struct {
@@ -77,22 +82,23 @@ type Model struct {
 	cursor     int
 	offset     int
 	devices    []bluetooth.Device
+	groups     int // controller header rows; 0 unless there are several controllers
 	confirming bool
 	confirmMsg string
 	confirmFn  func() tea.Cmd
 	showHelp   bool
 	err        error
 	statusMsg  string
-	blueutil   bool
+	controlErr error
 }
 
 // New creates a new TUI model.
 func New(version string) Model {
 	return Model{
-		version:  version,
-		keys:     newKeyMap(),
-		help:     help.New(),
-		blueutil: bluetooth.IsBlueUtilInstalled(),
+		version:    version,
+		keys:       newKeyMap(),
+		help:       help.New(),
+		controlErr: bluetooth.CanControl(context.Background()),
 	}
 }
 
@@ -104,14 +110,42 @@ func tickCmd() tea.Cmd {
 
 func fetchDevices() tea.Cmd {
 	return func() tea.Msg {
-		devices, err := bluetooth.ListDevices()
-		return deviceMsg{devices: devices, err: err}
+		ctx := context.Background()
+		devices, err := bluetooth.ListDevices(ctx)
+		if err != nil {
+			return deviceMsg{err: err}
+		}
+		controllers, err := bluetooth.Controllers(ctx)
+		return deviceMsg{devices: devices, controllers: controllers, err: err}
 	}
 }
 
+// groupByController orders devices by the position of their controller in
+// controllers, keeping the original order within each controller.
+func groupByController(devices []bluetooth.Device, controllers []bluetooth.Controller) []bluetooth.Device {
+	rank := make(map[string]int, len(controllers))
+	for i, c := range controllers {
+		rank[strings.ToLower(c.Address)] = i
+	}
+	out := make([]bluetooth.Device, len(devices))
+	copy(out, devices)
+	sort.SliceStable(out, func(i, j int) bool {
+		ri, ok := rank[strings.ToLower(out[i].Controller)]
+		if !ok {
+			ri = len(controllers)
+		}
+		rj, ok := rank[strings.ToLower(out[j].Controller)]
+		if !ok {
+			rj = len(controllers)
+		}
+		return ri < rj
+	})
+	return out
+}
+
 func connectDevice(address, name string) tea.Cmd {
 	return func() tea.Msg {
-		err := bluetooth.Connect(address)
+		err := bluetooth.Connect(context.Background(), address)
 		if err != nil {
 			return actionMsg{err: err}
 		}
@@ -121,7 +155,7 @@ func connectDevice(address, name string) tea.Cmd {
 
 func disconnectDevice(address, name string) tea.Cmd {
 	return func() tea.Msg {
-		err := bluetooth.Disconnect(address)
+		err := bluetooth.Disconnect(context.Background(), address)
 		if err != nil {
 			return actionMsg{err: err}
 		}
@@ -131,7 +165,7 @@ func disconnectDevice(address, name string) tea.Cmd {
 
 func removeDevice(address, name string) tea.Cmd {
 	return func() tea.Msg {
-		err := bluetooth.Remove(address)
+		err := bluetooth.Remove(context.Background(), address)
 		if err != nil {
 			return actionMsg{err: err}
 		}
@@ -143,9 +177,9 @@ func togglePower(currentlyOn bool) tea.Cmd {
 	return func() tea.Msg {
 		var err error
 		if currentlyOn {
-			err = bluetooth.PowerOff()
+			err = bluetooth.PowerOff(context.Background())
 		} else {
-			err = bluetooth.PowerOn()
+			err = bluetooth.PowerOn(context.Background())
 		}
 		if err != nil {
 			return actionMsg{err: err}
@@ -159,7 +193,7 @@ func togglePower(currentlyOn bool) tea.Cmd {
 
 func resetBluetooth() tea.Cmd {
 	return func() tea.Msg {
-		err := bluetooth.Reset()
+		err := bluetooth.Reset(context.Background())
 		if err != nil {
 			return actionMsg{err: err}
 		}
@@ -190,6 +224,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.devices = msg.devices
+		m.groups = 0
+		if len(msg.controllers) > 1 {
+			m.devices = groupByController(msg.devices, msg.controllers)
+			m.groups = len(msg.controllers)
+		}
 		m.err = nil
 		if m.cursor >= len(m.devices) && len(m.devices) > 0 {
 			m.cursor = len(m.devices) - 1
@@ -259,8 +298,8 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 
 	case key.Matches(msg, m.keys.Connect):
-		if len(m.devices) > 0 && !m.blueutil {
-			m.statusMsg = errorStyle.Render("blueutil required -- brew install blueutil")
+		if len(m.devices) > 0 && m.controlErr != nil {
+			m.statusMsg = errorStyle.Render(m.controlErr.Error())
 			return m, nil
 		}
 		if len(m.devices) > 0 {
@@ -273,8 +312,8 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 
 	case key.Matches(msg, m.keys.Disconnect):
-		if len(m.devices) > 0 && !m.blueutil {
-			m.statusMsg = errorStyle.Render("blueutil required -- brew install blueutil")
+		if len(m.devices) > 0 && m.controlErr != nil {
+			m.statusMsg = errorStyle.Render(m.controlErr.Error())
 			return m, nil
 		}
 		if len(m.devices) > 0 {
@@ -287,8 +326,8 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 
 	case key.Matches(msg, m.keys.Remove):
-		if len(m.devices) > 0 && !m.blueutil {
-			m.statusMsg = errorStyle.Render("blueutil required -- brew install blueutil")
+		if len(m.devices) > 0 && m.controlErr != nil {
+			m.statusMsg = errorStyle.Render(m.controlErr.Error())
 			return m, nil
 		}
 		if len(m.devices) > 0 {
@@ -335,7 +374,8 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 func (m Model) tableHeight() int {
 	// Title(1) + header(1) + status(1) + help(2) + padding(2)
-	overhead := 7
+	// plus one header row per controller when grouped.
+	overhead := 7 + m.groups
 	h := m.height - overhead
 	if h < 1 {
 		h = 10
@@ -353,11 +393,14 @@ func (m Model) View() string {
 
 	// Title bar.
 	title := fmt.Sprintf("bltctl %s", m.version)
-	blueUtilStatus := ""
-	if !m.blueutil {
-		blueUtilStatus = dimStyle.Render(" [blueutil not installed]")
+	controlStatus := ""
+	switch {
+	case errors.Is(m.controlErr, bluetooth.ErrBlueUtilNotInstalled):
+		controlStatus = dimStyle.Render(" [blueutil not installed]")
+	case m.controlErr != nil:
+		controlStatus = dimStyle.Render(" [control unavailable]")
 	}
-	b.WriteString(titleStyle.Render(title) + blueUtilStatus)
+	b.WriteString(titleStyle.Render(title) + controlStatus)
 	b.WriteString("\n")
 
 	// Help view.
@@ -420,6 +463,11 @@ func (m Model) renderDeviceTable() string {
 	for i := m.offset; i < end; i++ {
 		d := m.devices[i]
 
+		if m.groups > 0 && (i == m.offset || d.Controller != m.devices[i-1].Controller) {
+			b.WriteString(labelStyle.Render("Controller " + valueOrDash(d.Controller)))
+			b.WriteString("\n")
+		}
+
 		status := "\u25cb" // disconnected
 		if d.Connected {
 			status = "\u25cf" // connected
@@ -431,7 +479,10 @@ func (m Model) renderDeviceTable() string {
 		}
 
 		battery := "-"
-		if d.BatteryLevel >= 0 {
+		switch {
+		case d.HasComponentBatteries():
+			battery = renderBatteryComponents(d.Batteries)
+		case d.BatteryLevel >= 0:
 			battery = fmt.Sprintf("%s %d%%", renderBatteryBar(d.BatteryLevel), d.BatteryLevel)
 		}
 
@@ -477,15 +528,36 @@ func renderBatteryBar(level int) string {
 		}
 	}
 	bar = "[" + bar + "]"
+	return batteryStyle(level).Render(bar)
+}
+
+// renderBatteryComponents renders "L 100% R 40% C 77%", coloring each
+// component by its own level.
+func renderBatteryComponents(readings []bluetooth.BatteryReading) string {
+	parts := make([]string, 0, len(readings))
+	for _, r := range readings {
+		parts = append(parts, batteryStyle(r.Level).Render(bluetooth.FormatBatteries([]bluetooth.BatteryReading{r})))
+	}
+	return strings.Join(parts, " ")
+}
 
+// batteryStyle returns the color for a battery level.
+func batteryStyle(level int) lipgloss.Style {
 	switch {
 	case level > 60:
-		return batteryHighStyle.Render(bar)
+		return batteryHighStyle
 	case level >= 20:
-		return batteryMedStyle.Render(bar)
+		return batteryMedStyle
 	default:
-		return batteryLowStyle.Render(bar)
+		return batteryLowStyle
+	}
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
 	}
+	return s
 }
 
 func truncate(s string, max int) string {